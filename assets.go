@@ -0,0 +1,280 @@
+package sprites
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"io/fs"
+	"log"
+	"maps"
+	"path"
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/gary23b/sprites/game"
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/gary23b/sprites/spritestools"
+	"github.com/golang/freetype/truetype"
+)
+
+func (sim *simState) AddSoundFS(fsys fs.FS, path, name string) {
+	data, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		log.Printf("Failed to read sound file: %s, %v\n", path, err)
+		return
+	}
+	if err := sim.addSoundData(path, name, data); err != nil {
+		log.Println(err)
+	}
+}
+
+func (sim *simState) AddSoundReader(r io.Reader, fileName, name string) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		log.Printf("Failed to read sound: %s, %v\n", fileName, err)
+		return
+	}
+	if err := sim.addSoundData(fileName, name, data); err != nil {
+		log.Println(err)
+	}
+}
+
+// Decodes the sound in the calling go routine and then hands the PCM to the game.
+func (sim *simState) addSoundData(fileName, name string, data []byte) error {
+	pcm, err := game.DecodeSound(fileName, data)
+	if err != nil {
+		return err
+	}
+
+	cmd := spritesmodels.CmdAddSoundData{
+		SoundName: name,
+		Data:      pcm,
+	}
+	sim.cmdQueue.Push(cmd)
+	return nil
+}
+
+func (sim *simState) AddFont(fsys fs.FS, path, name string) error {
+	data, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return fmt.Errorf("Failed to read font file: %s, %w", path, err)
+	}
+	font, err := truetype.Parse(data)
+	if err != nil {
+		return fmt.Errorf("Failed to parse font file: %s, %w", path, err)
+	}
+
+	sim.fontsMutex.Lock()
+	sim.fonts[name] = font
+	sim.fontsMutex.Unlock()
+	return nil
+}
+
+func (sim *simState) GetFont(name string) *truetype.Font {
+	sim.fontsMutex.RLock()
+	defer sim.fontsMutex.RUnlock()
+	return sim.fonts[name]
+}
+
+type assetTask struct {
+	name string
+	path string
+	load func() error
+}
+
+// One task per asset in the manifest. The tasks are independent and can be run in any order.
+func (sim *simState) assetTasks(fsys fs.FS, manifest spritesmodels.AssetManifest) []assetTask {
+	ret := make([]assetTask, 0, manifest.Count())
+
+	for _, c := range manifest.Costumes {
+		ret = append(ret, assetTask{name: c.Name, path: c.Path, load: func() error {
+			img, err := LoadSpriteFS(fsys, c.Path)
+			if err != nil {
+				return err
+			}
+			sim.AddCostume(img, c.Name)
+			return nil
+		}})
+	}
+
+	for _, sheet := range manifest.SpriteSheets {
+		ret = append(ret, assetTask{name: sheet.Name, path: sheet.Path, load: func() error {
+			return sim.addSpriteSheet(fsys, sheet)
+		}})
+	}
+
+	for _, sound := range manifest.Sounds {
+		ret = append(ret, assetTask{name: sound.Name, path: sound.Path, load: func() error {
+			data, err := fs.ReadFile(fsys, sound.Path)
+			if err != nil {
+				return fmt.Errorf("Failed to read sound file: %s, %w", sound.Path, err)
+			}
+			return sim.addSoundData(sound.Path, sound.Name, data)
+		}})
+	}
+
+	for _, f := range manifest.Fonts {
+		ret = append(ret, assetTask{name: f.Name, path: f.Path, load: func() error {
+			return sim.AddFont(fsys, f.Path, f.Name)
+		}})
+	}
+
+	for _, a := range manifest.Atlases {
+		ret = append(ret, assetTask{name: a.Path, path: a.Path, load: func() error {
+			return sim.LoadAtlas(fsys, a.Path)
+		}})
+	}
+
+	return ret
+}
+
+func (sim *simState) LoadAssets(fsys fs.FS, manifestPath string, progress func(spritesmodels.AssetProgress)) error {
+	manifest, err := spritestools.LoadAssetManifest(fsys, manifestPath)
+	if err != nil {
+		return err
+	}
+
+	tasks := sim.assetTasks(fsys, manifest)
+	errs := []error{}
+	for i, task := range tasks {
+		err := task.load()
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if progress != nil {
+			progress(spritesmodels.AssetProgress{
+				Name:   task.name,
+				Loaded: i + 1,
+				Total:  len(tasks),
+				Err:    err,
+			})
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (sim *simState) LoadAssetsAsync(fsys fs.FS, manifestPath string) (chan spritesmodels.AssetProgress, error) {
+	manifest, err := spritestools.LoadAssetManifest(fsys, manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reserve the costume names right away so sprites can start using them.
+	for _, c := range manifest.Costumes {
+		sim.cmdQueue.Push(spritesmodels.CmdAddPlaceholderCostume{CostumeName: c.Name})
+	}
+	for _, sheet := range manifest.SpriteSheets {
+		for _, name := range spriteSheetFrameNames(fsys, sheet) {
+			sim.cmdQueue.Push(spritesmodels.CmdAddPlaceholderCostume{CostumeName: name})
+		}
+	}
+	for _, a := range manifest.Atlases {
+		index, err := readAtlasIndex(fsys, a.Path)
+		if err != nil {
+			continue // Loading it reports the error.
+		}
+		for _, name := range slices.Sorted(maps.Keys(index.Frames)) {
+			sim.cmdQueue.Push(spritesmodels.CmdAddPlaceholderCostume{CostumeName: name})
+		}
+	}
+
+	tasks := sim.assetTasks(fsys, manifest)
+	progress := make(chan spritesmodels.AssetProgress, len(tasks))
+
+	go func() {
+		pool := spritestools.NewWorkerPool(runtime.NumCPU())
+		mutex := sync.Mutex{}
+		loaded := 0
+
+		for _, task := range tasks {
+			pool.AddTask(func() {
+				err := task.load()
+
+				mutex.Lock()
+				loaded++
+				progress <- spritesmodels.AssetProgress{
+					Name:   task.name,
+					Loaded: loaded,
+					Total:  len(tasks),
+					Err:    err,
+				}
+				mutex.Unlock()
+			})
+		}
+
+		pool.WaitForCompletion()
+		close(progress)
+	}()
+
+	return progress, nil
+}
+
+func readAtlasIndex(fsys fs.FS, indexPath string) (spritesmodels.AtlasIndex, error) {
+	var index spritesmodels.AtlasIndex
+	data, err := fs.ReadFile(fsys, indexPath)
+	if err != nil {
+		return index, fmt.Errorf("Failed to read atlas index: %s, %w", indexPath, err)
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return index, fmt.Errorf("Failed to decode atlas index: %s, %w", indexPath, err)
+	}
+	return index, nil
+}
+
+func (sim *simState) LoadAtlas(fsys fs.FS, indexPath string) error {
+	index, err := readAtlasIndex(fsys, indexPath)
+	if err != nil {
+		return err
+	}
+
+	img, err := LoadSpriteFS(fsys, path.Join(path.Dir(indexPath), index.Image))
+	if err != nil {
+		return err
+	}
+	frames, err := spritestools.AtlasRects(img.Bounds(), index)
+	if err != nil {
+		return fmt.Errorf("%s, %w", indexPath, err)
+	}
+
+	cmd := spritesmodels.CmdAddAtlas{
+		Img:    img,
+		Frames: frames,
+	}
+	sim.cmdQueue.Push(cmd)
+	return nil
+}
+
+func (sim *simState) addSpriteSheet(fsys fs.FS, sheet spritesmodels.SpriteSheetAsset) error {
+	img, err := LoadSpriteFS(fsys, sheet.Path)
+	if err != nil {
+		return err
+	}
+	frames, err := spritestools.SplitSpriteSheet(img, sheet.FrameWidth, sheet.FrameHeight)
+	if err != nil {
+		return fmt.Errorf("%s, %w", sheet.Path, err)
+	}
+	names := spritestools.SpriteSheetFrameNames(sheet, img.Bounds().Dx(), img.Bounds().Dy())
+	for i, frame := range frames {
+		sim.AddCostume(frame, names[i])
+	}
+	return nil
+}
+
+// Only reads the image header, so it is quick even for big sheets. nil if the sheet can't be read; loading it
+// reports the error.
+func spriteSheetFrameNames(fsys fs.FS, sheet spritesmodels.SpriteSheetAsset) []string {
+	f, err := fsys.Open(sheet.Path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	config, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return nil
+	}
+	return spritestools.SpriteSheetFrameNames(sheet, config.Width, config.Height)
+}
@@ -0,0 +1,86 @@
+package sprites
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/stretchr/testify/require"
+)
+
+func testPNG(t *testing.T, w, h int) *fstest.MapFile {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h))))
+	return &fstest.MapFile{Data: buf.Bytes()}
+}
+
+func TestLoadAssetsAsync(t *testing.T) {
+	fsys := fstest.MapFS{
+		"manifest.json": {Data: []byte(`{
+			"costumes": [{"name": "player", "path": "player.png"}],
+			"spriteSheets": [
+				{"name": "walk", "path": "walk.png", "frameWidth": 2, "frameHeight": 2},
+				{"name": "missing", "path": "missing.png", "frameWidth": 2, "frameHeight": 2}
+			],
+			"atlases": [{"path": "atlas.json"}]
+		}`)},
+		"player.png": testPNG(t, 4, 4),
+		"walk.png":   testPNG(t, 6, 2),
+		"atlas.json": {Data: []byte(`{"image": "atlas.png", "frames": {
+			"gem": {"x": 0, "y": 0, "w": 2, "h": 2}, "coin": {"x": 2, "y": 0, "w": 2, "h": 2}}}`)},
+		"atlas.png": testPNG(t, 4, 2),
+	}
+
+	sim := newTestSim()
+	progress, err := sim.LoadAssetsAsync(fsys, "manifest.json")
+	require.NoError(t, err)
+
+	// Every costume name is reserved before it returns.
+	expected := []string{"player", "walk0", "walk1", "walk2", "coin", "gem"}
+	cmds := sim.takeCmds()
+	require.GreaterOrEqual(t, len(cmds), len(expected))
+	for i, name := range expected {
+		require.Equal(t, spritesmodels.CmdAddPlaceholderCostume{CostumeName: name}, cmds[i])
+	}
+
+	updates := 0
+	failed := []string{}
+	for p := range progress {
+		updates++
+		require.Equal(t, 4, p.Total)
+		if p.Err != nil {
+			failed = append(failed, p.Name)
+		}
+	}
+	require.Equal(t, 4, updates)
+	require.Equal(t, []string{"missing"}, failed)
+
+	// Then the real ones replace them.
+	cmds = append(cmds[len(expected):], sim.takeCmds()...)
+	added := []string{}
+	atlasFrames := 0
+	for _, cmd := range cmds {
+		switch v := cmd.(type) {
+		case spritesmodels.CmdAddCostume:
+			added = append(added, v.CostumeName)
+		case spritesmodels.CmdAddAtlas:
+			atlasFrames += len(v.Frames)
+		}
+	}
+	require.ElementsMatch(t, []string{"player", "walk0", "walk1", "walk2"}, added)
+	require.Equal(t, 2, atlasFrames)
+}
+
+func TestDefaultAssets(t *testing.T) {
+	sim := newTestSim()
+	require.NoError(t, sim.LoadAssets(DefaultAssets, "manifest.json", nil))
+	require.ElementsMatch(t, []string{"turtle", "arrow"}, sim.takeAddedCostumes())
+
+	// The deprecated strings still decode to the same images.
+	require.Equal(t, 64, DecodeCodedSprite(TurtleImage).Bounds().Dx())
+	require.Equal(t, 32, DecodeCodedSprite(ArrowImage).Bounds().Dx())
+}
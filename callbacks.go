@@ -0,0 +1,184 @@
+package sprites
+
+import (
+	"cmp"
+	"log"
+	"runtime"
+	"slices"
+	"time"
+
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/gary23b/sprites/spritestools"
+)
+
+// How many times a second the sim runs the sprite callbacks.
+const CallbackTickRate = 60
+
+type spriteCallbacks struct {
+	onTick    func(dt float64)
+	onMessage func(msg any)
+	onClick   func(input *spritesmodels.UserInput)
+}
+
+func (c spriteCallbacks) any() bool {
+	return c.onTick != nil || c.onMessage != nil || c.onClick != nil
+}
+
+func (s *sprite) OnTick(f func(dt float64)) {
+	s.setCallbacks(func(c *spriteCallbacks) { c.onTick = f })
+}
+
+func (s *sprite) OnMessage(f func(msg any)) {
+	s.setCallbacks(func(c *spriteCallbacks) { c.onMessage = f })
+}
+
+func (s *sprite) OnClick(f func(input *spritesmodels.UserInput)) {
+	s.setCallbacks(func(c *spriteCallbacks) { c.onClick = f })
+}
+
+func (s *sprite) setCallbacks(change func(c *spriteCallbacks)) {
+	s.callbackMutex.Lock()
+	change(&s.callbacks)
+	s.callbackMutex.Unlock()
+	s.sim.SpriteCallbacksChanged(s)
+}
+
+func (s *sprite) getCallbacks() spriteCallbacks {
+	s.callbackMutex.Lock()
+	defer s.callbackMutex.Unlock()
+	return s.callbacks
+}
+
+// A panic only ends the one callback, not the whole sim.
+func (s *sprite) runCallback(f func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Sprite %d: callback panicked: %v\n", s.spriteID, r)
+		}
+	}()
+	f()
+}
+
+func (sim *simState) SpriteCallbacksChanged(in Sprite) {
+	s, ok := in.(*sprite)
+	if !ok {
+		return
+	}
+
+	sim.callbackMutex.Lock()
+	defer sim.callbackMutex.Unlock()
+	if !s.getCallbacks().any() || s.isDeleted() {
+		delete(sim.callbackSprites, s.spriteID)
+		return
+	}
+	sim.callbackSprites[s.spriteID] = s
+	sim.callbackOnce.Do(func() {
+		go sim.runCallbacks()
+	})
+}
+
+// The sprites with callbacks, in ID order.
+func (sim *simState) spritesWithCallbacks() []*sprite {
+	sim.callbackMutex.Lock()
+	ret := make([]*sprite, 0, len(sim.callbackSprites))
+	for _, s := range sim.callbackSprites {
+		ret = append(ret, s)
+	}
+	sim.callbackMutex.Unlock()
+
+	slices.SortFunc(ret, func(a, b *sprite) int { return cmp.Compare(a.spriteID, b.spriteID) })
+	return ret
+}
+
+// Started by the first sprite to get a callback.
+func (sim *simState) runCallbacks() {
+	pool := spritestools.NewWorkerPool(runtime.NumCPU())
+	clicks := sim.SubscribeToJustPressedUserInput()
+	ticker := time.NewTicker(time.Second / CallbackTickRate)
+	last := time.Now()
+
+	for now := range ticker.C {
+		dt := now.Sub(last).Seconds()
+		last = now
+		sim.runCallbackTick(pool, clicks, dt)
+	}
+}
+
+// Runs clicks, then messages, then ticks. Every phase runs the sprites in parallel and finishes before the next one
+// starts.
+func (sim *simState) runCallbackTick(pool spritestools.WorkerPool, clicks chan *spritesmodels.UserInput, dt float64) {
+	sprites := sim.spritesWithCallbacks()
+
+	sim.runClickCallbacks(pool, clicks, sprites)
+
+	for _, s := range sprites {
+		onMessage := s.getCallbacks().onMessage
+		if onMessage == nil {
+			continue
+		}
+		msgs := s.GetMsgs()
+		if len(msgs) == 0 {
+			continue
+		}
+		pool.AddTask(func() {
+			for _, msg := range msgs {
+				s.runCallback(func() { onMessage(msg) })
+			}
+		})
+	}
+	pool.Wait()
+
+	for _, s := range sprites {
+		if onTick := s.getCallbacks().onTick; onTick != nil {
+			pool.AddTask(func() { s.runCallback(func() { onTick(dt) }) })
+		}
+	}
+	pool.Wait()
+}
+
+func (sim *simState) spriteDrawIndex(spriteID int) int {
+	if sim.drawIndex == nil {
+		return -1
+	}
+	return sim.drawIndex(spriteID)
+}
+
+// Each left click goes to the top sprite under the mouse that has OnClick. Clicks are handled in the order they happened.
+func (sim *simState) runClickCallbacks(pool spritestools.WorkerPool, clicks chan *spritesmodels.UserInput, sprites []*sprite) {
+	for {
+		var input *spritesmodels.UserInput
+		select {
+		case input = <-clicks:
+		default:
+			return
+		}
+		if !input.Mouse.Left {
+			continue
+		}
+
+		x, y := float64(input.Mouse.MouseX), float64(input.Mouse.MouseY)
+		var top *sprite
+		topIndex, topZ := -1, 0.0
+		for _, s := range sprites {
+			if s.getCallbacks().onClick == nil {
+				continue
+			}
+			state := s.GetState()
+			if !state.Visible || !s.GetClickBody().IsMouseClickInBody(x, y) {
+				continue
+			}
+			// The game's draw order decides. Sprites it hasn't drawn yet go by Z, and then the newest is on top.
+			index := sim.spriteDrawIndex(s.spriteID)
+			if top == nil || index > topIndex || index == topIndex && state.Z >= topZ {
+				top, topIndex, topZ = s, index, state.Z
+			}
+		}
+		if top == nil {
+			continue
+		}
+
+		onClick := top.getCallbacks().onClick
+		pool.AddTask(func() { top.runCallback(func() { onClick(input) }) })
+		pool.Wait()
+	}
+}
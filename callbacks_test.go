@@ -0,0 +1,94 @@
+package sprites
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/gary23b/sprites/spritestools"
+	"github.com/stretchr/testify/require"
+)
+
+func leftClick(x, y int) *spritesmodels.UserInput {
+	return &spritesmodels.UserInput{Mouse: spritesmodels.MouseStruct{Left: true, MouseX: x, MouseY: y}}
+}
+
+func TestCallbackTick(t *testing.T) {
+	sim := newTestSim()
+	sim.callbackOnce.Do(func() {}) // The test runs the ticks itself.
+
+	// Sprite 1 was brought to the front, even though it is the oldest.
+	drawIndex := map[int]int{1: 2, 2: 0, 3: 1}
+	sim.drawIndex = func(spriteID int) int { return drawIndex[spriteID] }
+
+	var eventsMutex sync.Mutex
+	var events []string
+	var running [4]atomic.Int32
+	record := func(spriteID int, event string) {
+		if running[spriteID].Add(1) != 1 {
+			t.Errorf("Sprite %d ran two callbacks at once", spriteID)
+		}
+		time.Sleep(time.Millisecond)
+		eventsMutex.Lock()
+		events = append(events, fmt.Sprintf("%s %d", event, spriteID))
+		eventsMutex.Unlock()
+		running[spriteID].Add(-1)
+	}
+
+	for id := 1; id <= 3; id++ {
+		s := sim.addTestSprite(id)
+		body := spritestools.NewTouchCollisionBody()
+		body.AddCircleBody(0, 0, 10)
+		require.NoError(t, s.ReplaceClickBody(body))
+		require.NoError(t, s.Visible(true))
+		s.OnClick(func(input *spritesmodels.UserInput) { record(id, "click") })
+		s.OnMessage(func(msg any) { record(id, "message") })
+		s.OnTick(func(dt float64) {
+			if dt != 0.5 {
+				t.Errorf("Wrong dt: %v", dt)
+			}
+			record(id, "tick")
+		})
+	}
+	for id := 1; id <= 3; id++ {
+		sim.SendMsg(id, "a")
+		sim.SendMsg(id, "b")
+	}
+	clicks := make(chan *spritesmodels.UserInput, 10)
+	clicks <- leftClick(0, 0)
+	clicks <- leftClick(100, 100) // Misses everything
+	clicks <- leftClick(1, 1)
+
+	pool := spritestools.NewWorkerPool(4)
+	defer pool.WaitForCompletion()
+	sim.runCallbackTick(pool, clicks, 0.5)
+
+	// Each phase finishes before the next starts.
+	require.Len(t, events, 2+6+3)
+	require.Equal(t, []string{"click 1", "click 1"}, events[:2])
+	for _, event := range events[2:8] {
+		require.True(t, strings.HasPrefix(event, "message"), event)
+	}
+	for _, event := range events[8:] {
+		require.True(t, strings.HasPrefix(event, "tick"), event)
+	}
+
+	// Without a draw order, the highest Z and then the newest sprite is on top.
+	sim.drawIndex = nil
+	events = nil
+	clicks <- leftClick(0, 0)
+	sim.runCallbackTick(pool, clicks, 0.5)
+	require.Equal(t, []string{"click 3", "tick 1", "tick 2", "tick 3"}, sortedAfter(events, 1))
+}
+
+// Sorts everything after the first n, for phases that run the sprites in parallel.
+func sortedAfter(events []string, n int) []string {
+	ret := append([]string{}, events...)
+	slices.Sort(ret[n:])
+	return ret
+}
@@ -0,0 +1,92 @@
+// Packs a directory of PNG files into atlas pages that can be loaded with sim.LoadAtlas(...).
+// Each page is written as <out><page>.png along with an index <out><page>.json.
+//
+//	go run ./cmd/packatlas -in ./art -out ./assets/atlas -size 2048
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gary23b/sprites/spritestools"
+)
+
+func main() {
+	inDir := flag.String("in", ".", "Directory of PNG files to pack. The file name without the extension becomes the costume name.")
+	out := flag.String("out", "atlas", "Output path prefix")
+	size := flag.Int("size", 2048, "Width and height of each atlas page")
+	flag.Parse()
+
+	images, err := loadPNGs(*inDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	pages, indexes, err := spritestools.PackAtlas(images, *size, *size)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for i := range pages {
+		pngPath := fmt.Sprintf("%s%d.png", *out, i)
+		indexes[i].Image = filepath.Base(pngPath)
+
+		if err := writePNG(pngPath, pages[i]); err != nil {
+			log.Fatal(err)
+		}
+
+		indexData, err := json.MarshalIndent(indexes[i], "", "\t")
+		if err != nil {
+			log.Fatal(err)
+		}
+		indexPath := fmt.Sprintf("%s%d.json", *out, i)
+		if err := os.WriteFile(indexPath, indexData, 0o644); err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("%s: %d costumes\n", indexPath, len(indexes[i].Frames))
+	}
+}
+
+func loadPNGs(dir string) (map[string]image.Image, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := map[string]image.Image{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.EqualFold(filepath.Ext(e.Name()), ".png") {
+			continue
+		}
+
+		f, err := os.Open(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		img, err := png.Decode(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to decode %s: %w", e.Name(), err)
+		}
+
+		ret[strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))] = img
+	}
+	return ret, nil
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return png.Encode(f, img)
+}
@@ -24,7 +24,7 @@ type bunny struct {
 func Main_Bunny(sim sprites.Sim, x, y float64) {
 
 	s := sim.AddSprite("")
-	s.Costume("Bunny")
+	s.Costume("turtle")
 	s.SetType(BunnyType)
 
 	s.Pos(x, y)
@@ -38,7 +38,7 @@ func simStartFunc(sim sprites.Sim) {
 	img = image.NewRGBA(image.Rect(0, 0, 1, 1))
 	img.Set(0, 0, LawnGreen)
 	sim.AddCostume(img, "Grass")
-	sim.AddCostume(sprites.DecodeCodedSprite(sprites.TurtleImage), "Bunny")
+	sim.LoadAssets(sprites.DefaultAssets, "manifest.json", nil)
 
 	for y := -300; y < 300; y += 10 {
 		for x := -300; x < 300; x += 10 {
@@ -31,11 +31,11 @@ func simStartFunc(sim sprites.Sim) {
 	s.Scale(1010)
 	s.Visible(true)
 
-	sim.AddCostume(sprites.DecodeCodedSprite(sprites.TurtleImage), "t")
+	sim.LoadAssets(sprites.DefaultAssets, "manifest.json", nil)
 
 	s = sim.AddSprite("mainTurtle")
 	b := s.GetClickBody()
-	s.Costume("t")
+	s.Costume("turtle")
 	s.Scale(1)
 	s.Angle(-90)
 
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/gary23b/sprites"
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/gary23b/sprites/spritestools"
+)
+
+const (
+	boidCount  = 300
+	boidType   = 1
+	tick       = time.Second / 60
+	viewRadius = 50.0
+)
+
+func main() {
+	params := sprites.SimParams{Width: 1000, Height: 1000, ShowFPS: true, PositionGridCellSize: viewRadius}
+	sprites.Start(params, simStartFunc)
+}
+
+// Each boid shares its velocity here so its neighbors can line up with it.
+var velocities sync.Map
+
+func simStartFunc(sim sprites.Sim) {
+	sim.LoadAssets(sprites.DefaultAssets, "manifest.json", nil)
+
+	for i := 0; i < boidCount; i++ {
+		go boid(sim)
+	}
+}
+
+func velocityOf(spriteID int) (float64, float64) {
+	v, ok := velocities.Load(spriteID)
+	if !ok {
+		return 0, 0
+	}
+	vel := v.([2]float64)
+	return vel[0], vel[1]
+}
+
+func boid(sim sprites.Sim) {
+	s := sim.AddSprite("")
+	s.Costume("turtle")
+	s.SetType(boidType)
+	s.Scale(.3)
+	s.Visible(true)
+
+	halfW, halfH := float64(sim.GetWidth())/2, float64(sim.GetHeight())/2
+	angle := rand.Float64() * 2 * math.Pi
+	a := spritestools.SteeringAgent{
+		X:        (rand.Float64()*2 - 1) * halfW,
+		Y:        (rand.Float64()*2 - 1) * halfH,
+		VelX:     math.Cos(angle) * 100,
+		VelY:     math.Sin(angle) * 100,
+		MaxSpeed: 150,
+		MaxForce: 300,
+	}
+	weights := spritestools.DefaultFlockWeights()
+	filter := spritesmodels.NearMeFilter{SpriteTypes: []int{boidType}, Limit: 10}
+
+	for {
+		near := s.WhoIsWithin(viewRadius, filter)
+		fx, fy := a.Flock(spritestools.SteeringNeighbors(near, velocityOf), weights)
+		wx, wy := a.Wander(20, 40, .3)
+		a.Apply(fx+wx*.3, fy+wy*.3, tick.Seconds())
+
+		// Wrap around the edges of the window.
+		a.X = math.Mod(a.X+3*halfW, 2*halfW) - halfW
+		a.Y = math.Mod(a.Y+3*halfH, 2*halfH) - halfH
+
+		velocities.Store(s.GetSpriteID(), [2]float64{a.VelX, a.VelY})
+		s.Pos(a.X, a.Y)
+		s.Angle(a.Heading())
+		time.Sleep(tick)
+	}
+}
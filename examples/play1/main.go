@@ -20,7 +20,7 @@ func main() {
 // }
 
 func simStartFunc(sim sprites.Sim) {
-	sim.AddCostume(sprites.DecodeCodedSprite(sprites.TurtleImage), "t1")
+	sim.LoadAssets(sprites.DefaultAssets, "manifest.json", nil)
 
 	// if fileExists("jab.wav") {
 	// 	sim.AddSound("jab.wav", "jab")
@@ -42,7 +42,7 @@ func testScene(sim sprites.Sim) {
 	broker := spritestools.NewBroker[string](100)
 
 	s := sim.AddSprite("mainTurtle")
-	s.Costume("t1")
+	s.Costume("turtle")
 	s.Scale(10)
 	s.Z(0)
 	s.Visible(true)
@@ -96,7 +96,7 @@ func testScene(sim sprites.Sim) {
 func turtle(sim sprites.Sim, broker *spritestools.Broker[string]) {
 	broadcasts := broker.Subscribe()
 	s := sim.AddSprite(fmt.Sprintf("turtle%d%d", rand.Uint64(), rand.Uint64()))
-	s.Costume("t1")
+	s.Costume("turtle")
 	s.Scale(.2)
 	s.Z(1)
 	s.Visible(true)
@@ -14,11 +14,11 @@ func main() {
 }
 
 func simStartFunc(sim sprites.Sim) {
-	sim.AddCostume(sprites.DecodeCodedSprite(sprites.TurtleImage), "t1")
+	sim.LoadAssets(sprites.DefaultAssets, "manifest.json", nil)
 
 	a := 0.0
 	s := sim.AddSprite("mainTurtle")
-	s.Costume("t1")
+	s.Costume("turtle")
 	s.Scale(10)
 	s.Z(0)
 	s.Visible(true)
@@ -53,7 +53,7 @@ MainSpriteLoop:
 
 func t2(sim sprites.Sim) {
 	s := sim.AddSprite("t2")
-	s.Costume("t1")
+	s.Costume("turtle")
 	s.Scale(1)
 	s.Z(0)
 	s.Visible(true)
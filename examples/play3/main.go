@@ -14,7 +14,7 @@ func main() {
 }
 
 func simStartFunc(sim sprites.Sim) {
-	// sim.AddCostume(ebitensim.DecodeCodedSprite(ebitensim.TurtleImage), "t1")
+	// sim.LoadAssets(sprites.DefaultAssets, "manifest.json", nil)
 
 	textImage := spritestools.CreateTextBubble(200, 100, "abasd sdf sdfsdfsd fs dfsdfsd fsdf sf\n    c234", 20)
 	sim.AddCostume(textImage, "textBubble")
@@ -14,7 +14,7 @@ func main() {
 }
 
 func simStartFunc(sim sprites.Sim) {
-	sim.AddCostume(sprites.DecodeCodedSprite(sprites.TurtleImage), "t")
+	sim.LoadAssets(sprites.DefaultAssets, "manifest.json", nil)
 
 	// go sprites.CreateGifDithered(sim, time.Millisecond*100, time.Millisecond*100, "./examples/randomwalk/randomwalk.gif", 100)
 
@@ -26,7 +26,7 @@ func simStartFunc(sim sprites.Sim) {
 
 func turtleRandomWalk(sim sprites.Sim) {
 	s := sim.AddSprite("")
-	s.Costume("t")
+	s.Costume("turtle")
 	s.Visible(true)
 
 	x, y := 0.0, 0.0
@@ -0,0 +1,93 @@
+package game
+
+import (
+	"image"
+	"image/draw"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	atlasPageSize       = 2048
+	atlasPadding        = 1
+	atlasMaxCostumeSize = 512 // Bigger costumes get a texture of their own.
+)
+
+// Where a costume was packed.
+type atlasSlot struct {
+	page int
+	rect image.Rectangle
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
+
+// Small costumes are packed into shared atlas pages so that sprites using different costumes can be drawn in one batch.
+func (g *EbitenGame) newCostumeImage(img image.Image) (ret *ebiten.Image, slot atlasSlot, inAtlas bool) {
+	b := img.Bounds()
+	if b.Dx() > atlasMaxCostumeSize || b.Dy() > atlasMaxCostumeSize {
+		return ebiten.NewImageFromImage(img), slot, false
+	}
+
+	page, r, ok := g.atlasPacker.Pack(b.Dx(), b.Dy())
+	if !ok {
+		return ebiten.NewImageFromImage(img), slot, false
+	}
+	for len(g.atlasPages) <= page {
+		g.atlasPages = append(g.atlasPages, ebiten.NewImage(atlasPageSize, atlasPageSize))
+	}
+
+	// The space may have been used before, so the padding is cleared too.
+	padded, ok := g.atlasPages[page].SubImage(r.Inset(-atlasPadding)).(*ebiten.Image)
+	if !ok {
+		return ebiten.NewImageFromImage(img), slot, false
+	}
+	padded.Clear()
+	sub := padded.SubImage(r).(*ebiten.Image)
+	sub.WritePixels(toRGBA(img).Pix)
+	return sub, atlasSlot{page: page, rect: r}, true
+}
+
+// A costume in the atlas that is replaced with an image of the same size is written over in place. One of another
+// size gives its space back to be packed again, so that reloading costumes doesn't use up the atlas.
+func (g *EbitenGame) replaceAtlasCostume(id int, img image.Image) bool {
+	slot, ok := g.atlasSlots[id]
+	if !ok {
+		return false
+	}
+	if slot.rect.Dx() != img.Bounds().Dx() || slot.rect.Dy() != img.Bounds().Dy() {
+		g.freeAtlasSlot(id)
+		return false
+	}
+
+	g.costumes[id].WritePixels(toRGBA(img).Pix)
+	return true
+}
+
+// Gives the costume's atlas space back, for when it is replaced by an image that lives somewhere else.
+func (g *EbitenGame) freeAtlasSlot(id int) {
+	if slot, ok := g.atlasSlots[id]; ok {
+		g.atlasPacker.Free(slot.page, slot.rect)
+		delete(g.atlasSlots, id)
+	}
+}
+
+// Uploads a pre-packed atlas page as a single texture.
+func (g *EbitenGame) addAtlas(img image.Image, frames map[string]image.Rectangle) {
+	page := ebiten.NewImageFromImage(img)
+	offset := img.Bounds().Min
+	for name, r := range frames {
+		sub, ok := page.SubImage(r.Sub(offset)).(*ebiten.Image)
+		if !ok {
+			continue
+		}
+		if id, ok := g.nameToCostumeIDMap[name]; ok {
+			g.freeAtlasSlot(id)
+		}
+		g.setCostume(name, sub)
+	}
+}
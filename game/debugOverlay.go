@@ -0,0 +1,87 @@
+package game
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/gary23b/sprites/spritesmodels"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const debugOverlayKey = ebiten.KeyF4
+
+var (
+	debugGridColor    = color.RGBA{0x30, 0x60, 0xA0, 0x80}
+	debugBoundsColor  = color.RGBA{0xFF, 0xD0, 0x00, 0xFF}
+	debugClickColor   = color.RGBA{0x00, 0xFF, 0x60, 0xFF}
+	debugAnchorColor  = color.RGBA{0xFF, 0x30, 0x30, 0xFF}
+	debugAnchorRadius = float32(4)
+)
+
+func (g *EbitenGame) updateDebugOverlayToggle() {
+	if inpututil.IsKeyJustPressed(debugOverlayKey) {
+		g.showOverlay = !g.showOverlay
+	}
+}
+
+// Cartesian world coordinates to screen pixels
+func (g *EbitenGame) toScreen(x, y float64) (float32, float32) {
+	return float32(float64(g.screenWidth/2) + x), float32(float64(g.screenHeight/2) - y)
+}
+
+func (g *EbitenGame) strokePolygon(screen *ebiten.Image, points []spritesmodels.Point, clr color.Color) {
+	for i := range points {
+		next := points[(i+1)%len(points)]
+		x0, y0 := g.toScreen(points[i].X, points[i].Y)
+		x1, y1 := g.toScreen(next.X, next.Y)
+		vector.StrokeLine(screen, x0, y0, x1, y1, 1, clr, false)
+	}
+}
+
+// Outlines the grid cells, costume bounds, click bodies, and anchors, and labels each sprite.
+func (g *EbitenGame) drawDebugOverlay(screen *ebiten.Image) {
+	info := spritesmodels.DebugOverlayInfo{}
+	if g.debugOverlaySource != nil {
+		info = g.debugOverlaySource()
+	}
+
+	for _, cell := range info.GridCells {
+		x, y := g.toScreen(cell.MinX, cell.MaxY)
+		vector.StrokeRect(screen, x, y, float32(cell.MaxX-cell.MinX), float32(cell.MaxY-cell.MinY), 1, debugGridColor, false)
+	}
+
+	names := make(map[int]string, len(info.Sprites))
+	for _, s := range info.Sprites {
+		names[s.SpriteID] = s.UniqueName
+	}
+
+	for _, sprite := range g.drawOrder {
+		if !sprite.visible || sprite.CostumeIndex < 0 {
+			continue
+		}
+		costume := g.costumes[sprite.CostumeIndex]
+		w, h := costume.Bounds().Dx(), costume.Bounds().Dy()
+		geoM := g.spriteGeoM(sprite, w, h)
+		corners := [4][2]float64{{0, 0}, {float64(w), 0}, {float64(w), float64(h)}, {0, float64(h)}}
+		for i := range corners {
+			x0, y0 := geoM.Apply(corners[i][0], corners[i][1])
+			x1, y1 := geoM.Apply(corners[(i+1)%4][0], corners[(i+1)%4][1])
+			vector.StrokeLine(screen, float32(x0), float32(y0), float32(x1), float32(y1), 1, debugBoundsColor, false)
+		}
+
+		x, y := g.toScreen(sprite.x, sprite.y)
+		vector.StrokeLine(screen, x-debugAnchorRadius, y, x+debugAnchorRadius, y, 1, debugAnchorColor, false)
+		vector.StrokeLine(screen, x, y-debugAnchorRadius, x, y+debugAnchorRadius, 1, debugAnchorColor, false)
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("%d %s", sprite.id, names[sprite.id]), int(x)+2, int(y)+2)
+	}
+
+	for _, s := range info.Sprites {
+		for _, outline := range s.ClickOutlines {
+			g.strokePolygon(screen, outline, debugClickColor)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package game
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/gary23b/sprites/spritesmodels"
+)
+
+// Sprites are drawn by z. Sprites with the same z are drawn by their order value, unless their z is Y-sorted.
+// In that case the sprites lower on the screen are drawn on top.
+func (g *EbitenGame) compareDrawOrder(a, b *ebitenSprite) int {
+	if c := cmp.Compare(a.z, b.z); c != 0 {
+		return c
+	}
+	if g.ySortLayers[a.z] {
+		if c := cmp.Compare(b.y, a.y); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(a.order, b.order)
+}
+
+// Deleted sprites are dropped here instead of leaving holes. Y-sorted layers are sorted every frame since anything may have moved.
+func (g *EbitenGame) sortDrawOrder() {
+	if !g.drawOrderDirty && len(g.ySortLayers) == 0 {
+		return
+	}
+	g.drawOrder = slices.DeleteFunc(g.drawOrder, func(s *ebitenSprite) bool { return s.deleted })
+	slices.SortStableFunc(g.drawOrder, g.compareDrawOrder)
+	for i, s := range g.drawOrder {
+		s.drawIndex = i
+	}
+	g.drawOrderDirty = false
+	g.drawOrderChanged = true
+}
+
+// Copies the draw order for DrawIndex.
+func (g *EbitenGame) publishDrawOrder() {
+	if !g.drawOrderChanged {
+		return
+	}
+	g.drawOrderChanged = false
+
+	g.drawIndexMutex.Lock()
+	defer g.drawIndexMutex.Unlock()
+	clear(g.drawIndexByID)
+	for i, s := range g.drawOrder {
+		if s.id >= len(g.drawIndexByID) {
+			g.drawIndexByID = slices.Grow(g.drawIndexByID, s.id+1-len(g.drawIndexByID))[:s.id+1]
+		}
+		g.drawIndexByID[s.id] = i + 1 // 0 is for sprites that haven't been drawn.
+	}
+}
+
+// Where the sprite was in the draw order when the last frame was drawn. Higher is drawn on top. -1 if it hasn't
+// been drawn yet.
+func (g *EbitenGame) DrawIndex(spriteID int) int {
+	if g == nil {
+		return -1
+	}
+	g.drawIndexMutex.Lock()
+	defer g.drawIndexMutex.Unlock()
+	if spriteID < 0 || spriteID >= len(g.drawIndexByID) {
+		return -1
+	}
+	return g.drawIndexByID[spriteID] - 1
+}
+
+// A sprite that changes z goes on top of the sprites that already have that z.
+func (g *EbitenGame) setSpriteZ(s *ebitenSprite, z float64) {
+	if s.z == z {
+		return
+	}
+	s.z = z
+	s.order = g.nextFrontOrder
+	g.nextFrontOrder++
+	g.drawOrderDirty = true
+}
+
+func (g *EbitenGame) changeDrawOrder(cmd spritesmodels.CmdSpriteDrawOrder) {
+	s := g.getSprite(cmd.SpriteID)
+	if s == nil {
+		return
+	}
+
+	switch cmd.Action {
+	case spritesmodels.OrderFront:
+		s.order = g.nextFrontOrder
+		g.nextFrontOrder++
+		g.drawOrderDirty = true
+	case spritesmodels.OrderBack:
+		s.order = g.nextBackOrder
+		g.nextBackOrder--
+		g.drawOrderDirty = true
+	case spritesmodels.OrderForward:
+		g.swapDrawOrder(s, cmd.Steps, 1)
+	case spritesmodels.OrderBackward:
+		g.swapDrawOrder(s, cmd.Steps, -1)
+	}
+}
+
+// Swaps places with the neighbor in direction dir, steps times, without leaving sprites of the same z.
+// In a Y-sorted layer, the y decides first, so only sprites at the same y can swap.
+func (g *EbitenGame) swapDrawOrder(s *ebitenSprite, steps, dir int) {
+	g.sortDrawOrder()
+	i := s.drawIndex
+	ySorted := g.ySortLayers[s.z]
+	for ; steps > 0; steps-- {
+		j := i + dir
+		if j < 0 || j >= len(g.drawOrder) || g.drawOrder[j].z != s.z {
+			break
+		}
+		other := g.drawOrder[j]
+		if ySorted && other.y != s.y {
+			break
+		}
+		s.order, other.order = other.order, s.order
+		g.drawOrder[i], g.drawOrder[j] = other, s
+		other.drawIndex = i
+		s.drawIndex = j
+		i = j
+		g.drawOrderChanged = true
+	}
+}
+
+func (g *EbitenGame) setYSort(z float64, enabled bool) {
+	if enabled {
+		g.ySortLayers[z] = true
+	} else {
+		delete(g.ySortLayers, z)
+	}
+	g.drawOrderDirty = true
+}
@@ -0,0 +1,123 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/stretchr/testify/require"
+)
+
+// The sprite IDs from back to front.
+func (g *EbitenGame) drawnIDs() []int {
+	g.sortDrawOrder()
+	ret := make([]int, len(g.drawOrder))
+	for i, s := range g.drawOrder {
+		ret[i] = s.id
+	}
+	return ret
+}
+
+func TestDrawOrder(t *testing.T) {
+	type sprite struct{ z, y float64 }
+	front := func(id int) any {
+		return spritesmodels.CmdSpriteDrawOrder{SpriteID: id, Action: spritesmodels.OrderFront}
+	}
+	back := func(id int) any {
+		return spritesmodels.CmdSpriteDrawOrder{SpriteID: id, Action: spritesmodels.OrderBack}
+	}
+	forward := func(id, steps int) any {
+		return spritesmodels.CmdSpriteDrawOrder{SpriteID: id, Action: spritesmodels.OrderForward, Steps: steps}
+	}
+	backward := func(id, steps int) any {
+		return spritesmodels.CmdSpriteDrawOrder{SpriteID: id, Action: spritesmodels.OrderBackward, Steps: steps}
+	}
+
+	tests := []struct {
+		name     string
+		sprites  []sprite // IDs are the index
+		ySortZ   []float64
+		cmds     []any
+		expected []int
+	}{
+		{"Added order", []sprite{{}, {}, {}}, nil, nil, []int{0, 1, 2}},
+		{"Z first", []sprite{{z: 2}, {z: -1}, {}, {z: 2}}, nil, nil, []int{1, 2, 0, 3}},
+		{"Front", []sprite{{}, {}, {}}, nil, []any{front(0)}, []int{1, 2, 0}},
+		{"Back", []sprite{{}, {}, {}}, nil, []any{back(2)}, []int{2, 0, 1}},
+		{"Front stays in its z", []sprite{{}, {}, {z: 1}}, nil, []any{front(0)}, []int{1, 0, 2}},
+		{"Back then front", []sprite{{}, {}, {}}, nil, []any{back(2), front(2), back(0)}, []int{0, 1, 2}},
+		{"Forward", []sprite{{}, {}, {}, {}}, nil, []any{forward(0, 2)}, []int{1, 2, 0, 3}},
+		{"Backward", []sprite{{}, {}, {}, {}}, nil, []any{backward(3, 1)}, []int{0, 1, 3, 2}},
+		{"Forward stops at the z", []sprite{{}, {}, {z: 1}}, nil, []any{forward(0, 5)}, []int{1, 0, 2}},
+		{"Backward stops at the z", []sprite{{z: -1}, {}, {}}, nil, []any{backward(2, 5)}, []int{0, 2, 1}},
+		{"Backward stops at the end", []sprite{{}, {}}, nil, []any{backward(1, 5)}, []int{1, 0}},
+		{"Forward twice", []sprite{{}, {}, {}}, nil, []any{forward(0, 1), forward(0, 1)}, []int{1, 2, 0}},
+		{"Y-sort", []sprite{{y: 1}, {y: 3}, {y: 2}, {z: 1, y: 5}}, []float64{0}, nil, []int{1, 2, 0, 3}},
+		{"Y-sort ties", []sprite{{y: 1}, {y: 1}, {y: 2}}, []float64{0}, []any{front(0)}, []int{2, 1, 0}},
+		{"Y-sort front", []sprite{{y: 1}, {y: 2}}, []float64{0}, []any{front(1)}, []int{1, 0}},
+		{"Y-sort forward", []sprite{{y: 2}, {y: 1}, {y: 1}}, []float64{0}, []any{forward(1, 5)}, []int{0, 2, 1}},
+		{"Y-sort backward", []sprite{{y: 1}, {y: 1}, {y: 0}}, []float64{0}, []any{backward(1, 5)}, []int{1, 0, 2}},
+		{
+			"Y-sort forward past a different y does nothing", []sprite{{y: 1}, {y: 0}}, []float64{0},
+			[]any{forward(0, 1), spritesmodels.CmdSpriteUpdateMin{SpriteID: 1, Y: 1}}, []int{0, 1},
+		},
+		{"Other z not Y-sorted", []sprite{{z: 1, y: 1}, {z: 1, y: 3}}, []float64{0}, []any{forward(0, 1)}, []int{1, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGame()
+			for _, z := range tt.ySortZ {
+				g.cmdQueue.Push(spritesmodels.CmdSetYSort{Z: z, Enabled: true})
+			}
+			for _, s := range tt.sprites {
+				id := g.GetNextSpriteID()
+				g.cmdQueue.Push(spritesmodels.CmdAddNewSprite{SpriteID: id})
+				g.cmdQueue.Push(spritesmodels.CmdSpriteUpdateFull{SpriteID: id, CostumeName: "a", Z: s.z, Y: s.y})
+			}
+			g.processSpriteCommands()
+			g.sortDrawOrder()
+			for _, cmd := range tt.cmds {
+				g.cmdQueue.Push(cmd)
+			}
+			g.processSpriteCommands()
+			require.Equal(t, tt.expected, g.drawnIDs())
+
+			// And again on the next frame.
+			g.drawOrderDirty = true
+			require.Equal(t, tt.expected, g.drawnIDs())
+		})
+	}
+}
+
+func TestDrawOrderSetZ(t *testing.T) {
+	g := newTestGame()
+	for range 3 {
+		g.addTestSprite()
+	}
+
+	// A sprite that changes z goes on top of that z, and it stays there when it comes back.
+	g.cmdQueue.Push(spritesmodels.CmdSpriteUpdateFull{SpriteID: 0, CostumeName: "a", Z: 1})
+	g.processSpriteCommands()
+	require.Equal(t, []int{1, 2, 0}, g.drawnIDs())
+	g.cmdQueue.Push(spritesmodels.CmdSpriteUpdateFull{SpriteID: 0, CostumeName: "a", Z: 0})
+	g.processSpriteCommands()
+	require.Equal(t, []int{1, 2, 0}, g.drawnIDs())
+	g.cmdQueue.Push(spritesmodels.CmdSpriteUpdateFull{SpriteID: 2, CostumeName: "a", Z: 0})
+	g.processSpriteCommands()
+	require.Equal(t, []int{1, 2, 0}, g.drawnIDs())
+
+	// Deleted sprites drop out.
+	g.cmdQueue.Push(spritesmodels.CmdSpriteDelete{SpriteID: 2})
+	g.processSpriteCommands()
+	require.Equal(t, []int{1, 0}, g.drawnIDs())
+	require.Equal(t, 1, g.getSprite(0).drawIndex)
+
+	// Turning off the Y-sort goes back to the order values.
+	g.cmdQueue.Push(spritesmodels.CmdSetYSort{Z: 0, Enabled: true})
+	g.cmdQueue.Push(spritesmodels.CmdSpriteUpdateMin{SpriteID: 1, Y: -5})
+	g.processSpriteCommands()
+	require.Equal(t, []int{0, 1}, g.drawnIDs())
+	g.cmdQueue.Push(spritesmodels.CmdSetYSort{Z: 0, Enabled: false})
+	g.processSpriteCommands()
+	require.Equal(t, []int{1, 0}, g.drawnIDs())
+}
@@ -1,27 +1,30 @@
 package game
 
 import (
-	"fmt"
 	"image"
+	"image/color"
 	"log"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/gary23b/sprites/spritesmodels"
 	"github.com/gary23b/sprites/spritestools"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/audio"
-	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 )
 
 const (
-	sampleRate = 48000
+	SampleRate = 48000 // All sounds are converted to 16 bit stereo PCM at this rate.
 )
 
 type ebitenSprite struct {
-	id         int // used in idToSpriteMap as the key to point to this struct
-	z          int // The current layer. 0-9 allowed
-	arrayIndex int // Used for moving a sprite to a new layer
+	id        int     // used in idToSpriteMap as the key to point to this struct
+	z         float64 // Higher is drawn on top
+	order     int     // The draw order among sprites with the same z
+	drawIndex int     // Where the sprite is in g.drawOrder after the last sort
+	deleted   bool    // Removed from g.drawOrder on the next sort
 
 	CostumeIndex int // the index to use to get the current sprite bitmap costume from g.costumes[]
 
@@ -30,66 +33,139 @@ type ebitenSprite struct {
 	visible        bool
 	xScale, yScale float64
 	opacity        float64
+	flipX, flipY   bool
+	pivotX, pivotY float64 // Cartesian offset from the costume center
+	skewX, skewY   float64 // radians
+	effects        spritesmodels.SpriteEffects
+	shader         *shaderUse                // nil when drawn normally
+	kinematics     *spritesmodels.Kinematics // nil when not moving on its own
+	generation     int                       // The newest one the sim sent. See KinematicsUpdate.
 }
 
 ////////////////////////////////
 
 type EbitenGame struct {
-	screenWidth       int
-	screenHeight      int
-	showFPS           bool
-	justPressedBroker *spritestools.Broker[*spritesmodels.UserInput]
-	exitFlag          bool
+	screenWidth  int
+	screenHeight int
+	showHUD      bool // The debug HUD. Toggled with F3.
+	showOverlay  bool // The debug overlay of sprite bounds and click bodies. Toggled with F4.
+
+	debugOverlaySource func() spritesmodels.DebugOverlayInfo
+	justPressedBroker  *spritestools.Broker[*spritesmodels.UserInput]
+	exitFlag           bool
 
 	controlState        SavedControlState
 	controlsPressed     *spritesmodels.UserInput
 	controlsJustPressed *spritesmodels.UserInput
+	inputRecorder       *spritestools.InputRecorder // nil unless recording
+	inputReplayer       *spritestools.InputReplayer // nil unless replaying
 
-	cmdChan      chan any
+	cmdQueue     *spritestools.CommandQueue
+	cmdChanOnce  sync.Once
+	cmdChan      chan any   // Only made by the deprecated GetSpriteCmdChannel
 	spriteMutex  sync.Mutex // only for protecting nextSpriteID
 	nextSpriteID int
-	idToSprite   []*ebitenSprite
-	sprites      [][]*ebitenSprite // The sprites separated into layers 0 through 9
+	idToSprite   []*ebitenSprite // Only used on the game loop
+
+	drawOrder      []*ebitenSprite // Sorted by sortDrawOrder
+	drawOrderDirty bool
+	nextFrontOrder int
+	nextBackOrder  int
+	ySortLayers    map[float64]bool
+
+	drawOrderChanged bool       // Since publishDrawOrder last ran
+	drawIndexMutex   sync.Mutex // Protects drawIndexByID, which DrawIndex reads from the sim go routines
+	drawIndexByID    []int      // Sprite ID to its draw index plus one, as of the last frame
 
-	costumes           []ebiten.Image
+	moving            map[int]*ebitenSprite // Sprites with kinematics
+	kinematicsUpdates chan []spritesmodels.KinematicsUpdate
+	pendingKinematics []spritesmodels.KinematicsUpdate // Not yet taken by the sim
+
+	costumes           []*ebiten.Image // Often sub-images of the atlas pages
 	nameToCostumeIDMap map[string]int
+	placeholderImg     image.Image
+	atlasPacker        *spritestools.AtlasPacker
+	atlasPages         []*ebiten.Image
+	atlasSlots         map[int]atlasSlot // Costume IDs that were packed into atlasPages
+	effectBuffers      map[effectBufferKey]*ebiten.Image
+
+	// Shaders:
+	shaders     map[string]*ebiten.Shader
+	postProcess []*shaderUse     // Run in order on the whole frame
+	postBuffers [2]*ebiten.Image // The offscreen frame and the ping-pong buffer for the passes
+	startTime   time.Time        // For the Time uniform
 
 	// Sounds:
 	audioContext *audio.Context
 	sounds       map[string][]byte
+	soundMutex   sync.Mutex // only for protecting nextSoundID
+	nextSoundID  int
+	soundPlayers map[int]*soundPlayer // Sounds that are playing or paused, by handle ID
+	music        *soundPlayer         // The streamed music track, if any
+	buses        map[string]*mixerBus
+	masterVolume float64
+	masterMuted  bool
 
 	screenShotRequests []chan image.Image
+
+	statsMutex sync.Mutex // Protects stats, which is read from the sim go routines
+	stats      spritesmodels.RenderStats
+	drawCalls  int // Counted during Draw
 }
 
 type GameInitStruct struct {
-	Width             int
-	Height            int
-	ShowFPS           bool
-	JustPressedBroker *spritestools.Broker[*spritesmodels.UserInput]
+	Width   int
+	Height  int
+	ShowFPS bool // Start with the debug HUD showing
+	// Called from Draw while the debug overlay is showing, for the things only the sim knows about.
+	DebugOverlaySource func() spritesmodels.DebugOverlayInfo
+	JustPressedBroker  *spritestools.Broker[*spritesmodels.UserInput]
+	InputRecorder      *spritestools.InputRecorder // Optional. Every tick of user input is written to it.
+	InputReplayer      *spritestools.InputReplayer // Optional. Used in place of live user input until it runs out.
+	// Optional. Where sprites moved by their kinematics each tick are sent. Sends never block the game.
+	KinematicsUpdates chan []spritesmodels.KinematicsUpdate
+	CmdQueue          *spritestools.CommandQueue // Optional. Where the sim sends its commands.
 }
 
 func NewGame(init GameInitStruct) *EbitenGame {
 	g := &EbitenGame{
-		screenWidth:       init.Width,
-		screenHeight:      init.Height,
-		showFPS:           init.ShowFPS,
-		justPressedBroker: init.JustPressedBroker,
-
-		cmdChan:      make(chan any, 100000),
-		nextSpriteID: 0,
-		sprites:      make([][]*ebitenSprite, 10),
-		idToSprite:   make([]*ebitenSprite, 0, 31000), // Not sure if this should be an list or map...
-
-		costumes:           make([]ebiten.Image, 0, 1000),
+		screenWidth:        init.Width,
+		screenHeight:       init.Height,
+		showHUD:            init.ShowFPS,
+		debugOverlaySource: init.DebugOverlaySource,
+		justPressedBroker:  init.JustPressedBroker,
+		inputRecorder:      init.InputRecorder,
+		inputReplayer:      init.InputReplayer,
+		kinematicsUpdates:  init.KinematicsUpdates,
+
+		cmdQueue:      init.CmdQueue,
+		nextSpriteID:  0,
+		idToSprite:    make([]*ebitenSprite, 0, 31000), // Not sure if this should be an list or map...
+		drawOrder:     make([]*ebitenSprite, 0, 31000),
+		ySortLayers:   make(map[float64]bool),
+		moving:        make(map[int]*ebitenSprite),
+		nextBackOrder: -1,
+
+		costumes:           make([]*ebiten.Image, 0, 1000),
 		nameToCostumeIDMap: make(map[string]int),
+		atlasPacker:        spritestools.NewAtlasPacker(atlasPageSize, atlasPageSize, atlasPadding),
+		atlasSlots:         make(map[int]atlasSlot),
+		effectBuffers:      make(map[effectBufferKey]*ebiten.Image),
+
+		shaders:   make(map[string]*ebiten.Shader),
+		startTime: time.Now(),
 
-		audioContext: audio.NewContext(sampleRate),
+		audioContext: audio.NewContext(SampleRate),
 		sounds:       make(map[string][]byte),
+		soundPlayers: make(map[int]*soundPlayer),
+		buses:        make(map[string]*mixerBus),
+		masterVolume: 1,
 	}
 
-	for i := 0; i < 10; i++ {
-		g.sprites[i] = make([]*ebitenSprite, 0, 31000)
+	if g.cmdQueue == nil {
+		g.cmdQueue = spritestools.NewCommandQueue(spritestools.DefaultCommandQueueSize, spritesmodels.OverflowBlock, nil)
 	}
+	g.addBuiltinShaders()
 
 	ebiten.SetTPS(120)
 	// ebiten.SetVsyncEnabled(false) // For some reason, on Windows, there is quite a bit of lag.
@@ -100,15 +176,24 @@ func NewGame(init GameInitStruct) *EbitenGame {
 }
 
 func (g *EbitenGame) deleteAllSprite() {
-	// Deleting everything means just allocating new arrays.
-	g.idToSprite = make([]*ebitenSprite, 0, 31000)
-	g.sprites = make([][]*ebitenSprite, 10)
-	for i := 0; i < 10; i++ {
-		g.sprites[i] = make([]*ebitenSprite, 0, 31000)
-	}
+	// IDs keep counting up, so idToSprite keeps its length. Clearing it lets the sprites be garbage collected.
+	clear(g.idToSprite)
+	g.drawOrder = make([]*ebitenSprite, 0, 31000)
+	g.drawOrderChanged = true
+	g.moving = make(map[int]*ebitenSprite)
 }
 
+// Deprecated: Push to the CommandQueue passed in as GameInitStruct.CmdQueue instead. Commands sent on this channel are
+// moved to that queue by another go routine, so they can land after commands pushed to the queue directly.
 func (g *EbitenGame) GetSpriteCmdChannel() chan any {
+	g.cmdChanOnce.Do(func() {
+		g.cmdChan = make(chan any, 100000)
+		go func() {
+			for cmd := range g.cmdChan {
+				g.cmdQueue.Push(cmd)
+			}
+		}()
+	})
 	return g.cmdChan
 }
 
@@ -122,115 +207,215 @@ func (g *EbitenGame) GetNextSpriteID() int {
 
 	newID := g.nextSpriteID
 	g.nextSpriteID++
-	g.idToSprite = append(g.idToSprite, nil)
-
 	return newID
 }
 
+// Returns nil if the sprite was deleted or the ID is not valid.
+func (g *EbitenGame) getSprite(spriteID int) *ebitenSprite {
+	if spriteID < 0 || spriteID >= len(g.idToSprite) {
+		return nil
+	}
+	return g.idToSprite[spriteID]
+}
+
 func (g *EbitenGame) addSprite(newID int) {
-	newArrayIndex := len(g.sprites[0])
-	newSprite := ebitenSprite{
+	newSprite := &ebitenSprite{
 		id:           newID,
 		z:            0,
-		arrayIndex:   newArrayIndex,
+		order:        g.nextFrontOrder,
 		opacity:      100,
 		CostumeIndex: -1,
 	}
-
-	g.sprites[0] = append(g.sprites[0], &newSprite)
-	g.idToSprite[newSprite.id] = g.sprites[0][newSprite.arrayIndex]
+	g.nextFrontOrder++
+
+	g.drawOrder = append(g.drawOrder, newSprite)
+	g.drawOrderDirty = true
+	// The sim hands out IDs from its own go routines, so idToSprite only grows here on the game loop.
+	if oldLen := len(g.idToSprite); newID >= oldLen {
+		g.idToSprite = slices.Grow(g.idToSprite, newID+1-oldLen)[:newID+1]
+		clear(g.idToSprite[oldLen:])
+	}
+	g.idToSprite[newSprite.id] = newSprite
 }
 
 func (g *EbitenGame) addSpriteCostume(img image.Image, costumeName string) {
-	newSprite := ebiten.NewImageFromImage(img)
-
 	// check if we should replace an existing costume:
 	id, ok := g.nameToCostumeIDMap[costumeName]
 	if ok {
-		g.costumes[id] = *newSprite
+		if g.replaceAtlasCostume(id, img) {
+			return
+		}
 		// fmt.Printf("Replacing a sprite: %s\n", costumeName)
-		return
 	}
 
-	g.costumes = append(g.costumes, *newSprite)
-	g.nameToCostumeIDMap[costumeName] = len(g.costumes) - 1
+	newSprite, slot, inAtlas := g.newCostumeImage(img)
+	id = g.setCostume(costumeName, newSprite)
+	if inAtlas {
+		g.atlasSlots[id] = slot
+	}
 	// fmt.Printf("creating a new sprite: %s\n", costumeName)
 }
 
+// Returns the costume ID.
+func (g *EbitenGame) setCostume(costumeName string, img *ebiten.Image) int {
+	id, ok := g.nameToCostumeIDMap[costumeName]
+	if ok {
+		g.costumes[id] = img
+	} else {
+		g.costumes = append(g.costumes, img)
+		id = len(g.costumes) - 1
+		g.nameToCostumeIDMap[costumeName] = id
+	}
+	return id
+}
+
+// Sprites can use a costume that is still loading. They show the placeholder until the real costume replaces it.
+func (g *EbitenGame) addPlaceholderCostume(costumeName string) {
+	if _, ok := g.nameToCostumeIDMap[costumeName]; ok {
+		return
+	}
+
+	if g.placeholderImg == nil {
+		g.placeholderImg = createPlaceholderImg()
+	}
+	g.addSpriteCostume(g.placeholderImg, costumeName)
+}
+
+// A grey checkerboard
+func createPlaceholderImg() image.Image {
+	const size, square = 32, 8
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			c := color.RGBA{0xA0, 0xA0, 0xA0, 0xFF}
+			if (x/square+y/square)%2 == 0 {
+				c = color.RGBA{0xD3, 0xD3, 0xD3, 0xFF}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
 func (g *EbitenGame) deleteSprite(spriteIndex int) {
-	s := g.idToSprite[spriteIndex]
+	s := g.getSprite(spriteIndex)
+	if s == nil {
+		return
+	}
 	g.idToSprite[spriteIndex] = nil
-	g.sprites[s.z][s.arrayIndex] = nil
+	s.deleted = true
+	g.drawOrderDirty = true
 
 	s.visible = false
 	// Ideally when this function returns, there will be no more refs to the struct, so it will be garbage collected.
 }
 
-func (g *EbitenGame) moveSpriteToNewLayer(s *ebitenSprite, newZ int) *ebitenSprite {
-	g.sprites[s.z][s.arrayIndex] = nil
-	g.sprites[newZ] = append(g.sprites[newZ], s)
-	newIndex := len(g.sprites[newZ]) - 1
-	g.idToSprite[s.id] = s
-	s.arrayIndex = newIndex
-	s.z = newZ
-	return s
+func (g *EbitenGame) updateSpriteFull(cmd spritesmodels.CmdSpriteUpdateFull) {
+	s := g.getSprite(cmd.SpriteID)
+	if s == nil {
+		return
+	}
+	s.generation = cmd.Generation
+	g.setSpriteZ(s, cmd.Z)
+
+	costumeID, ok := g.nameToCostumeIDMap[cmd.CostumeName]
+	if !ok {
+		log.Printf("The given costume name is not valid: %d, %s\n", cmd.SpriteID, cmd.CostumeName)
+		return
+	}
+	s.CostumeIndex = costumeID
+	s.x = cmd.X
+	s.y = cmd.Y
+	s.angleRad = cmd.Angle
+	s.visible = cmd.Visible
+	s.xScale = cmd.XScale
+	s.yScale = cmd.YScale
+	s.opacity = cmd.Opacity
+	s.flipX = cmd.FlipX
+	s.flipY = cmd.FlipY
+	s.pivotX = cmd.PivotX
+	s.pivotY = cmd.PivotY
+	s.skewX = cmd.SkewX
+	s.skewY = cmd.SkewY
+	s.effects = cmd.Effects
 }
 
 func (g *EbitenGame) processSpriteCommands() {
-	// Pull all the items out of the command channel until it is empty.
-EatSpritesCmdLoop:
-	for {
-		select {
-		case cmd := <-g.cmdChan:
-			switch v := cmd.(type) {
-			case spritesmodels.CmdSpriteUpdateMin:
-				s := g.idToSprite[v.SpriteID]
-				s.x = v.X
-				s.y = v.Y
-				s.angleRad = v.AngleRad
-
-			case spritesmodels.CmdSpriteUpdateFull:
-				s := g.idToSprite[v.SpriteID]
-				if s.z != v.Z {
-					s = g.moveSpriteToNewLayer(s, v.Z)
-				}
-
-				costumeID, ok := g.nameToCostumeIDMap[v.CostumeName]
-				if !ok {
-					log.Printf("The given costume name is not valid: %d, %s\n", v.SpriteID, v.CostumeName)
-					continue
-				}
-				s.CostumeIndex = costumeID
-				s.x = v.X
-				s.y = v.Y
-				s.angleRad = v.Angle
-				s.visible = v.Visible
-				s.xScale = v.XScale
-				s.yScale = v.YScale
-				s.opacity = v.Opacity
-			case spritesmodels.CmdAddNewSprite:
-				g.addSprite(v.SpriteID)
-			case spritesmodels.CmdAddCostume:
-				g.addSpriteCostume(v.Img, v.CostumeName)
-			case spritesmodels.CmdSpriteDelete:
-				g.deleteSprite(v.SpriteID)
-			case spritesmodels.CmdSpritesDeleteAll:
-				g.deleteAllSprite()
-			// Sounds
-			case spritesmodels.CmdAddSound:
-				g.addSound(v.Path, v.SoundName)
-
-			case spritesmodels.CmdPlaySound:
-				g.playSound(v.SoundName, v.Volume)
-
-			case spritesmodels.CmdGetScreenshot:
-				g.screenShotRequests = append(g.screenShotRequests, v.ImageChan)
-
-			default:
-				log.Printf("I don't know about type %T!\n", v)
+	// Everything the sim sent since the last update.
+	for _, cmd := range g.cmdQueue.TakeAll() {
+		switch v := cmd.(type) {
+		case spritesmodels.CmdSpriteUpdateMin:
+			s := g.getSprite(v.SpriteID)
+			if s == nil {
+				continue
+			}
+			s.x = v.X
+			s.y = v.Y
+			s.angleRad = v.AngleRad
+			s.generation = v.Generation
+
+		case spritesmodels.CmdSpriteUpdateFull:
+			g.updateSpriteFull(v)
+		case spritesmodels.CmdSpriteBatch:
+			for _, add := range v.Adds {
+				g.addSprite(add.SpriteID)
+			}
+			for _, update := range v.Updates {
+				g.updateSpriteFull(update)
 			}
+		case spritesmodels.CmdAddNewSprite:
+			g.addSprite(v.SpriteID)
+		case spritesmodels.CmdAddCostume:
+			g.addSpriteCostume(v.Img, v.CostumeName)
+		case spritesmodels.CmdAddAtlas:
+			g.addAtlas(v.Img, v.Frames)
+		case spritesmodels.CmdAddPlaceholderCostume:
+			g.addPlaceholderCostume(v.CostumeName)
+		case spritesmodels.CmdAddShader:
+			v.ErrChan <- g.addShader(v.ShaderName, v.Source)
+		case spritesmodels.CmdSpriteShader:
+			g.setSpriteShader(v)
+		case spritesmodels.CmdSetPostProcessing:
+			g.setPostProcessing(v.Passes)
+		case spritesmodels.CmdSpriteKinematics:
+			g.setKinematics(v)
+		case spritesmodels.CmdSpriteDrawOrder:
+			g.changeDrawOrder(v)
+		case spritesmodels.CmdSetYSort:
+			g.setYSort(v.Z, v.Enabled)
+		case spritesmodels.CmdSpriteDelete:
+			g.deleteSprite(v.SpriteID)
+		case spritesmodels.CmdSpritesDeleteAll:
+			g.deleteAllSprite()
+		// Sounds
+		case spritesmodels.CmdAddSoundData:
+			g.sounds[v.SoundName] = v.Data
+		case spritesmodels.CmdAddSound: //nolint:staticcheck
+			g.addSound(v)
+
+		case spritesmodels.CmdPlaySound:
+			g.playSound(v)
+		case spritesmodels.CmdSoundControl:
+			g.controlSound(v)
+		case spritesmodels.CmdPlayMusic:
+			g.playMusic(v.Path, v.Loop, v.Volume)
+		case spritesmodels.CmdStopMusic:
+			g.stopMusic()
+		case spritesmodels.CmdSetBusVolume:
+			g.setBusVolume(v.Bus, v.Volume)
+		case spritesmodels.CmdMuteBus:
+			g.muteBus(v.Bus, v.Mute)
+
+		case spritesmodels.CmdShowDebugHUD:
+			g.showHUD = v.Show
+		case spritesmodels.CmdShowDebugOverlay:
+			g.showOverlay = v.Show
+
+		case spritesmodels.CmdGetScreenshot:
+			g.screenShotRequests = append(g.screenShotRequests, v.ImageChan)
+
 		default:
-			break EatSpritesCmdLoop
+			log.Printf("I don't know about type %T!\n", v)
 		}
 	}
 }
@@ -242,7 +427,15 @@ EatSpritesCmdLoop:
 
 // This function will not return. It must be run on the main thread.
 func (g *EbitenGame) RunGame() {
-	if err := ebiten.RunGame(g); err != nil {
+	err := ebiten.RunGame(g)
+
+	if g.inputRecorder != nil {
+		if err := g.inputRecorder.Close(); err != nil {
+			log.Println(err)
+		}
+	}
+
+	if err != nil {
 		log.Fatal(err)
 	}
 }
@@ -251,55 +444,94 @@ func (g *EbitenGame) Update() error {
 	if g.exitFlag {
 		return ebiten.Termination
 	}
+	start := time.Now()
+	queueDepth := g.cmdQueue.Len()
 
-	g.controlsPressed, g.controlsJustPressed = g.controlState.GetUserInput(g.screenWidth, g.screenHeight)
+	g.updateUserInput()
 	if g.controlsJustPressed.AnyPressed {
 		g.justPressedBroker.Publish(g.controlsJustPressed)
 	}
 
 	g.processSpriteCommands()
+	g.updateKinematics()
+	g.updateSounds()
+	g.updateDebugHUDToggle()
+	g.updateDebugOverlayToggle()
+
+	g.statsMutex.Lock()
+	g.stats.CommandQueueDepth = queueDepth
+	g.stats.UpdateTime = time.Since(start)
+	g.stats.TPS = ebiten.ActualTPS()
+	g.statsMutex.Unlock()
 
 	return nil
 }
 
 func (g *EbitenGame) Draw(screen *ebiten.Image) {
+	start := time.Now()
+	frame := g.frameTarget(screen)
 	op := ebiten.DrawImageOptions{}
 	count := 0
-	for i := range g.sprites {
-		a := g.sprites[i]
-		for j := range a {
-			sprite := a[j]
-			if sprite == nil {
-				continue
-			}
-			if !sprite.visible {
-				continue
-			}
-			if sprite.CostumeIndex < 0 {
-				continue
-			}
-			op.GeoM.Reset()
-			op.ColorScale.Reset()
-			costume := g.costumes[sprite.CostumeIndex]
-			w, h := costume.Bounds().Dx(), costume.Bounds().Dy()
-			op.GeoM.Translate(-float64(w)/2, -float64(h)/2) // Move the center to (0,0) so that we can rotate around the center.
-			op.GeoM.Scale(sprite.xScale, sprite.yScale)
-			op.GeoM.Rotate(-sprite.angleRad) // This command rotates clockwise for some reason.
-
-			op.GeoM.Translate(float64(g.screenWidth/2), float64(g.screenHeight/2)) // (0,0) is in the center for Cartesian coordinates
-			op.GeoM.Translate(sprite.x, -sprite.y)
-
-			if sprite.opacity != 100 {
-				op.ColorScale.SetA(float32(sprite.opacity) / 100)
-			}
+	culled := 0
+	g.drawCalls = 0
+	g.sortDrawOrder()
+	g.publishDrawOrder()
+	for i := range g.drawOrder {
+		sprite := g.drawOrder[i]
+		if !sprite.visible {
+			continue
+		}
+		if sprite.CostumeIndex < 0 {
+			continue
+		}
+		costume := g.costumes[sprite.CostumeIndex]
+		w, h := costume.Bounds().Dx(), costume.Bounds().Dy()
+		op.GeoM = g.spriteGeoM(sprite, w, h)
+		op.ColorScale.Reset()
+
+		if !g.isOnScreen(op.GeoM, w, h) {
+			culled++
+			continue
+		}
+
+		if sprite.shader != nil && g.drawWithShader(frame, costume, sprite, op.GeoM) {
+			count++
+			continue
+		}
 
-			screen.DrawImage(&costume, &op)
+		if sprite.effects != (spritesmodels.SpriteEffects{}) {
+			g.drawWithEffects(frame, costume, sprite, op.GeoM)
 			count++
+			continue
 		}
+
+		if sprite.opacity != 100 {
+			op.ColorScale.SetA(float32(sprite.opacity) / 100)
+		}
+
+		frame.DrawImage(costume, &op)
+		g.drawCalls++
+		count++
 	}
 
-	if g.showFPS {
-		ebitenutil.DebugPrint(screen, fmt.Sprintf("FPS: %0.2f, TPS: %0.2f, Cnt: %d", ebiten.ActualFPS(), ebiten.ActualTPS(), count))
+	if frame != screen {
+		g.applyPostProcessing(screen, frame)
+	}
+
+	g.statsMutex.Lock()
+	g.stats.FPS = ebiten.ActualFPS()
+	g.stats.SpritesDrawn = count
+	g.stats.SpritesCulled = culled
+	g.stats.DrawCalls = g.drawCalls
+	g.stats.DrawTime = time.Since(start)
+	stats := g.stats
+	g.statsMutex.Unlock()
+
+	if g.showOverlay {
+		g.drawDebugOverlay(screen)
+	}
+	if g.showHUD {
+		g.drawDebugHUD(screen, stats)
 	}
 
 	if len(g.screenShotRequests) > 0 {
@@ -312,6 +544,53 @@ func (g *EbitenGame) Draw(screen *ebiten.Image) {
 	}
 }
 
+// Maps costume pixels to the screen.
+func (g *EbitenGame) spriteGeoM(sprite *ebitenSprite, w, h int) ebiten.GeoM {
+	geoM := ebiten.GeoM{}
+	geoM.Translate(-float64(w)/2, -float64(h)/2) // Move the center to (0,0) so that we can rotate around the center.
+	// The pivot is Cartesian, so y is flipped compared to the image. This matches spritestools.LocalToWorld.
+	geoM.Translate(-sprite.pivotX, sprite.pivotY)
+	if sprite.flipX || sprite.flipY {
+		geoM.Scale(flipSign(sprite.flipX), flipSign(sprite.flipY))
+	}
+	if sprite.skewX != 0 || sprite.skewY != 0 {
+		geoM.Skew(-sprite.skewX, -sprite.skewY)
+	}
+	geoM.Scale(sprite.xScale, sprite.yScale)
+	geoM.Rotate(-sprite.angleRad) // This command rotates clockwise for some reason.
+
+	geoM.Translate(float64(g.screenWidth/2), float64(g.screenHeight/2)) // (0,0) is in the center for Cartesian coordinates
+	geoM.Translate(sprite.x, -sprite.y)
+	return geoM
+}
+
+func flipSign(flip bool) float64 {
+	if flip {
+		return -1
+	}
+	return 1
+}
+
+func (g *EbitenGame) updateUserInput() {
+	if g.inputReplayer != nil {
+		var done bool
+		g.controlsPressed, g.controlsJustPressed, done = g.inputReplayer.Next()
+		if done {
+			log.Println("Input replay finished, switching to live input")
+			g.inputReplayer = nil
+		}
+	} else {
+		g.controlsPressed, g.controlsJustPressed = g.controlState.GetUserInput(g.screenWidth, g.screenHeight)
+	}
+
+	if g.inputRecorder != nil {
+		if err := g.inputRecorder.Record(g.controlsPressed, g.controlsJustPressed); err != nil {
+			log.Println(err)
+			g.inputRecorder = nil
+		}
+	}
+}
+
 func (g *EbitenGame) Layout(outsideWidth, outsideHeight int) (int, int) {
 	return g.screenWidth, g.screenHeight
 }
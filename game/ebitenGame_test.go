@@ -0,0 +1,114 @@
+package game
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/gary23b/sprites/spritestools"
+	"github.com/stretchr/testify/require"
+)
+
+// A game that can process sprite commands without a window or audio.
+func newTestGame() *EbitenGame {
+	return &EbitenGame{
+		cmdQueue:           spritestools.NewCommandQueue(spritestools.DefaultCommandQueueSize, spritesmodels.OverflowBlock, nil),
+		ySortLayers:        make(map[float64]bool),
+		moving:             make(map[int]*ebitenSprite),
+		nextBackOrder:      -1,
+		nameToCostumeIDMap: map[string]int{"a": 0},
+	}
+}
+
+func (g *EbitenGame) addTestSprite() int {
+	id := g.GetNextSpriteID()
+	g.cmdQueue.Push(spritesmodels.CmdAddNewSprite{SpriteID: id})
+	g.processSpriteCommands()
+	return id
+}
+
+func TestDeleteAllSprites(t *testing.T) {
+	g := newTestGame()
+	first := g.addTestSprite()
+	g.addTestSprite()
+
+	g.cmdQueue.Push(spritesmodels.CmdSpritesDeleteAll{})
+	g.processSpriteCommands()
+	require.Nil(t, g.getSprite(first))
+
+	// New sprites keep counting up from the old IDs.
+	id := g.addTestSprite()
+	require.Equal(t, 2, id)
+	g.cmdQueue.Push(spritesmodels.CmdSpriteUpdateMin{SpriteID: id, X: 5})
+	g.processSpriteCommands()
+	require.Equal(t, 5.0, g.getSprite(id).x)
+	g.sortDrawOrder()
+	require.Len(t, g.drawOrder, 1)
+
+	// Commands for sprites that are gone or never existed are ignored.
+	for _, spriteID := range []int{first, 99, -1} {
+		g.cmdQueue.Push(spritesmodels.CmdSpriteUpdateMin{SpriteID: spriteID})
+		g.cmdQueue.Push(spritesmodels.CmdSpriteUpdateFull{SpriteID: spriteID, CostumeName: "a"})
+		g.cmdQueue.Push(spritesmodels.CmdSpriteKinematics{SpriteID: spriteID})
+		g.cmdQueue.Push(spritesmodels.CmdSpriteShader{SpriteID: spriteID})
+		g.cmdQueue.Push(spritesmodels.CmdSpriteDrawOrder{SpriteID: spriteID, Action: spritesmodels.OrderFront})
+		g.cmdQueue.Push(spritesmodels.CmdSpriteDelete{SpriteID: spriteID})
+	}
+	g.processSpriteCommands()
+}
+
+// Like simState.AddSprite, which runs on the sim go routines. Run with -race.
+func TestAddSpriteWhileProcessingCommands(t *testing.T) {
+	g := newTestGame()
+
+	var wg sync.WaitGroup
+	const adders, loops = 8, 200
+	for range adders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range loops {
+				id := g.GetNextSpriteID()
+				g.cmdQueue.Push(spritesmodels.CmdAddNewSprite{SpriteID: id})
+				g.cmdQueue.Push(spritesmodels.CmdSpriteUpdateMin{SpriteID: id, X: float64(i)})
+				if i == loops/2 {
+					g.cmdQueue.Push(spritesmodels.CmdSpritesDeleteAll{})
+				}
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+			time.Sleep(time.Millisecond)
+		}
+		g.processSpriteCommands()
+	}
+
+	require.Equal(t, adders*loops, g.GetNextSpriteID())
+	require.Len(t, g.idToSprite, adders*loops)
+}
+
+func TestGetSpriteCmdChannel(t *testing.T) {
+	g := newTestGame()
+	cmdChan := g.GetSpriteCmdChannel()
+	require.Equal(t, cmdChan, g.GetSpriteCmdChannel())
+
+	id := g.GetNextSpriteID()
+	cmdChan <- spritesmodels.CmdAddNewSprite{SpriteID: id}
+	cmdChan <- spritesmodels.CmdSpriteUpdateMin{SpriteID: id, X: 5}
+	require.Eventually(t, func() bool {
+		g.processSpriteCommands()
+		s := g.getSprite(id)
+		return s != nil && s.x == 5
+	}, time.Second, time.Millisecond)
+}
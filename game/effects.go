@@ -0,0 +1,138 @@
+package game
+
+import (
+	"image"
+	"math"
+
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/gary23b/sprites/spritestools"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/colorm"
+)
+
+var (
+	blendMultiply = ebiten.Blend{
+		BlendFactorSourceRGB:        ebiten.BlendFactorDestinationColor,
+		BlendFactorSourceAlpha:      ebiten.BlendFactorOne,
+		BlendFactorDestinationRGB:   ebiten.BlendFactorOneMinusSourceAlpha,
+		BlendFactorDestinationAlpha: ebiten.BlendFactorOneMinusSourceAlpha,
+		BlendOperationRGB:           ebiten.BlendOperationAdd,
+		BlendOperationAlpha:         ebiten.BlendOperationAdd,
+	}
+	blendScreen = ebiten.Blend{
+		BlendFactorSourceRGB:        ebiten.BlendFactorOne,
+		BlendFactorSourceAlpha:      ebiten.BlendFactorOne,
+		BlendFactorDestinationRGB:   ebiten.BlendFactorOneMinusSourceColor,
+		BlendFactorDestinationAlpha: ebiten.BlendFactorOneMinusSourceAlpha,
+		BlendOperationRGB:           ebiten.BlendOperationAdd,
+		BlendOperationAlpha:         ebiten.BlendOperationAdd,
+	}
+)
+
+func blendFor(mode spritesmodels.BlendMode) ebiten.Blend {
+	switch mode {
+	case spritesmodels.BlendAdditive:
+		return ebiten.BlendLighter
+	case spritesmodels.BlendMultiply:
+		return blendMultiply
+	case spritesmodels.BlendScreen:
+		return blendScreen
+	default:
+		return ebiten.BlendSourceOver
+	}
+}
+
+type effectBufferKey struct {
+	size    image.Point
+	purpose int
+}
+
+const (
+	bufferMosaic = iota
+	bufferPixelate
+)
+
+// Offscreen images are reused between sprites and frames. Ebiten keeps the draw order straight.
+func (g *EbitenGame) getEffectBuffer(w, h, purpose int) *ebiten.Image {
+	key := effectBufferKey{size: image.Pt(w, h), purpose: purpose}
+	buf, ok := g.effectBuffers[key]
+	if !ok {
+		buf = ebiten.NewImage(w, h)
+		g.effectBuffers[key] = buf
+	}
+	buf.Clear()
+	return buf
+}
+
+// The costume shrunk and repeated n by n times in the same space.
+func (g *EbitenGame) mosaicImage(src *ebiten.Image, n int) *ebiten.Image {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	buf := g.getEffectBuffer(w, h, bufferMosaic)
+
+	op := ebiten.DrawImageOptions{}
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			op.GeoM.Reset()
+			op.GeoM.Scale(1/float64(n), 1/float64(n))
+			op.GeoM.Translate(float64(x*w)/float64(n), float64(y*h)/float64(n))
+			buf.DrawImage(src, &op)
+			g.drawCalls++
+		}
+	}
+	return buf
+}
+
+// The costume shrunk by the block size. It gets scaled back up with nearest filtering to make the blocks.
+func (g *EbitenGame) pixelateImage(src *ebiten.Image, blockSize float64) (*ebiten.Image, float64, float64) {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	sw := max(1, int(math.Ceil(float64(w)/blockSize)))
+	sh := max(1, int(math.Ceil(float64(h)/blockSize)))
+	buf := g.getEffectBuffer(sw, sh, bufferPixelate)
+
+	op := ebiten.DrawImageOptions{}
+	op.GeoM.Scale(float64(sw)/float64(w), float64(sh)/float64(h))
+	op.Filter = ebiten.FilterLinear
+	buf.DrawImage(src, &op)
+	g.drawCalls++
+	return buf, float64(w) / float64(sw), float64(h) / float64(sh)
+}
+
+func clamp01(x float64) float64 {
+	return max(0, min(1, x))
+}
+
+// geoM maps costume pixels to the screen.
+func (g *EbitenGame) drawWithEffects(screen, costume *ebiten.Image, sprite *ebitenSprite, geoM ebiten.GeoM) {
+	e := sprite.effects
+	src := costume
+
+	if e.Mosaic > 1 {
+		src = g.mosaicImage(src, e.Mosaic)
+	}
+
+	if e.Pixelate > 1 {
+		var xScale, yScale float64
+		src, xScale, yScale = g.pixelateImage(src, e.Pixelate)
+		pre := ebiten.GeoM{}
+		pre.Scale(xScale, yScale)
+		pre.Concat(geoM)
+		geoM = pre
+	}
+
+	cm := colorm.ColorM{}
+	m := spritestools.EffectsColorMatrix(e, sprite.opacity)
+	for i := range m {
+		for j := range m[i] {
+			cm.SetElement(i, j, m[i][j])
+		}
+	}
+
+	op := colorm.DrawImageOptions{
+		GeoM:   geoM,
+		Blend:  blendFor(e.Blend),
+		Filter: ebiten.FilterNearest,
+	}
+	colorm.DrawImage(screen, src, cm, &op)
+	g.drawCalls++
+}
@@ -0,0 +1,52 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/stretchr/testify/require"
+)
+
+// Blends one premultiplied color channel the way the GPU would.
+func blendChannel(t *testing.T, b ebiten.Blend, src, srcAlpha, dst float64) float64 {
+	t.Helper()
+	factor := func(f ebiten.BlendFactor) float64 {
+		switch f {
+		case ebiten.BlendFactorZero:
+			return 0
+		case ebiten.BlendFactorOne:
+			return 1
+		case ebiten.BlendFactorSourceColor:
+			return src
+		case ebiten.BlendFactorOneMinusSourceColor:
+			return 1 - src
+		case ebiten.BlendFactorSourceAlpha:
+			return srcAlpha
+		case ebiten.BlendFactorOneMinusSourceAlpha:
+			return 1 - srcAlpha
+		case ebiten.BlendFactorDestinationColor:
+			return dst
+		}
+		t.Fatalf("Unexpected blend factor %v", f)
+		return 0
+	}
+	require.Equal(t, ebiten.BlendOperationAdd, b.BlendOperationRGB)
+	return min(1, src*factor(b.BlendFactorSourceRGB)+dst*factor(b.BlendFactorDestinationRGB))
+}
+
+func TestBlendFor(t *testing.T) {
+	require.Equal(t, ebiten.BlendSourceOver, blendFor(spritesmodels.BlendNormal))
+	require.Equal(t, ebiten.BlendSourceOver, blendFor(spritesmodels.BlendMode(99)))
+	require.Equal(t, ebiten.BlendLighter, blendFor(spritesmodels.BlendAdditive))
+
+	multiply := blendFor(spritesmodels.BlendMultiply)
+	require.InDelta(t, 0.25, blendChannel(t, multiply, 0.5, 1, 0.5), 1e-9)
+	require.InDelta(t, 0.5, blendChannel(t, multiply, 1, 1, 0.5), 1e-9) // White changes nothing
+	require.InDelta(t, 0.5, blendChannel(t, multiply, 0, 0, 0.5), 1e-9) // Nor does a see-through pixel
+
+	screen := blendFor(spritesmodels.BlendScreen)
+	require.InDelta(t, 0.75, blendChannel(t, screen, 0.5, 1, 0.5), 1e-9)
+	require.InDelta(t, 0.5, blendChannel(t, screen, 0, 1, 0.5), 1e-9) // Black changes nothing
+	require.InDelta(t, 1, blendChannel(t, screen, 1, 1, 0.5), 1e-9)
+}
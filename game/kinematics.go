@@ -0,0 +1,54 @@
+package game
+
+import (
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/gary23b/sprites/spritestools"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func (g *EbitenGame) setKinematics(cmd spritesmodels.CmdSpriteKinematics) {
+	s := g.getSprite(cmd.SpriteID)
+	if s == nil {
+		return
+	}
+	s.generation = cmd.Generation
+	if !cmd.Kinematics.Moving() {
+		s.kinematics = nil
+		delete(g.moving, s.id)
+		return
+	}
+	k := cmd.Kinematics
+	s.kinematics = &k
+	g.moving[s.id] = s
+}
+
+// Moves every sprite with a velocity by one tick and tells the sim where they ended up.
+func (g *EbitenGame) updateKinematics() {
+	dt := 1 / float64(ebiten.TPS())
+	halfW, halfH := float64(g.screenWidth)/2, float64(g.screenHeight)/2
+
+	for id, s := range g.moving {
+		if s.deleted || s.kinematics == nil {
+			delete(g.moving, id)
+			continue
+		}
+		update := spritestools.StepKinematics(s.kinematics, s.x, s.y, s.angleRad, dt, halfW, halfH)
+		s.x, s.y, s.angleRad = update.X, update.Y, update.AngleRad
+		update.SpriteID = id
+		update.Generation = s.generation
+		// One that left with EdgeDestroy keeps going until the sim deletes it, in case the sim moved it back since.
+		g.pendingKinematics = append(g.pendingKinematics, update)
+	}
+
+	if len(g.pendingKinematics) == 0 || g.kinematicsUpdates == nil {
+		g.pendingKinematics = g.pendingKinematics[:0]
+		return
+	}
+	// Never wait on the sim. If it is behind, these go out with the next tick's updates.
+	select {
+	case g.kinematicsUpdates <- g.pendingKinematics:
+		g.pendingKinematics = nil
+	default:
+	}
+}
@@ -0,0 +1,104 @@
+package game
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/gary23b/sprites/spritesmodels"
+)
+
+type mixerBus struct {
+	volume float64
+	muted  bool
+}
+
+func (g *EbitenGame) getBus(name string) *mixerBus {
+	if name == "" {
+		name = spritesmodels.BusSfx
+	}
+
+	bus, ok := g.buses[name]
+	if !ok {
+		bus = &mixerBus{volume: 1}
+		g.buses[name] = bus
+	}
+	return bus
+}
+
+// The gain applied on top of each sound's own volume.
+func (g *EbitenGame) busGain(name string) float64 {
+	bus := g.getBus(name)
+	if bus.muted || g.masterMuted {
+		return 0
+	}
+	return bus.volume * g.masterVolume
+}
+
+func (g *EbitenGame) setBusVolume(name string, volume float64) {
+	if name == "" {
+		g.masterVolume = clampVolume(volume)
+		return
+	}
+	g.getBus(name).volume = clampVolume(volume)
+}
+
+// Like setBusVolume, an empty name is the master.
+func (g *EbitenGame) muteBus(name string, mute bool) {
+	if name == "" {
+		g.masterMuted = mute
+		return
+	}
+	g.getBus(name).muted = mute
+}
+
+////////////////////////////////////////////////////////////////////////////////////////
+
+// Applies separate left and right gains to a 16 bit stereo PCM stream. The gains are fixed when the sound starts,
+// so a positional sound doesn't follow a sprite that moves while it plays.
+type panStream struct {
+	src   io.ReadSeeker
+	left  float64
+	right float64
+
+	carry   []byte // Half of a sample that was split between reads
+	channel int    // 0 for left, 1 for right. The channel of the next sample.
+}
+
+func newPanStream(src io.ReadSeeker, left, right float64) *panStream {
+	ret := &panStream{
+		src:   src,
+		left:  left,
+		right: right,
+		carry: make([]byte, 0, 1),
+	}
+	return ret
+}
+
+func (s *panStream) Read(p []byte) (int, error) {
+	offset := copy(p, s.carry)
+	s.carry = s.carry[:0]
+	n, err := s.src.Read(p[offset:])
+	n += offset
+
+	whole := n &^ 1
+	if whole < n {
+		s.carry = append(s.carry, p[whole])
+	}
+
+	gains := [2]float64{s.left, s.right}
+	for i := 0; i < whole; i += 2 {
+		sample := float64(int16(binary.LittleEndian.Uint16(p[i:]))) * gains[s.channel]
+		sample = max(math.MinInt16, min(math.MaxInt16, sample))
+		binary.LittleEndian.PutUint16(p[i:], uint16(int16(sample)))
+		s.channel ^= 1
+	}
+	return whole, err
+}
+
+func (s *panStream) Seek(offset int64, whence int) (int64, error) {
+	pos, err := s.src.Seek(offset, whence)
+	s.carry = s.carry[:0]
+	s.channel = int(pos/2) % 2
+	return pos, err
+}
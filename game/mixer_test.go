@@ -0,0 +1,33 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMixer(t *testing.T) {
+	g := newTestGame()
+	g.buses = make(map[string]*mixerBus)
+	g.masterVolume = 1
+
+	g.setBusVolume(spritesmodels.BusSfx, 0.5)
+	g.setBusVolume("", 0.5)
+	require.InDelta(t, 0.25, g.busGain(spritesmodels.BusSfx), 1e-9)
+	require.InDelta(t, 0.5, g.busGain(spritesmodels.BusMusic), 1e-9)
+	require.InDelta(t, 0.25, g.busGain(""), 1e-9) // Sounds without a bus use sfx
+
+	g.muteBus(spritesmodels.BusMusic, true)
+	require.Zero(t, g.busGain(spritesmodels.BusMusic))
+	require.InDelta(t, 0.25, g.busGain(spritesmodels.BusSfx), 1e-9)
+
+	// An empty name is the master, the same as for the volume.
+	g.muteBus("", true)
+	require.Zero(t, g.busGain(spritesmodels.BusSfx))
+	require.Zero(t, g.busGain(spritesmodels.BusUI))
+	require.False(t, g.getBus(spritesmodels.BusSfx).muted)
+	g.muteBus("", false)
+	require.InDelta(t, 0.25, g.busGain(spritesmodels.BusSfx), 1e-9)
+	require.Zero(t, g.busGain(spritesmodels.BusMusic))
+}
@@ -0,0 +1,47 @@
+package game
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gary23b/sprites/spritesmodels"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+const debugHUDKey = ebiten.KeyF3
+
+// True if any part of a w by h costume drawn with geoM lands on the screen.
+func (g *EbitenGame) isOnScreen(geoM ebiten.GeoM, w, h int) bool {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, corner := range [4][2]float64{{0, 0}, {float64(w), 0}, {0, float64(h)}, {float64(w), float64(h)}} {
+		x, y := geoM.Apply(corner[0], corner[1])
+		minX, maxX = min(minX, x), max(maxX, x)
+		minY, maxY = min(minY, y), max(maxY, y)
+	}
+	return maxX >= 0 && maxY >= 0 && minX <= float64(g.screenWidth) && minY <= float64(g.screenHeight)
+}
+
+func (g *EbitenGame) GetRenderStats() spritesmodels.RenderStats {
+	g.statsMutex.Lock()
+	defer g.statsMutex.Unlock()
+	return g.stats
+}
+
+func (g *EbitenGame) updateDebugHUDToggle() {
+	if inpututil.IsKeyJustPressed(debugHUDKey) {
+		g.showHUD = !g.showHUD
+	}
+}
+
+func (g *EbitenGame) drawDebugHUD(screen *ebiten.Image, stats spritesmodels.RenderStats) {
+	ebitenutil.DebugPrint(screen, fmt.Sprintf(
+		"FPS: %0.2f, TPS: %0.2f\nDrawn: %d, Culled: %d, Draw calls: %d\nQueue: %d, Update: %0.2fms, Draw: %0.2fms",
+		stats.FPS, stats.TPS,
+		stats.SpritesDrawn, stats.SpritesCulled, stats.DrawCalls,
+		stats.CommandQueueDepth, float64(stats.UpdateTime.Microseconds())/1000, float64(stats.DrawTime.Microseconds())/1000,
+	))
+}
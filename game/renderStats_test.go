@@ -0,0 +1,41 @@
+package game
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsOnScreen(t *testing.T) {
+	// The screen goes from -100 to 100 in x and -50 to 50 in y. The costume is 20 by 20.
+	g := &EbitenGame{screenWidth: 200, screenHeight: 100}
+
+	tests := []struct {
+		name     string
+		sprite   ebitenSprite
+		expected bool
+	}{
+		{"Center", ebitenSprite{xScale: 1, yScale: 1}, true},
+		{"Off the right", ebitenSprite{x: 150, xScale: 1, yScale: 1}, false},
+		{"Off the left", ebitenSprite{x: -111, xScale: 1, yScale: 1}, false},
+		{"Off the top", ebitenSprite{y: 61, xScale: 1, yScale: 1}, false},
+		{"Off the bottom", ebitenSprite{y: -61, xScale: 1, yScale: 1}, false},
+		{"Off the corner", ebitenSprite{x: 111, y: 61, xScale: 1, yScale: 1}, false},
+		{"Partly off the right", ebitenSprite{x: 105, xScale: 1, yScale: 1}, true},
+		{"Partly off the top", ebitenSprite{y: 55, xScale: 1, yScale: 1}, true},
+		{"Touching the edge", ebitenSprite{x: 110, xScale: 1, yScale: 1}, true},
+		{"Rotated corner reaches in", ebitenSprite{x: 112, angleRad: math.Pi / 4, xScale: 1, yScale: 1}, true},
+		{"Rotated still off", ebitenSprite{x: 115, angleRad: math.Pi / 4, xScale: 1, yScale: 1}, false},
+		{"Scaled up reaches in", ebitenSprite{x: 125, xScale: 3, yScale: 3}, true},
+		{"Scaled down is off", ebitenSprite{x: 108, xScale: 0.5, yScale: 0.5}, false},
+		{"Stretched in x only", ebitenSprite{x: 125, xScale: 3, yScale: 1}, true},
+		{"Bigger than the screen", ebitenSprite{xScale: 50, yScale: 50}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, g.isOnScreen(g.spriteGeoM(&tt.sprite, 20, 20), 20, 20))
+		})
+	}
+}
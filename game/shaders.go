@@ -0,0 +1,191 @@
+package game
+
+import (
+	"embed"
+	"fmt"
+	"log"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/gary23b/sprites/spritesmodels"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+//go:embed shaders/*.kage
+var builtinShaderFiles embed.FS
+
+// Used for any uniform the caller leaves out of a built-in shader.
+var builtinShaderDefaults = map[string]spritesmodels.ShaderUniforms{
+	spritesmodels.ShaderBloom:      {"Threshold": 0.6, "Intensity": 1.0, "Radius": 2.0},
+	spritesmodels.ShaderCRT:        {"Curvature": 0.15, "Scanlines": 0.2},
+	spritesmodels.ShaderVignette:   {"Strength": 0.8},
+	spritesmodels.ShaderColorGrade: {"Contrast": 1.0, "Saturation": 1.0, "Tint": []float32{1, 1, 1}},
+}
+
+// A shader with its uniforms already merged with the defaults. Used for sprites and post-processing passes.
+type shaderUse struct {
+	shaderName string
+	uniforms   spritesmodels.ShaderUniforms
+	autoTime   bool // Time was not given, so it is updated every frame.
+}
+
+func (g *EbitenGame) addBuiltinShaders() {
+	entries, err := builtinShaderFiles.ReadDir("shaders")
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	for _, e := range entries {
+		src, err := builtinShaderFiles.ReadFile("shaders/" + e.Name())
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if err := g.addShader(strings.TrimSuffix(e.Name(), ".kage"), src); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+func (g *EbitenGame) addShader(name string, src []byte) error {
+	shader, err := ebiten.NewShader(src)
+	if err != nil {
+		return fmt.Errorf("Failed to compile shader: %s, %w", name, err)
+	}
+	if old, ok := g.shaders[name]; ok {
+		old.Deallocate()
+	}
+	g.shaders[name] = shader
+	return nil
+}
+
+// Fills in the built-in defaults. The caller's map is never modified.
+func newShaderUse(name string, uniforms spritesmodels.ShaderUniforms) *shaderUse {
+	merged := maps.Clone(builtinShaderDefaults[name])
+	if merged == nil {
+		merged = make(spritesmodels.ShaderUniforms, len(uniforms)+1)
+	}
+	maps.Copy(merged, uniforms)
+	_, hasTime := uniforms["Time"]
+	return &shaderUse{shaderName: name, uniforms: merged, autoTime: !hasTime}
+}
+
+func (g *EbitenGame) frameUniforms(use *shaderUse) spritesmodels.ShaderUniforms {
+	if use.autoTime {
+		use.uniforms["Time"] = float32(time.Since(g.startTime).Seconds())
+	}
+	return use.uniforms
+}
+
+func (g *EbitenGame) setSpriteShader(cmd spritesmodels.CmdSpriteShader) {
+	s := g.getSprite(cmd.SpriteID)
+	if s == nil {
+		return
+	}
+	if cmd.ShaderName == "" {
+		s.shader = nil
+		return
+	}
+	if _, ok := g.shaders[cmd.ShaderName]; !ok {
+		log.Printf("Shader %s not found.\n", cmd.ShaderName)
+	}
+	s.shader = newShaderUse(cmd.ShaderName, cmd.Uniforms)
+}
+
+func (g *EbitenGame) setPostProcessing(passes []spritesmodels.ShaderPass) {
+	g.postProcess = g.postProcess[:0]
+	for _, p := range passes {
+		if _, ok := g.shaders[p.ShaderName]; !ok {
+			log.Printf("Shader %s not found. Skipping it in post-processing.\n", p.ShaderName)
+			continue
+		}
+		g.postProcess = append(g.postProcess, newShaderUse(p.ShaderName, p.Uniforms))
+	}
+}
+
+// A uniform with the wrong type makes ebiten panic. That is turned into an error so one bad call doesn't end the game.
+func drawRectShader(dst *ebiten.Image, w, h int, shader *ebiten.Shader, op *ebiten.DrawRectShaderOptions) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Failed to draw shader: %v", r)
+		}
+	}()
+	dst.DrawRectShader(w, h, shader, op)
+	return nil
+}
+
+// Returns false if the sprite's shader doesn't exist, in which case the sprite should be drawn normally.
+func (g *EbitenGame) drawWithShader(screen, costume *ebiten.Image, sprite *ebitenSprite, geoM ebiten.GeoM) bool {
+	shader, ok := g.shaders[sprite.shader.shaderName]
+	if !ok {
+		return false
+	}
+
+	op := ebiten.DrawRectShaderOptions{
+		GeoM:     geoM,
+		Blend:    blendFor(sprite.effects.Blend),
+		Images:   [4]*ebiten.Image{costume},
+		Uniforms: g.frameUniforms(sprite.shader),
+	}
+	op.ColorScale.ScaleAlpha(float32(clamp01(sprite.opacity/100) * (1 - clamp01(sprite.effects.Ghost/100))))
+
+	w, h := costume.Bounds().Dx(), costume.Bounds().Dy()
+	if err := drawRectShader(screen, w, h, shader, &op); err != nil {
+		log.Printf("Sprite %d: %v. Removing the shader.\n", sprite.id, err)
+		sprite.shader = nil
+		return false
+	}
+	g.drawCalls++
+	return true
+}
+
+// Where the sprites should be drawn this frame. With post-processing on, that is an offscreen frame instead of the screen.
+func (g *EbitenGame) frameTarget(screen *ebiten.Image) *ebiten.Image {
+	if len(g.postProcess) == 0 {
+		return screen
+	}
+	return g.postProcessBuffer(0, screen.Bounds().Dx(), screen.Bounds().Dy())
+}
+
+func (g *EbitenGame) postProcessBuffer(i, w, h int) *ebiten.Image {
+	buf := g.postBuffers[i]
+	if buf == nil || buf.Bounds().Dx() != w || buf.Bounds().Dy() != h {
+		if buf != nil {
+			buf.Deallocate()
+		}
+		buf = ebiten.NewImage(w, h)
+		g.postBuffers[i] = buf
+	}
+	buf.Clear()
+	return buf
+}
+
+// Runs each pass on the output of the one before it. The last pass draws to the screen.
+func (g *EbitenGame) applyPostProcessing(screen, frame *ebiten.Image) {
+	w, h := screen.Bounds().Dx(), screen.Bounds().Dy()
+	src := frame
+	for i := 0; i < len(g.postProcess); i++ {
+		pass := g.postProcess[i]
+		dst := screen
+		if i < len(g.postProcess)-1 {
+			// Ping-pong between the two buffers.
+			dst = g.postProcessBuffer((i+1)%2, w, h)
+		}
+
+		op := ebiten.DrawRectShaderOptions{
+			Images:   [4]*ebiten.Image{src},
+			Uniforms: g.frameUniforms(pass),
+		}
+		if err := drawRectShader(dst, w, h, g.shaders[pass.shaderName], &op); err != nil {
+			log.Printf("Post-processing %s: %v. Removing it from the chain.\n", pass.shaderName, err)
+			g.postProcess = append(g.postProcess[:i], g.postProcess[i+1:]...)
+			// Show what the earlier passes made.
+			screen.DrawImage(src, nil)
+			return
+		}
+		g.drawCalls++
+		src = dst
+	}
+}
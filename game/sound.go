@@ -2,111 +2,332 @@ package game
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"log"
+	"math"
 	"os"
 	"path"
 	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/gary23b/sprites/spritestools"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/audio"
 	"github.com/hajimehoshi/ebiten/v2/audio/mp3"
 	"github.com/hajimehoshi/ebiten/v2/audio/vorbis"
 	"github.com/hajimehoshi/ebiten/v2/audio/wav"
 )
 
-func parseWavFile(path string) []byte {
-	rawData, err := os.ReadFile(path)
-	if err != nil {
-		log.Fatal(err)
-		return nil
+const (
+	musicCrossFadeTime = time.Second
+	// Positional sounds are silent once they are this many screen widths from the center.
+	hearingDistanceScreens = 1.5
+)
+
+// Decodes a wav, ogg, or mp3 stream to PCM at the game sample rate. The format is picked from the file name.
+// The returned stream decodes on the fly as it is read.
+func decodeSoundStream(fileName string, src io.Reader) (io.ReadSeeker, error) {
+	fileName = strings.ToLower(path.Base(fileName))
+
+	switch {
+	case strings.Contains(fileName, ".wav"):
+		return wav.DecodeWithSampleRate(SampleRate, src)
+	case strings.Contains(fileName, ".ogg"):
+		return vorbis.DecodeWithSampleRate(SampleRate, src)
+	case strings.Contains(fileName, ".mp3"):
+		return mp3.DecodeWithSampleRate(SampleRate, src)
+	default:
+		return nil, fmt.Errorf("Unable to decode sound file: %s", fileName)
 	}
+}
 
-	s, err := wav.DecodeWithSampleRate(sampleRate, bytes.NewReader(rawData))
+// Fully decodes an encoded wav, ogg, or mp3 file to PCM. This is safe to call from any go routine,
+// so large files can be decoded without stalling the game loop.
+func DecodeSound(fileName string, rawData []byte) ([]byte, error) {
+	s, err := decodeSoundStream(fileName, bytes.NewReader(rawData))
 	if err != nil {
-		log.Fatal(err)
-		return nil
+		return nil, err
 	}
 	b, err := io.ReadAll(s)
 	if err != nil {
-		log.Fatal(err)
-		return nil
+		return nil, fmt.Errorf("Failed to decode sound file: %s, %w", fileName, err)
 	}
-	return b
+	return b, nil
 }
 
-func parseOggFile(path string) []byte {
-	rawData, err := os.ReadFile(path)
-	if err != nil {
-		log.Fatal(err)
-		return nil
+// For the deprecated CmdAddSound. The decoding happens on the game loop.
+func (g *EbitenGame) addSound(cmd spritesmodels.CmdAddSound) { //nolint:staticcheck
+	rawData := cmd.Data
+	if rawData == nil {
+		var err error
+		rawData, err = os.ReadFile(cmd.Path)
+		if err != nil {
+			log.Printf("Failed to read sound file: %s, %v\n", cmd.Path, err)
+			return
+		}
 	}
 
-	s, err := vorbis.DecodeWithSampleRate(sampleRate, bytes.NewReader(rawData))
+	soundData, err := DecodeSound(cmd.Path, rawData)
 	if err != nil {
-		log.Fatal(err)
-		return nil
+		log.Println(err)
+		return
 	}
-	b, err := io.ReadAll(s)
-	if err != nil {
-		log.Fatal(err)
-		return nil
+	g.sounds[cmd.SoundName] = soundData
+}
+
+////////////////////////////////////////////////////////////////////////////////////////
+
+// Wraps a PCM stream so that looping can be turned on and off while the player is reading from it.
+type loopStream struct {
+	src  io.ReadSeeker
+	loop atomic.Bool
+}
+
+func newLoopStream(src io.ReadSeeker, loop bool) *loopStream {
+	ret := &loopStream{src: src}
+	ret.loop.Store(loop)
+	return ret
+}
+
+func (s *loopStream) Read(p []byte) (int, error) {
+	n, err := s.src.Read(p)
+	if err == io.EOF && s.loop.Load() {
+		if _, seekErr := s.src.Seek(0, io.SeekStart); seekErr != nil {
+			return n, seekErr
+		}
+		if n == 0 {
+			return s.src.Read(p)
+		}
+		return n, nil
 	}
-	return b
+	return n, err
 }
 
-func parseMp3File(path string) []byte {
-	rawData, err := os.ReadFile(path)
-	if err != nil {
-		log.Fatal(err)
-		return nil
+func (s *loopStream) Seek(offset int64, whence int) (int64, error) {
+	return s.src.Seek(offset, whence)
+}
+
+// A sound that is currently playing or paused.
+type soundPlayer struct {
+	player *audio.Player
+	stream *loopStream
+	closer io.Closer // The open file for streamed music. nil for sounds decoded into memory.
+	paused bool
+	bus    string
+	mix    float64 // The bus and master gain. Updated every tick.
+
+	volume        float64
+	fadeTarget    float64
+	fadeStep      float64 // Volume change per tick. 0 when not fading.
+	stopAfterFade bool
+}
+
+func clampVolume(volume float64) float64 {
+	if volume < 0 {
+		volume = 0
+	}
+	if volume > 1 {
+		volume = 1
 	}
+	return volume
+}
 
-	s, err := mp3.DecodeWithSampleRate(sampleRate, bytes.NewReader(rawData))
-	if err != nil {
-		log.Fatal(err)
-		return nil
+func (p *soundPlayer) setVolume(volume float64) {
+	p.volume = clampVolume(volume)
+	p.fadeStep = 0
+	p.applyVolume()
+}
+
+func (p *soundPlayer) applyVolume() {
+	p.player.SetVolume(p.volume * p.mix)
+}
+
+func (p *soundPlayer) fade(volume float64, duration time.Duration, stopAfterFade bool) {
+	p.fadeTarget = clampVolume(volume)
+	p.stopAfterFade = stopAfterFade
+	ticks := duration.Seconds() * float64(ebiten.TPS())
+	if ticks < 1 {
+		p.setVolume(p.fadeTarget)
+		return
 	}
-	b, err := io.ReadAll(s)
+	p.fadeStep = math.Abs(p.fadeTarget-p.volume) / ticks
+	if p.fadeStep == 0 {
+		p.fadeStep = 1
+	}
+}
+
+// Returns false once the sound is done and should be removed.
+func (p *soundPlayer) update() bool {
+	if p.fadeStep != 0 {
+		if math.Abs(p.fadeTarget-p.volume) <= p.fadeStep {
+			p.volume = p.fadeTarget
+			p.fadeStep = 0
+			if p.stopAfterFade {
+				return false
+			}
+		} else if p.fadeTarget > p.volume {
+			p.volume += p.fadeStep
+		} else {
+			p.volume -= p.fadeStep
+		}
+	}
+	p.applyVolume()
+
+	return p.paused || p.player.IsPlaying()
+}
+
+func (p *soundPlayer) close() {
+	if err := p.player.Close(); err != nil {
+		log.Println(err)
+	}
+	if p.closer != nil {
+		p.closer.Close()
+	}
+}
+
+func (g *EbitenGame) GetNextSoundID() int {
+	g.soundMutex.Lock()
+	defer g.soundMutex.Unlock()
+
+	newID := g.nextSoundID
+	g.nextSoundID++
+	return newID
+}
+
+func (g *EbitenGame) newSoundPlayer(src io.ReadSeeker, loop bool, volume float64, bus string) (*soundPlayer, error) {
+	stream := newLoopStream(src, loop)
+	return g.newSoundPlayerFromStream(stream, stream, volume, bus)
+}
+
+// playerSrc is what the player reads from. It is either stream or a wrapper around it.
+func (g *EbitenGame) newSoundPlayerFromStream(stream *loopStream, playerSrc io.ReadSeeker, volume float64, bus string) (*soundPlayer, error) {
+	player, err := g.audioContext.NewPlayer(playerSrc)
 	if err != nil {
-		log.Fatal(err)
-		return nil
+		return nil, err
+	}
+
+	ret := &soundPlayer{
+		player: player,
+		stream: stream,
+		bus:    bus,
+		mix:    g.busGain(bus),
 	}
-	return b
+	ret.setVolume(volume)
+	return ret, nil
 }
 
-func (g *EbitenGame) addSound(pathStr, soundName string) {
-	fileName := strings.ToLower(path.Base(pathStr))
+func (g *EbitenGame) playSound(cmd spritesmodels.CmdPlaySound) {
+	soundData, ok := g.sounds[cmd.SoundName]
+	if !ok {
+		log.Printf("Sound %s not found.\n", cmd.SoundName)
+		return
+	}
+
+	stream := newLoopStream(bytes.NewReader(soundData), cmd.Loop)
+	var playerSrc io.ReadSeeker = stream
+	if cmd.Positional {
+		screenWidth := float64(g.screenWidth)
+		left, right := spritestools.PositionalGains(cmd.X, cmd.Y, 0, 0, screenWidth, screenWidth*hearingDistanceScreens)
+		playerSrc = newPanStream(stream, left, right)
+	}
 
-	var soundData []byte
-	switch {
-	case strings.Contains(fileName, ".wav"):
-		soundData = parseWavFile(pathStr)
-	case strings.Contains(fileName, ".ogg"):
-		soundData = parseOggFile(pathStr)
-	case strings.Contains(fileName, ".mp3"):
-		soundData = parseMp3File(pathStr)
-	default:
-		log.Printf("Unable to decode sound file: %s\n", pathStr)
+	p, err := g.newSoundPlayerFromStream(stream, playerSrc, cmd.Volume, cmd.Bus)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	p.player.Play()
+	g.soundPlayers[cmd.HandleID] = p
+}
+
+func (g *EbitenGame) controlSound(cmd spritesmodels.CmdSoundControl) {
+	p, ok := g.soundPlayers[cmd.HandleID]
+	if !ok {
+		// The sound already finished.
 		return
 	}
 
-	g.sounds[soundName] = soundData
+	switch cmd.Action {
+	case spritesmodels.SoundStop:
+		p.close()
+		delete(g.soundPlayers, cmd.HandleID)
+	case spritesmodels.SoundPause:
+		p.paused = true
+		p.player.Pause()
+	case spritesmodels.SoundResume:
+		p.paused = false
+		p.player.Play()
+	case spritesmodels.SoundSetVolume:
+		p.setVolume(cmd.Volume)
+	case spritesmodels.SoundFade:
+		p.fade(cmd.Volume, cmd.Duration, false)
+	case spritesmodels.SoundSeek:
+		if err := p.player.SetPosition(cmd.Duration); err != nil {
+			log.Println(err)
+		}
+	case spritesmodels.SoundLoop:
+		p.stream.loop.Store(cmd.Loop)
+	}
 }
 
-func (g *EbitenGame) playSound(soundName string, volume float64) {
-	if volume < 0 {
-		volume = 0
+// Music is streamed from disk instead of being decoded into memory. Starting a new track cross-fades from the old one.
+func (g *EbitenGame) playMusic(pathStr string, loop bool, volume float64) {
+	f, err := os.Open(pathStr)
+	if err != nil {
+		log.Printf("Failed to open music file: %s, %v\n", pathStr, err)
+		return
 	}
-	if volume > 1 {
-		volume = 1
+
+	stream, err := decodeSoundStream(pathStr, f)
+	if err != nil {
+		log.Println(err)
+		f.Close()
+		return
 	}
 
-	soundData, ok := g.sounds[soundName]
-	if !ok {
-		log.Printf("Sound %s not found.\n", soundName)
+	p, err := g.newSoundPlayer(stream, loop, 0, spritesmodels.BusMusic)
+	if err != nil {
+		log.Println(err)
+		f.Close()
+		return
+	}
+	p.closer = f
+
+	g.stopMusic()
+	g.music = p
+	p.fade(volume, musicCrossFadeTime, false)
+	p.player.Play()
+}
+
+// The current track fades out and is then dropped.
+func (g *EbitenGame) stopMusic() {
+	if g.music == nil {
 		return
 	}
+	g.music.fade(0, musicCrossFadeTime, true)
+	g.soundPlayers[g.GetNextSoundID()] = g.music
+	g.music = nil
+}
+
+// Called every tick to run fades and to release sounds that have finished.
+func (g *EbitenGame) updateSounds() {
+	for id, p := range g.soundPlayers {
+		p.mix = g.busGain(p.bus)
+		if !p.update() {
+			p.close()
+			delete(g.soundPlayers, id)
+		}
+	}
 
-	p := g.audioContext.NewPlayerFromBytes(soundData)
-	p.Play()
-	p.SetVolume(volume)
+	if g.music != nil {
+		g.music.mix = g.busGain(g.music.bus)
+	}
+	if g.music != nil && !g.music.update() {
+		g.music.close()
+		g.music = nil
+	}
 }
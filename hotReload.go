@@ -0,0 +1,110 @@
+package sprites
+
+import (
+	"io/fs"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gary23b/sprites/spritestools"
+)
+
+// How often WatchAssets polls when it isn't told.
+const defaultAssetPollInterval = 500 * time.Millisecond
+
+// Development helper. The manifest and every file it lists are polled, and anything that changes is decoded again
+// and replaces the old costume or sound in the running sim. Call the returned function to stop watching.
+// Use os.DirFS(...) for fsys; files in an embed.FS never change. A pollInterval of 0 polls twice a second.
+func (sim *simState) WatchAssets(fsys fs.FS, manifestPath string, pollInterval time.Duration) (stop func()) {
+	if pollInterval <= 0 {
+		pollInterval = defaultAssetPollInterval
+	}
+	stopCh := make(chan struct{})
+	once := sync.Once{}
+
+	go func() {
+		w := sim.newAssetWatcher(fsys, manifestPath)
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				w.poll()
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() { close(stopCh) })
+	}
+}
+
+// Reloads the assets that changed each time poll is called.
+type assetWatcher struct {
+	sim          *simState
+	fsys         fs.FS
+	manifestPath string
+	watcher      *spritestools.FileWatcher
+	tasksByPath  map[string][]assetTask
+}
+
+func (sim *simState) newAssetWatcher(fsys fs.FS, manifestPath string) *assetWatcher {
+	ret := &assetWatcher{
+		sim:          sim,
+		fsys:         fsys,
+		manifestPath: manifestPath,
+		watcher:      spritestools.NewFileWatcher(fsys, nil),
+		tasksByPath:  map[string][]assetTask{},
+	}
+	ret.loadManifest()
+	return ret
+}
+
+// Returns false if the manifest could not be read. The old assets keep being watched in that case.
+func (w *assetWatcher) loadManifest() bool {
+	manifest, err := spritestools.LoadAssetManifest(w.fsys, w.manifestPath)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+
+	w.tasksByPath = map[string][]assetTask{}
+	paths := []string{w.manifestPath}
+	for _, task := range w.sim.assetTasks(w.fsys, manifest) {
+		if _, ok := w.tasksByPath[task.path]; !ok {
+			paths = append(paths, task.path)
+		}
+		w.tasksByPath[task.path] = append(w.tasksByPath[task.path], task)
+	}
+	w.watcher.SetPaths(paths)
+	return true
+}
+
+func (w *assetWatcher) poll() {
+	for _, path := range w.watcher.Poll() {
+		if path == w.manifestPath {
+			if !w.loadManifest() {
+				continue
+			}
+			// The manifest may point to different files now, so everything is reloaded.
+			for _, tasks := range w.tasksByPath {
+				w.sim.runReloadTasks(tasks)
+			}
+			continue
+		}
+
+		w.sim.runReloadTasks(w.tasksByPath[path])
+	}
+}
+
+func (sim *simState) runReloadTasks(tasks []assetTask) {
+	for _, task := range tasks {
+		if err := task.load(); err != nil {
+			log.Printf("Failed to reload %s: %v\n", task.name, err)
+			continue
+		}
+		log.Printf("Reloaded %s from %s\n", task.name, task.path)
+	}
+}
@@ -0,0 +1,89 @@
+package sprites
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/stretchr/testify/require"
+)
+
+// The costume names added since the last call.
+func (sim *simState) takeAddedCostumes() []string {
+	ret := []string{}
+	for _, cmd := range sim.takeCmds() {
+		if v, ok := cmd.(spritesmodels.CmdAddCostume); ok {
+			ret = append(ret, v.CostumeName)
+		}
+	}
+	return ret
+}
+
+func TestAssetWatcher(t *testing.T) {
+	fsys := fstest.MapFS{
+		"manifest.json": {Data: []byte(`{"costumes": [{"name": "player", "path": "player.png"}]}`)},
+		"player.png":    testPNG(t, 2, 2),
+		"enemy.png":     testPNG(t, 2, 2),
+	}
+
+	sim := newTestSim()
+	w := sim.newAssetWatcher(fsys, "manifest.json")
+	w.poll()
+	require.Empty(t, sim.takeAddedCostumes())
+
+	// A changed costume is reloaded once.
+	fsys["player.png"] = testPNG(t, 3, 3)
+	w.poll()
+	cmds := sim.takeCmds()
+	require.Len(t, cmds, 1)
+	require.Equal(t, "player", cmds[0].(spritesmodels.CmdAddCostume).CostumeName)
+	require.Equal(t, 3, cmds[0].(spritesmodels.CmdAddCostume).Img.Bounds().Dx())
+	w.poll()
+	require.Empty(t, sim.takeAddedCostumes())
+
+	// Files the manifest doesn't list are ignored.
+	fsys["enemy.png"] = testPNG(t, 4, 4)
+	w.poll()
+	require.Empty(t, sim.takeAddedCostumes())
+
+	// A changed manifest reloads everything and watches its new files.
+	fsys["manifest.json"] = &fstest.MapFile{Data: []byte(`{"costumes": [
+		{"name": "player", "path": "player.png"}, {"name": "enemy", "path": "enemy.png"}]}`)}
+	w.poll()
+	require.ElementsMatch(t, []string{"player", "enemy"}, sim.takeAddedCostumes())
+	fsys["enemy.png"] = testPNG(t, 5, 5)
+	w.poll()
+	require.Equal(t, []string{"enemy"}, sim.takeAddedCostumes())
+
+	// A broken manifest keeps the old files watched.
+	fsys["manifest.json"] = &fstest.MapFile{Data: []byte(`{"costumes": 5}`)}
+	w.poll()
+	require.Empty(t, sim.takeAddedCostumes())
+	fsys["player.png"] = testPNG(t, 6, 6)
+	w.poll()
+	require.Equal(t, []string{"player"}, sim.takeAddedCostumes())
+
+	// A deleted file is reloaded when it comes back.
+	delete(fsys, "player.png")
+	w.poll()
+	require.Empty(t, sim.takeAddedCostumes())
+	fsys["player.png"] = testPNG(t, 7, 7)
+	w.poll()
+	require.Equal(t, []string{"player"}, sim.takeAddedCostumes())
+}
+
+func TestWatchAssetsStop(t *testing.T) {
+	fsys := fstest.MapFS{
+		"manifest.json": {Data: []byte(`{"costumes": []}`)},
+	}
+	sim := newTestSim()
+
+	// A pollInterval of 0 uses the default instead of panicking in time.NewTicker.
+	stop := sim.WatchAssets(fsys, "manifest.json", 0)
+	stop()
+	stop()
+
+	stop = sim.WatchAssets(fsys, "manifest.json", time.Millisecond)
+	stop()
+}
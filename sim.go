@@ -3,30 +3,79 @@ package sprites
 import (
 	"fmt"
 	"image"
+	"io"
+	"io/fs"
 	"log"
+	"maps"
 	"math"
 	"math/rand"
+	"os"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gary23b/sprites/game"
 	"github.com/gary23b/sprites/spritesmodels"
 	"github.com/gary23b/sprites/spritestools"
+	"github.com/golang/freetype/truetype"
 )
 
 type Sim interface {
 	GetWidth() int
 	GetHeight() int
 
-	AddCostume(img image.Image, name string)
-	AddSprite(UniqueName string) Sprite // If no name is given, a random name is generated.
+	AddCostume(img image.Image, name string)      // Small costumes are automatically packed into shared atlas textures.
+	LoadAtlas(fsys fs.FS, indexPath string) error // Loads an atlas made by cmd/packatlas. Each frame becomes a costume.
+	AddSprite(UniqueName string) Sprite           // If no name is given, a random name is generated.
+	// Adds n sprites set up like template, all in the same frame. The names are random and the kinematics are applied too.
+	AddSprites(n int, template spritesmodels.SpriteState) []Sprite
 	DeleteSprite(Sprite)
 	DeleteAllSprites()
 
+	// Makes all the changes to all the sprites at once. The game draws either none of them or all of them.
+	// Nothing is changed if any of the sprites is deleted.
+	Transaction(f func(tx Transaction)) error
+
 	SpriteUpdatePosAngle(in Sprite)
 	SpriteUpdateFull(in Sprite)
+	SetSpriteKinematics(in Sprite, k spritesmodels.Kinematics)
+	SpriteCallbacksChanged(in Sprite)
+	SetSpriteDrawOrder(spriteID int, action spritesmodels.DrawOrderAction, steps int)
+	SetYSort(z float64, enabled bool) // Sprites with this Z are drawn top to bottom, for top-down games. BringToFront and friends only reorder sprites at the same y.
 
 	AddSound(path, name string)
-	PlaySound(name string, volume float64) // volume must be between 0 and 1.
+	AddSoundFS(fsys fs.FS, path, name string)                // Works with embed.FS
+	AddSoundReader(r io.Reader, fileName, name string)       // The extension of fileName picks the decoder.
+	AddSynthSound(name string, spec spritesmodels.SynthSpec) // Generates a sound from oscillators instead of a file.
+	PlaySound(name string, volume float64) SoundHandle       // volume must be between 0 and 1.
+	PlaySoundOnBus(name, bus string, volume float64) SoundHandle
+	PlaySoundAt(name string, volume, x, y float64) SoundHandle // Panned and attenuated based on the distance from the center of the screen. Fixed once it starts.
+	PlayMusic(path string, loop bool)                          // Streams the file instead of loading it all. Cross-fades from the current track.
+	StopMusic()
+
+	// Mixer. PlaySound uses the sfx bus and PlayMusic uses the music bus. Volumes must be between 0 and 1.
+	// SetBusVolume and MuteBus take an empty bus name as the master, which every bus goes through.
+	SetMasterVolume(volume float64)
+	SetBusVolume(bus string, volume float64)
+	MuteBus(bus string, mute bool)
+
+	// Shaders. Kage source is registered by name like a costume. See spritesmodels.ShaderBloom and friends for the built-in ones.
+	AddShader(name string, kageSource []byte) error
+	SetSpriteShader(spriteID int, shaderName string, uniforms spritesmodels.ShaderUniforms) // An empty name removes the shader.
+	SetPostProcessing(passes ...spritesmodels.ShaderPass)                                   // Applied to the whole frame in order. Call again to change uniforms.
+
+	// Loads every costume, sprite sheet, sound, and font listed in a JSON manifest. See spritesmodels.AssetManifest.
+	// progress is optional and is called after each asset. Failed assets are skipped and all errors are returned together.
+	LoadAssets(fsys fs.FS, manifestPath string, progress func(spritesmodels.AssetProgress)) error
+	// Same as LoadAssets, but the assets are decoded in parallel in the background and this returns right away.
+	// Costumes, sprite sheet frames, and atlas frames can be used while they load; a placeholder is shown until they are ready.
+	// The returned channel receives one update per asset and is closed when everything is done.
+	LoadAssetsAsync(fsys fs.FS, manifestPath string) (chan spritesmodels.AssetProgress, error)
+	// Development mode. Polls the manifest's files and reloads any costume, sprite sheet, or sound that changes on disk.
+	WatchAssets(fsys fs.FS, manifestPath string, pollInterval time.Duration) (stop func())
+	AddFont(fsys fs.FS, path, name string) error
+	GetFont(name string) *truetype.Font // nil if the font was never added
 
 	PressedUserInput() *spritesmodels.UserInput
 	SubscribeToJustPressedUserInput() chan *spritesmodels.UserInput
@@ -36,26 +85,54 @@ type Sim interface {
 	GetSpriteInfo(UniqueName string) spritesmodels.SpriteState
 	GetSpriteInfoByID(id int) spritesmodels.SpriteState
 
-	WhoIsNearMe(x, y, distance float64) []spritesmodels.NearMeInfo
+	WhoIsNearMe(x, y, distance float64) []spritesmodels.NearMeInfo // Everything in the nearby grid cells, unsorted
+	WhoIsWithin(x, y, distance float64, filter spritesmodels.NearMeFilter) []spritesmodels.NearMeInfo
+	NearestSprites(x, y float64, k int, filter spritesmodels.NearMeFilter) []spritesmodels.NearMeInfo
+	WhoIsInRect(rect spritesmodels.Rect, filter spritesmodels.NearMeFilter) []spritesmodels.NearMeInfo
+	// Sprites within width of the ray, nearest first. NearMeInfo.Distance is how far along the ray they are.
+	// maxDistance can be math.Inf(1).
+	WhoIsAlongRay(x, y, dirX, dirY, maxDistance, width float64, filter spritesmodels.NearMeFilter) []spritesmodels.NearMeInfo
+	HasLineOfSight(x1, y1, x2, y2, width float64, filter spritesmodels.NearMeFilter) bool // True if no matching sprite is in the way
+	// The first click body the ray enters. The direction doesn't need to be normalized. maxDistance can be math.Inf(1).
+	Raycast(fromX, fromY, dirX, dirY, maxDistance float64, filter spritesmodels.NearMeFilter) (spritesmodels.RayHit, bool)
+	RaycastAll(fromX, fromY, dirX, dirY, maxDistance float64, filter spritesmodels.NearMeFilter) []spritesmodels.RayHit // Nearest first
+	// A navigation grid over the window, with the click bodies of every sprite of the obstacle types blocked.
+	// An obstacle without a click body blocks the cell it is in. Later moves of the obstacles don't change the grid.
+	BuildNavGrid(cellSize float64, obstacleTypes ...int) *spritestools.NavGrid
 	SendMsg(toSpriteID int, msg any)
 
 	GetScreenshot() image.Image
+	RenderStats() spritesmodels.RenderStats
+	// How well the game is keeping up with the sprites.
+	CommandQueueStats() spritesmodels.CommandQueueStats
+	ShowDebugHUD(show bool)     // Frame rate, render stats, and timing in the top left corner. F3 toggles it too.
+	ShowDebugOverlay(show bool) // Outlines sprite bounds, click bodies, anchors, and position grid cells. F4 toggles it too.
 
 	Exit()
 }
 
 type simState struct {
-	width   int
-	height  int
-	g       *game.EbitenGame
-	cmdChan chan any
+	width    int
+	height   int
+	g        *game.EbitenGame
+	cmdQueue *spritestools.CommandQueue
+
+	drawIndex func(spriteID int) int // Where the game last drew the sprite. Higher is on top, -1 for not drawn yet.
 
 	justPressedBroker *spritestools.Broker[*spritesmodels.UserInput]
 	posBroker         *spritestools.PositionBroker
+	maxBodyRadius     atomic.Uint64 // Float bits of the biggest click body seen. Tells raycasts how far from the ray to look.
 
 	idToSpriteMapMutex sync.RWMutex
 	idToSpriteMap      map[int]Sprite
 	nameToSpriteMap    map[string]Sprite
+
+	callbackMutex   sync.Mutex
+	callbackSprites map[int]*sprite // Sprites with at least one callback
+	callbackOnce    sync.Once       // Starts the callback go routine
+
+	fontsMutex sync.RWMutex
+	fonts      map[string]*truetype.Font
 }
 
 var _ Sim = &simState{} // Force the linter to tell us if the interface is implemented
@@ -63,20 +140,40 @@ var _ Sim = &simState{} // Force the linter to tell us if the interface is imple
 type SimParams struct {
 	Width   int  // Window Width in pixels
 	Height  int  // Window Height in pixels
-	ShowFPS bool // Show Frame-Rate and Update-Rate information in top left corner of window
+	ShowFPS bool // Start with the debug HUD showing in the top left corner of the window. F3 toggles it.
+
+	// The cell size of the grid used by WhoIsNearMe. Defaults to 20. Set it close to the distances usually asked about.
+	PositionGridCellSize float64
+
+	// How many commands can wait for the game before OverflowPolicy kicks in. Defaults to 100000.
+	CommandQueueSize int
+	OverflowPolicy   spritesmodels.OverflowPolicy // Defaults to waiting for room.
+	// Called on its own go routine when the game is falling behind the sprites, at most once a second. Defaults to logging a warning.
+	OnFallingBehind func(stats spritesmodels.CommandQueueStats)
+
+	RecordInputPath string // If set, the user input of every tick is recorded to this file.
+	ReplayInputPath string // If set, the user input recorded in this file is used instead of live input until it runs out.
 }
 
 // The drawFunc will be started as a go routine.
 func Start(params SimParams, simStartFunc func(Sim)) {
 	log.SetFlags(log.Ltime | log.Lmicroseconds | log.Lshortfile)
 
+	gridCellSize := params.PositionGridCellSize
+	if gridCellSize == 0 {
+		gridCellSize = spritestools.DefaultPositionGridCellSize
+	}
+
 	ret := &simState{
 		width:             params.Width,
 		height:            params.Height,
 		justPressedBroker: spritestools.NewBroker[*spritesmodels.UserInput](100),
-		posBroker:         spritestools.NewPositionBroker(),
+		posBroker:         spritestools.NewPositionBrokerWithCellSize(gridCellSize),
 		idToSpriteMap:     make(map[int]Sprite),
 		nameToSpriteMap:   make(map[string]Sprite),
+		fonts:             make(map[string]*truetype.Font),
+		callbackSprites:   make(map[int]*sprite),
+		cmdQueue:          spritestools.NewCommandQueue(params.CommandQueueSize, params.OverflowPolicy, params.OnFallingBehind),
 	}
 
 	gameInit := game.GameInitStruct{
@@ -84,9 +181,33 @@ func Start(params SimParams, simStartFunc func(Sim)) {
 		Height:            params.Height,
 		ShowFPS:           params.ShowFPS,
 		JustPressedBroker: ret.justPressedBroker,
+		KinematicsUpdates: make(chan []spritesmodels.KinematicsUpdate, 4),
+		CmdQueue:          ret.cmdQueue,
+	}
+	go ret.applyKinematicsUpdates(gameInit.KinematicsUpdates)
+	gameInit.DebugOverlaySource = ret.debugOverlayInfo
+
+	if params.ReplayInputPath != "" {
+		f, err := os.Open(params.ReplayInputPath)
+		if err != nil {
+			log.Fatalf("Failed to open input replay file: %s, %v", params.ReplayInputPath, err)
+		}
+		gameInit.InputReplayer, err = spritestools.NewInputReplayer(f)
+		f.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if params.RecordInputPath != "" {
+		f, err := os.Create(params.RecordInputPath)
+		if err != nil {
+			log.Fatalf("Failed to create input record file: %s, %v", params.RecordInputPath, err)
+		}
+		gameInit.InputRecorder = spritestools.NewInputRecorder(f)
 	}
 	ret.g = game.NewGame(gameInit)
-	ret.cmdChan = ret.g.GetSpriteCmdChannel()
+	ret.drawIndex = ret.g.DrawIndex
 	go simStartFunc(ret)
 	ret.g.RunGame()
 }
@@ -98,12 +219,12 @@ func (s *simState) Exit() {
 func (s *simState) AddSprite(uniqueName string) Sprite {
 	spriteID := s.g.GetNextSpriteID()
 	if uniqueName == "" {
-		uniqueName = fmt.Sprintf("rand%X%X", rand.Uint64(), rand.Uint64())
+		uniqueName = randomSpriteName()
 	}
 	update := spritesmodels.CmdAddNewSprite{
 		SpriteID: spriteID,
 	}
-	s.cmdChan <- update
+	s.cmdQueue.Push(update)
 
 	s.posBroker.AddSprite(spriteID)
 	ret := NewSprite(s, uniqueName, spriteID)
@@ -117,48 +238,81 @@ func (s *simState) AddSprite(uniqueName string) Sprite {
 	return ret
 }
 
+func randomSpriteName() string {
+	return fmt.Sprintf("rand%X%X", rand.Uint64(), rand.Uint64())
+}
+
+// The same as in.DeleteSprite(). Nothing happens if the sprite is already deleted.
 func (s *simState) DeleteSprite(in Sprite) {
-	spriteID := in.GetSpriteID()
-	s.posBroker.RemoveSprite(spriteID)
+	in.DeleteSprite()
+}
+
+// Tells the game to remove the sprite and forgets about it. Only called once, by the sprite's DeleteSprite.
+func (s *simState) removeSprite(sp *sprite) {
+	s.posBroker.RemoveSprite(sp.spriteID)
 	update := spritesmodels.CmdSpriteDelete{
-		SpriteID: spriteID,
+		SpriteID: sp.spriteID,
 	}
-	s.cmdChan <- update
+	s.cmdQueue.Push(update)
 
 	s.idToSpriteMapMutex.Lock()
-	delete(s.idToSpriteMap, spriteID)
-	delete(s.nameToSpriteMap, in.GetUniqueName())
+	delete(s.idToSpriteMap, sp.spriteID)
+	delete(s.nameToSpriteMap, sp.UniqueName)
 	s.idToSpriteMapMutex.Unlock()
+
+	s.callbackMutex.Lock()
+	delete(s.callbackSprites, sp.spriteID)
+	s.callbackMutex.Unlock()
 }
 
 func (s *simState) DeleteAllSprites() {
-	update := spritesmodels.CmdSpritesDeleteAll{}
-	s.cmdChan <- update
-	s.posBroker = spritestools.NewPositionBroker()
-
 	s.idToSpriteMapMutex.Lock()
+	all := s.idToSpriteMap
 	s.idToSpriteMap = make(map[int]Sprite)
 	s.nameToSpriteMap = make(map[string]Sprite)
 	s.idToSpriteMapMutex.Unlock()
+
+	// Nothing more is sent for these once they are marked, so the delete below is the last the game hears of them.
+	for id, in := range all {
+		if sp, ok := in.(*sprite); ok {
+			sp.markDeleted()
+		}
+		s.posBroker.RemoveSprite(id)
+	}
+
+	update := spritesmodels.CmdSpritesDeleteAll{}
+	s.cmdQueue.Push(update)
+
+	s.callbackMutex.Lock()
+	s.callbackSprites = make(map[int]*sprite)
+	s.callbackMutex.Unlock()
 }
 
 func (s *simState) SpriteUpdatePosAngle(in Sprite) {
 	status := in.GetState()
+	s.noteBodyRadius(in.GetClickBody())
 	s.posBroker.UpdateSpriteInfo(status.SpriteID, status)
 	cmd := spritesmodels.CmdSpriteUpdateMin{
-		SpriteID: status.SpriteID,
-		X:        status.X,
-		Y:        status.Y,
-		AngleRad: status.AngleDegrees * (math.Pi / 180.0),
+		SpriteID:   status.SpriteID,
+		X:          status.X,
+		Y:          status.Y,
+		AngleRad:   status.AngleDegrees * (math.Pi / 180.0),
+		Generation: spriteGeneration(in),
 	}
 
-	s.cmdChan <- cmd
+	s.cmdQueue.Push(cmd)
 }
 
 func (s *simState) SpriteUpdateFull(in Sprite) {
+	s.cmdQueue.Push(s.fullUpdateCmd(in))
+}
+
+// Also updates the position broker with the sprite's new state.
+func (s *simState) fullUpdateCmd(in Sprite) spritesmodels.CmdSpriteUpdateFull {
 	status := in.GetState()
+	s.noteBodyRadius(in.GetClickBody())
 	s.posBroker.UpdateSpriteInfo(status.SpriteID, status)
-	cmd := spritesmodels.CmdSpriteUpdateFull{
+	return spritesmodels.CmdSpriteUpdateFull{
 		SpriteID:    status.SpriteID,
 		CostumeName: status.CostumeName,
 		X:           status.X,
@@ -169,9 +323,61 @@ func (s *simState) SpriteUpdateFull(in Sprite) {
 		XScale:      status.ScaleX,
 		YScale:      status.ScaleY,
 		Opacity:     status.Opacity,
+		Effects:     status.Effects,
+		FlipX:       status.FlipX,
+		FlipY:       status.FlipY,
+		PivotX:      status.PivotX,
+		PivotY:      status.PivotY,
+		SkewX:       status.SkewX * (math.Pi / 180.0),
+		SkewY:       status.SkewY * (math.Pi / 180.0),
+		Generation:  spriteGeneration(in),
+	}
+}
+
+// The game tags its kinematics updates with this, so the sim can tell which ones are out of date.
+func spriteGeneration(in Sprite) int {
+	if sp, ok := in.(*sprite); ok {
+		return sp.getGeneration()
 	}
+	return 0
+}
 
-	s.cmdChan <- cmd
+func (s *simState) SetSpriteKinematics(in Sprite, k spritesmodels.Kinematics) {
+	s.cmdQueue.Push(spritesmodels.CmdSpriteKinematics{
+		SpriteID:   in.GetSpriteID(),
+		Kinematics: k,
+		Generation: spriteGeneration(in),
+	})
+}
+
+// Brings the sprites and the PositionBroker up to date with where the game moved them.
+func (s *simState) applyKinematicsUpdates(updates chan []spritesmodels.KinematicsUpdate) {
+	for batch := range updates {
+		for _, u := range batch {
+			s.idToSpriteMapMutex.RLock()
+			in := s.idToSpriteMap[u.SpriteID]
+			s.idToSpriteMapMutex.RUnlock()
+			sp, ok := in.(*sprite)
+			if !ok {
+				continue
+			}
+			if sp.applyKinematicsUpdate(u) && !u.Destroyed {
+				s.posBroker.UpdateSpriteInfo(u.SpriteID, sp.GetState())
+			}
+		}
+	}
+}
+
+func (s *simState) SetSpriteDrawOrder(spriteID int, action spritesmodels.DrawOrderAction, steps int) {
+	s.cmdQueue.Push(spritesmodels.CmdSpriteDrawOrder{
+		SpriteID: spriteID,
+		Action:   action,
+		Steps:    steps,
+	})
+}
+
+func (s *simState) SetYSort(z float64, enabled bool) {
+	s.cmdQueue.Push(spritesmodels.CmdSetYSort{Z: z, Enabled: enabled})
 }
 
 func (s *simState) GetSpriteID(uniqueName string) int {
@@ -219,29 +425,212 @@ func (sim *simState) AddCostume(img image.Image, name string) {
 		Img:         img,
 		CostumeName: name,
 	}
-	sim.cmdChan <- update
+	sim.cmdQueue.Push(update)
 }
 
+// The sound is decoded in the calling go routine so the game loop doesn't stall.
 func (sim *simState) AddSound(path, name string) {
-	cmd := spritesmodels.CmdAddSound{
-		Path:      path,
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("Failed to read sound file: %s, %v\n", path, err)
+		return
+	}
+	if err := sim.addSoundData(path, name, data); err != nil {
+		log.Println(err)
+	}
+}
+
+func (sim *simState) AddSynthSound(name string, spec spritesmodels.SynthSpec) {
+	data, err := spritestools.RenderSynth(spec, game.SampleRate)
+	if err != nil {
+		log.Printf("Failed to create synth sound: %s, %v\n", name, err)
+		return
+	}
+
+	cmd := spritesmodels.CmdAddSoundData{
 		SoundName: name,
+		Data:      data,
 	}
-	sim.cmdChan <- cmd
+	sim.cmdQueue.Push(cmd)
+}
+
+func (sim *simState) PlaySound(name string, volume float64) SoundHandle {
+	return sim.playSound(spritesmodels.CmdPlaySound{
+		SoundName: name,
+		Volume:    volume,
+		Bus:       spritesmodels.BusSfx,
+	})
 }
 
-func (sim *simState) PlaySound(name string, volume float64) {
-	cmd := spritesmodels.CmdPlaySound{
+func (sim *simState) PlaySoundOnBus(name, bus string, volume float64) SoundHandle {
+	return sim.playSound(spritesmodels.CmdPlaySound{
 		SoundName: name,
 		Volume:    volume,
+		Bus:       bus,
+	})
+}
+
+func (sim *simState) PlaySoundAt(name string, volume, x, y float64) SoundHandle {
+	return sim.playSound(spritesmodels.CmdPlaySound{
+		SoundName:  name,
+		Volume:     volume,
+		Bus:        spritesmodels.BusSfx,
+		Positional: true,
+		X:          x,
+		Y:          y,
+	})
+}
+
+func (sim *simState) playSound(cmd spritesmodels.CmdPlaySound) SoundHandle {
+	cmd.HandleID = sim.g.GetNextSoundID()
+	sim.cmdQueue.Push(cmd)
+
+	return &soundHandle{sim: sim, handleID: cmd.HandleID}
+}
+
+func (sim *simState) PlayMusic(path string, loop bool) {
+	cmd := spritesmodels.CmdPlayMusic{
+		Path:   path,
+		Loop:   loop,
+		Volume: 1,
 	}
-	sim.cmdChan <- cmd
+	sim.cmdQueue.Push(cmd)
+}
+
+func (sim *simState) StopMusic() {
+	sim.cmdQueue.Push(spritesmodels.CmdStopMusic{})
+}
+
+func (sim *simState) SetMasterVolume(volume float64) {
+	sim.cmdQueue.Push(spritesmodels.CmdSetBusVolume{Volume: volume})
+}
+
+func (sim *simState) SetBusVolume(bus string, volume float64) {
+	sim.cmdQueue.Push(spritesmodels.CmdSetBusVolume{Bus: bus, Volume: volume})
+}
+
+func (sim *simState) MuteBus(bus string, mute bool) {
+	sim.cmdQueue.Push(spritesmodels.CmdMuteBus{Bus: bus, Mute: mute})
+}
+
+// Blocks until the game has compiled the shader, so that compile errors can be returned.
+func (sim *simState) AddShader(name string, kageSource []byte) error {
+	errChan := make(chan error)
+	sim.cmdQueue.Push(spritesmodels.CmdAddShader{
+		ShaderName: name,
+		Source:     kageSource,
+		ErrChan:    errChan,
+	})
+	return <-errChan
+}
+
+func (sim *simState) SetSpriteShader(spriteID int, shaderName string, uniforms spritesmodels.ShaderUniforms) {
+	sim.cmdQueue.Push(spritesmodels.CmdSpriteShader{
+		SpriteID:   spriteID,
+		ShaderName: shaderName,
+		Uniforms:   maps.Clone(uniforms), // The caller is free to keep changing their map.
+	})
+}
+
+func (sim *simState) SetPostProcessing(passes ...spritesmodels.ShaderPass) {
+	cmd := spritesmodels.CmdSetPostProcessing{
+		Passes: make([]spritesmodels.ShaderPass, len(passes)),
+	}
+	for i, p := range passes {
+		cmd.Passes[i] = spritesmodels.ShaderPass{ShaderName: p.ShaderName, Uniforms: maps.Clone(p.Uniforms)}
+	}
+	sim.cmdQueue.Push(cmd)
 }
 
 func (sim *simState) WhoIsNearMe(x, y, distance float64) []spritesmodels.NearMeInfo {
 	return sim.posBroker.GetSpritesNearMe(x, y, distance)
 }
 
+func (sim *simState) WhoIsWithin(x, y, distance float64, filter spritesmodels.NearMeFilter) []spritesmodels.NearMeInfo {
+	return sim.posBroker.GetSpritesWithin(x, y, distance, filter)
+}
+
+func (sim *simState) NearestSprites(x, y float64, k int, filter spritesmodels.NearMeFilter) []spritesmodels.NearMeInfo {
+	return sim.posBroker.GetNearestSprites(x, y, k, filter)
+}
+
+func (sim *simState) WhoIsInRect(rect spritesmodels.Rect, filter spritesmodels.NearMeFilter) []spritesmodels.NearMeInfo {
+	return sim.posBroker.GetSpritesInRect(rect, filter)
+}
+
+func (sim *simState) WhoIsAlongRay(x, y, dirX, dirY, maxDistance, width float64, filter spritesmodels.NearMeFilter) []spritesmodels.NearMeInfo {
+	return sim.posBroker.GetSpritesAlongRay(x, y, dirX, dirY, maxDistance, width, filter)
+}
+
+func (sim *simState) HasLineOfSight(x1, y1, x2, y2, width float64, filter spritesmodels.NearMeFilter) bool {
+	return sim.posBroker.HasLineOfSight(x1, y1, x2, y2, width, filter)
+}
+
+func (sim *simState) Raycast(fromX, fromY, dirX, dirY, maxDistance float64, filter spritesmodels.NearMeFilter) (spritesmodels.RayHit, bool) {
+	filter.Limit = 1
+	hits := sim.RaycastAll(fromX, fromY, dirX, dirY, maxDistance, filter)
+	if len(hits) == 0 {
+		return spritesmodels.RayHit{}, false
+	}
+	return hits[0], true
+}
+
+func (sim *simState) RaycastAll(fromX, fromY, dirX, dirY, maxDistance float64, filter spritesmodels.NearMeFilter) []spritesmodels.RayHit {
+	margin := math.Float64frombits(sim.maxBodyRadius.Load())
+	return sim.posBroker.Raycast(fromX, fromY, dirX, dirY, maxDistance, margin, filter, func(info spritesmodels.NearMeInfo) (spritesmodels.RayHit, bool) {
+		sim.idToSpriteMapMutex.RLock()
+		sprite, ok := sim.idToSpriteMap[info.SpriteID]
+		sim.idToSpriteMapMutex.RUnlock()
+		if !ok {
+			return spritesmodels.RayHit{}, false
+		}
+		body := sprite.GetClickBody()
+		if body == nil {
+			return spritesmodels.RayHit{}, false
+		}
+		return body.Raycast(fromX, fromY, dirX, dirY, maxDistance)
+	})
+}
+
+func (sim *simState) BuildNavGrid(cellSize float64, obstacleTypes ...int) *spritestools.NavGrid {
+	halfW, halfH := float64(sim.width)/2, float64(sim.height)/2
+	ret := spritestools.NewNavGrid(spritesmodels.Rect{MinX: -halfW, MinY: -halfH, MaxX: halfW, MaxY: halfH}, cellSize)
+
+	sim.idToSpriteMapMutex.RLock()
+	defer sim.idToSpriteMapMutex.RUnlock()
+	for id, s := range sim.idToSpriteMap {
+		state := sim.posBroker.GetSpriteInfo(id)
+		if !slices.Contains(obstacleTypes, state.SpriteType) {
+			continue
+		}
+		body := s.GetClickBody()
+		if body == nil || len(body.Outlines()) == 0 {
+			ret.SetBlocked(state.X, state.Y, true)
+			continue
+		}
+		ret.BlockBody(body)
+	}
+	return ret
+}
+
+// Only ever grows. Positive floats sort the same as their bits, so the bits can be compared directly.
+func (s *simState) noteBodyRadius(body spritesmodels.ClickOnBody) {
+	if body == nil {
+		return
+	}
+	radius := body.BoundingRadius()
+	if !(radius > 0) {
+		return
+	}
+	bits := math.Float64bits(radius)
+	for {
+		old := s.maxBodyRadius.Load()
+		if bits <= old || s.maxBodyRadius.CompareAndSwap(old, bits) {
+			return
+		}
+	}
+}
+
 func (sim *simState) SendMsg(toSpriteID int, msg any) {
 	sim.idToSpriteMapMutex.RLock()
 	toSprite, ok := sim.idToSpriteMap[toSpriteID]
@@ -254,13 +643,50 @@ func (sim *simState) SendMsg(toSpriteID int, msg any) {
 	toSprite.AddMsg(msg)
 }
 
+func (sim *simState) RenderStats() spritesmodels.RenderStats {
+	return sim.g.GetRenderStats()
+}
+
+func (sim *simState) CommandQueueStats() spritesmodels.CommandQueueStats {
+	return sim.cmdQueue.Stats()
+}
+
+func (sim *simState) ShowDebugHUD(show bool) {
+	sim.cmdQueue.Push(spritesmodels.CmdShowDebugHUD{Show: show})
+}
+
+func (sim *simState) ShowDebugOverlay(show bool) {
+	sim.cmdQueue.Push(spritesmodels.CmdShowDebugOverlay{Show: show})
+}
+
+// Called from the game's Draw while the debug overlay is showing.
+func (sim *simState) debugOverlayInfo() spritesmodels.DebugOverlayInfo {
+	ret := spritesmodels.DebugOverlayInfo{
+		GridCells: sim.posBroker.OccupiedCells(),
+	}
+
+	sim.idToSpriteMapMutex.RLock()
+	defer sim.idToSpriteMapMutex.RUnlock()
+	for id, s := range sim.idToSpriteMap {
+		info := spritesmodels.DebugSpriteInfo{
+			SpriteID:   id,
+			UniqueName: s.GetUniqueName(),
+		}
+		if body := s.GetClickBody(); body != nil {
+			info.ClickOutlines = body.Outlines()
+		}
+		ret.Sprites = append(ret.Sprites, info)
+	}
+	return ret
+}
+
 func (sim *simState) GetScreenshot() image.Image {
 	screenshotChan := make(chan image.Image)
 
 	cmd := spritesmodels.CmdGetScreenshot{
 		ImageChan: screenshotChan,
 	}
-	sim.cmdChan <- cmd
+	sim.cmdQueue.Push(cmd)
 
 	// Now wait for the screenshot to arrive.
 	screenshot := <-screenshotChan
@@ -0,0 +1,58 @@
+package sprites
+
+import (
+	"time"
+
+	"github.com/gary23b/sprites/spritesmodels"
+)
+
+// A handle to a sound started with Sim.PlaySound. Calls on a sound that has already finished are ignored.
+type SoundHandle interface {
+	Stop()
+	Pause()
+	Resume()
+	SetVolume(volume float64)                    // volume must be between 0 and 1.
+	Fade(volume float64, duration time.Duration) // Smoothly change to the given volume.
+	Seek(offset time.Duration)                   // Jump to the given time from the start of the sound.
+	Loop(loop bool)                              // Start over from the beginning every time the end is reached.
+}
+
+type soundHandle struct {
+	sim      *simState
+	handleID int
+}
+
+var _ SoundHandle = &soundHandle{}
+
+func (h *soundHandle) send(cmd spritesmodels.CmdSoundControl) {
+	cmd.HandleID = h.handleID
+	h.sim.cmdQueue.Push(cmd)
+}
+
+func (h *soundHandle) Stop() {
+	h.send(spritesmodels.CmdSoundControl{Action: spritesmodels.SoundStop})
+}
+
+func (h *soundHandle) Pause() {
+	h.send(spritesmodels.CmdSoundControl{Action: spritesmodels.SoundPause})
+}
+
+func (h *soundHandle) Resume() {
+	h.send(spritesmodels.CmdSoundControl{Action: spritesmodels.SoundResume})
+}
+
+func (h *soundHandle) SetVolume(volume float64) {
+	h.send(spritesmodels.CmdSoundControl{Action: spritesmodels.SoundSetVolume, Volume: volume})
+}
+
+func (h *soundHandle) Fade(volume float64, duration time.Duration) {
+	h.send(spritesmodels.CmdSoundControl{Action: spritesmodels.SoundFade, Volume: volume, Duration: duration})
+}
+
+func (h *soundHandle) Seek(offset time.Duration) {
+	h.send(spritesmodels.CmdSoundControl{Action: spritesmodels.SoundSeek, Duration: offset})
+}
+
+func (h *soundHandle) Loop(loop bool) {
+	h.send(spritesmodels.CmdSoundControl{Action: spritesmodels.SoundLoop, Loop: loop})
+}
@@ -2,39 +2,73 @@ package sprites
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"image"
-	"log"
+	"io"
+	"io/fs"
+	"maps"
 	"math"
 	"os"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/gary23b/sprites/spritesmodels"
 	"github.com/gary23b/sprites/spritestools"
 )
 
+// Every method is safe to call from any go routine. Changes are applied one at a time, and the game gets them in the
+// order they were made. Once a sprite is deleted, its updates do nothing and return ErrSpriteDeleted.
 type Sprite interface {
 	GetSpriteID() int
 	GetUniqueName() string
 	Clone(UniqueName string) Sprite
 
 	// Updates
-	Costume(name string)
-	SetType(newType int)
-	Angle(angleDegrees float64)
-	Pos(cartX, cartY float64) // Cartesian (x,y). Center in the middle of the window
-	Z(int)                    //
-	Visible(visible bool)
-	Scale(scale float64) // Sets xScale and yScale together
-	XYScale(xScale, yScale float64)
-	Opacity(opacityPercent float64) // 0 is completely transparent and 100 is completely opaque
-	All(in spritesmodels.SpriteState)
+	Costume(name string) error
+	SetType(newType int) error
+	Angle(angleDegrees float64) error
+	Pos(cartX, cartY float64) error // Cartesian (x,y). Center in the middle of the window
+	Z(z float64) error              // Higher is drawn on top. Sprites with the same Z are drawn in the order they got it.
+	Visible(visible bool) error
+	Scale(scale float64) error // Sets xScale and yScale together
+	XYScale(xScale, yScale float64) error
+	Flip(flipX, flipY bool) error                    // Mirrors the costume around the pivot.
+	Pivot(x, y float64) error                        // Cartesian offset from the costume center to rotate and scale around. This point sits at Pos.
+	Skew(xAngleDegrees, yAngleDegrees float64) error // Slants the costume. A positive x angle leans the top to the right.
+	Opacity(opacityPercent float64) error            // 0 is completely transparent and 100 is completely opaque
+	Effects(in spritesmodels.SpriteEffects) error
+	GhostEffect(ghostPercent float64) error // Like Scratch. 0 is normal and 100 is invisible.
+	ColorEffect(amount float64) error       // Like Scratch. Shifts the hue, where 200 goes all the way around the color wheel.
+	ClearEffects() error
+	// Like Scratch, but only among sprites with the same Z. In a Y-sorted Z, the y comes first, so these only
+	// reorder sprites at the same y.
+	BringToFront() error
+	SendToBack() error
+	MoveForward(steps int) error
+	MoveBackward(steps int) error
+	SetShader(shaderName string, uniforms spritesmodels.ShaderUniforms) error // Draws the costume with a Kage shader. An empty name goes back to normal.
+	// Sets everything at once. The values are relative to the parent, if there is one.
+	All(in spritesmodels.SpriteState) error
+	// Makes all the changes in f as one update, so the sprite is never drawn half changed. f runs before anything
+	// is locked, and the changes are made after it returns.
+	Batch(f func(b SpriteBuilder)) error
 
 	// Info
-	GetState() spritesmodels.SpriteState
+	GetState() spritesmodels.SpriteState // World values, after the parent is applied.
+
+	// Hierarchy. A child's position, angle, scale, and flips are relative to its parent's pivot.
+	// It is also hidden and faded along with its parent, and deleted with it.
+	SetParent(parent Sprite) error // nil detaches. The local values are kept, so the sprite jumps to its new place. Fails on loops and deleted parents.
+	GetParent() Sprite             // nil if there is no parent
+	GetChildren() []Sprite
+	LocalTransform() spritesmodels.SpriteTransform
+	WorldTransform() spritesmodels.SpriteTransform
 
 	// Click Body
 	GetClickBody() spritesmodels.ClickOnBody
-	ReplaceClickBody(in spritesmodels.ClickOnBody)
+	ReplaceClickBody(in spritesmodels.ClickOnBody) error
 
 	// User Input
 	PressedUserInput() *spritesmodels.UserInput
@@ -42,12 +76,42 @@ type Sprite interface {
 
 	// Interact With other sprites
 	WhoIsNearMe(distance float64) []spritesmodels.NearMeInfo
+	// These leave out the sprite itself.
+	WhoIsWithin(distance float64, filter spritesmodels.NearMeFilter) []spritesmodels.NearMeInfo
+	NearestSprites(k int, filter spritesmodels.NearMeFilter) []spritesmodels.NearMeInfo
+	WhoIsAhead(maxDistance, width float64, filter spritesmodels.NearMeFilter) []spritesmodels.NearMeInfo // Along the direction the sprite is facing
+	CanSee(x, y, width float64, filter spritesmodels.NearMeFilter) bool
+	RaycastAhead(maxDistance float64, filter spritesmodels.NearMeFilter) (spritesmodels.RayHit, bool) // The first click body in front of the sprite
+
+	// Movement
+	// Walks through each point in turn at speed pixels per second, facing the way it is going. Blocks until the end.
+	// The points are in the same coordinates as Pos. Paths from a NavGrid or FlowField are in world coordinates.
+	MoveAlongPath(path []spritesmodels.Point, speed float64) error
+	// The game moves the sprite every tick with these, and GetState follows along. Only for sprites without a parent.
+	SetVelocity(velX, velY float64) error // Pixels per second
+	SetAcceleration(accelX, accelY float64) error
+	SetAngularVelocity(degreesPerSecond float64) error
+	SetDrag(drag float64) error // The fraction of the velocity lost each second, from 0 to 1.
+	SetMaxSpeed(maxSpeed float64) error
+	SetEdgeBehavior(edge spritesmodels.EdgeBehavior) error
+	SetKinematics(in spritesmodels.Kinematics) error // All of the above at once
 	SendMsg(toSpriteID int, msg any)
 	GetMsgs() []any
 	AddMsg(msg any)
 
+	// Sound
+	PlaySound(name string) SoundHandle // Panned and attenuated based on where the sprite is on the screen when it starts.
+
+	// Callbacks
+	// An alternative to running a go routine for each sprite. The sim calls these CallbackTickRate times a second from
+	// a pool of workers. Each tick, clicks are handled first, then messages in the order they were sent, then OnTick.
+	// Different sprites run in parallel, but one sprite's callbacks never run at the same time. nil removes a callback.
+	OnTick(f func(dt float64))                      // dt is the seconds since the last tick.
+	OnMessage(f func(msg any))                      // Takes the place of GetMsgs.
+	OnClick(f func(input *spritesmodels.UserInput)) // Only the top visible sprite under a left click gets it.
+
 	// exit
-	DeleteSprite()
+	DeleteSprite() error // Children are deleted too.
 }
 
 func LoadSpriteFile(path string) (image.Image, error) {
@@ -55,33 +119,82 @@ func LoadSpriteFile(path string) (image.Image, error) {
 	if err != nil {
 		return nil, fmt.Errorf("Failed to read sprite file: %s, %w", path, err)
 	}
-	img, _, err := image.Decode(bytes.NewReader(spriteFileData))
+	img, err := LoadSpriteReader(bytes.NewReader(spriteFileData))
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", path, err)
+	}
+	return img, nil
+}
+
+// Same as LoadSpriteFile, but the file is read from fsys. This works with embed.FS, which is handy for wasm builds.
+func LoadSpriteFS(fsys fs.FS, path string) (image.Image, error) {
+	spriteFileData, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read sprite file: %s, %w", path, err)
+	}
+	img, err := LoadSpriteReader(bytes.NewReader(spriteFileData))
+	if err != nil {
+		return nil, fmt.Errorf("%s, %w", path, err)
+	}
+	return img, nil
+}
+
+// Decodes an image in any format registered with the image package. PNG is always available.
+func LoadSpriteReader(r io.Reader) (image.Image, error) {
+	img, _, err := image.Decode(r)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to decode image data: %s, %w", path, err)
+		return nil, fmt.Errorf("Failed to decode image data: %w", err)
 	}
 	return img, nil
 }
 
+// Returned by the updates of a sprite that has been deleted.
+var ErrSpriteDeleted = errors.New("sprite is deleted")
+
 type sprite struct {
 	sim Sim
 
-	spriteID    int
+	spriteID   int
+	UniqueName string
+
+	// updateMutex is held from when a change is made until it is sent, so the game gets changes in the order they
+	// were made and nothing is sent once the sprite is deleted. Lock a parent's before its children's.
+	updateMutex sync.Mutex
+
+	stateMutex  sync.RWMutex // Protects everything down to the family.
 	spriteType  int
-	UniqueName  string
 	costumeName string
 	x, y        float64
-	z           int
+	z           float64
 	angleRad    float64
 	visible     bool
 	opacity     float64
 	scaleX      float64
 	scaleY      float64
-
-	deleted bool
+	flipX       bool
+	flipY       bool
+	pivotX      float64
+	pivotY      float64
+	skewXRad    float64
+	skewYRad    float64
+	effects     spritesmodels.SpriteEffects
+	shaderName  string
+	uniforms    spritesmodels.ShaderUniforms
+	kinematics  spritesmodels.Kinematics
+	generation  int // Counts the times the position or kinematics were set by hand. See KinematicsUpdate.
+	deleted     bool
 
 	clickBody     spritesmodels.ClickOnBody
 	userInputChan chan *spritesmodels.UserInput
-	receivedMsgs  chan any
+
+	familyMutex sync.Mutex // Protects parent and children. Parents update their children from their own go routine.
+	parent      *sprite
+	children    []*sprite
+
+	callbackMutex sync.Mutex
+	callbacks     spriteCallbacks
+
+	receivedMsgs chan any
 }
 
 var _ Sprite = &sprite{}
@@ -101,10 +214,21 @@ func NewSprite(sim Sim, uniqueName string, spriteID int) *sprite {
 }
 
 func (s *sprite) Clone(uniqueName string) Sprite {
+	s.stateMutex.RLock()
+	clickBody := s.clickBody
+	shaderName, uniforms := s.shaderName, s.uniforms
+	s.stateMutex.RUnlock()
+
 	sClone := s.sim.AddSprite(uniqueName)
-	sClone.All(s.GetState())
-	if s.clickBody != nil {
-		sClone.ReplaceClickBody(s.clickBody.Clone())
+	if p := s.getParent(); p != nil {
+		sClone.SetParent(p)
+	}
+	sClone.All(s.localState())
+	if clickBody != nil {
+		sClone.ReplaceClickBody(clickBody.Clone())
+	}
+	if shaderName != "" {
+		sClone.SetShader(shaderName, uniforms)
 	}
 	return sClone
 }
@@ -117,88 +241,221 @@ func (s *sprite) GetUniqueName() string {
 	return s.UniqueName
 }
 
+func (s *sprite) getGeneration() int {
+	s.stateMutex.RLock()
+	defer s.stateMutex.RUnlock()
+	return s.generation
+}
+
+func (s *sprite) isDeleted() bool {
+	s.stateMutex.RLock()
+	defer s.stateMutex.RUnlock()
+	return s.deleted
+}
+
+// Runs change under the state lock, unless the sprite is deleted. The caller holds updateMutex.
+func (s *sprite) changeState(change func()) error {
+	s.stateMutex.Lock()
+	defer s.stateMutex.Unlock()
+	if s.deleted {
+		return fmt.Errorf("Failed to update sprite %d: %w", s.spriteID, ErrSpriteDeleted)
+	}
+	if change != nil {
+		change()
+	}
+	return nil
+}
+
+// Makes the change and sends the result to the game. A full update sends everything, not just the position and angle.
+func (s *sprite) update(full bool, change func()) error {
+	s.updateMutex.Lock()
+	defer s.updateMutex.Unlock()
+	if err := s.changeState(change); err != nil {
+		return err
+	}
+	s.send(full)
+	return nil
+}
+
 // Updates
-func (s *sprite) Costume(name string) {
-	s.costumeName = name
-	s.fullUpdate()
+func (s *sprite) Costume(name string) error {
+	return s.update(true, func() { s.costumeName = name })
 }
 
-func (s *sprite) SetType(newType int) {
-	s.spriteType = newType
-	s.minUpdate()
+func (s *sprite) SetType(newType int) error {
+	return s.update(false, func() { s.spriteType = newType })
 }
 
-func (s *sprite) Angle(angleDegrees float64) {
-	s.angleRad = angleDegrees * (math.Pi / 180.0)
-	s.minUpdate()
+func (s *sprite) Angle(angleDegrees float64) error {
+	return s.update(false, func() {
+		s.angleRad = angleDegrees * (math.Pi / 180.0)
+		s.generation++
+	})
+}
 
-	s.clickBody.Angle(s.angleRad)
+func (s *sprite) Pos(cartX, cartY float64) error {
+	return s.update(false, func() {
+		s.x = cartX
+		s.y = cartY
+		s.generation++
+	})
 }
 
-func (s *sprite) Pos(cartX, cartY float64) {
-	s.x = cartX
-	s.y = cartY
-	s.minUpdate()
+func (s *sprite) Z(z float64) error {
+	if math.IsNaN(z) {
+		return fmt.Errorf("Z must be a number")
+	}
+	return s.update(true, func() { s.z = z })
+}
 
-	s.clickBody.Pos(s.x, s.y)
+func (s *sprite) BringToFront() error {
+	return s.changeDrawOrder(spritesmodels.OrderFront, 0)
 }
 
-func (s *sprite) Z(z int) {
-	if z < 0 || z > 9 {
-		log.Println("Z must be from 0 to 9")
-		return
+func (s *sprite) SendToBack() error {
+	return s.changeDrawOrder(spritesmodels.OrderBack, 0)
+}
+
+func (s *sprite) MoveForward(steps int) error {
+	return s.changeDrawOrder(spritesmodels.OrderForward, steps)
+}
+
+func (s *sprite) MoveBackward(steps int) error {
+	return s.changeDrawOrder(spritesmodels.OrderBackward, steps)
+}
+
+func (s *sprite) changeDrawOrder(action spritesmodels.DrawOrderAction, steps int) error {
+	s.updateMutex.Lock()
+	defer s.updateMutex.Unlock()
+	if err := s.changeState(nil); err != nil {
+		return err
 	}
+	s.sim.SetSpriteDrawOrder(s.spriteID, action, steps)
+	return nil
+}
 
-	s.z = z
-	s.fullUpdate()
+func (s *sprite) Visible(visible bool) error {
+	return s.update(true, func() { s.visible = visible })
 }
 
-func (s *sprite) Visible(visible bool) {
-	s.visible = visible
-	s.fullUpdate()
+func (s *sprite) Scale(scale float64) error {
+	return s.update(true, func() {
+		s.scaleX = scale
+		s.scaleY = scale
+	})
 }
 
-func (s *sprite) Scale(scale float64) {
-	s.scaleX = scale
-	s.scaleY = scale
-	s.fullUpdate()
+func (s *sprite) XYScale(xScale, yScale float64) error {
+	return s.update(true, func() {
+		s.scaleX = xScale
+		s.scaleY = yScale
+	})
 }
 
-func (s *sprite) XYScale(xScale, yScale float64) {
-	s.scaleX = xScale
-	s.scaleY = yScale
-	s.fullUpdate()
+func (s *sprite) Flip(flipX, flipY bool) error {
+	return s.update(true, func() {
+		s.flipX = flipX
+		s.flipY = flipY
+	})
 }
 
-func (s *sprite) Opacity(opacityPercent float64) {
-	s.opacity = opacityPercent
-	s.fullUpdate()
+func (s *sprite) Pivot(x, y float64) error {
+	return s.update(true, func() {
+		s.pivotX = x
+		s.pivotY = y
+	})
 }
 
-func (s *sprite) All(in spritesmodels.SpriteState) {
-	if in.Z < 0 || in.Z > 9 {
-		log.Println("Z must be from 0 to 9")
-		return
+func (s *sprite) Skew(xAngleDegrees, yAngleDegrees float64) error {
+	return s.update(true, func() {
+		s.skewXRad = xAngleDegrees * (math.Pi / 180.0)
+		s.skewYRad = yAngleDegrees * (math.Pi / 180.0)
+	})
+}
+
+func (s *sprite) Opacity(opacityPercent float64) error {
+	return s.update(true, func() { s.opacity = opacityPercent })
+}
+
+func (s *sprite) Effects(in spritesmodels.SpriteEffects) error {
+	return s.update(true, func() { s.effects = in })
+}
+
+func (s *sprite) GhostEffect(ghostPercent float64) error {
+	return s.update(true, func() { s.effects.Ghost = ghostPercent })
+}
+
+func (s *sprite) ColorEffect(amount float64) error {
+	return s.update(true, func() { s.effects.HueShift = amount * 360.0 / 200.0 })
+}
+
+func (s *sprite) ClearEffects() error {
+	return s.update(true, func() { s.effects = spritesmodels.SpriteEffects{} })
+}
+
+func (s *sprite) SetShader(shaderName string, uniforms spritesmodels.ShaderUniforms) error {
+	s.updateMutex.Lock()
+	defer s.updateMutex.Unlock()
+	uniforms = maps.Clone(uniforms)
+	err := s.changeState(func() {
+		s.shaderName = shaderName
+		s.uniforms = uniforms
+	})
+	if err != nil {
+		return err
 	}
+	s.sim.SetSpriteShader(s.spriteID, shaderName, uniforms)
+	return nil
+}
 
-	s.spriteType = in.SpriteType
-	s.costumeName = in.CostumeName
-	s.x = in.X
-	s.y = in.Y
-	s.z = in.Z
-	s.angleRad = in.AngleDegrees * (math.Pi / 180.0)
-	s.visible = in.Visible
-	s.opacity = in.Opacity
-	s.scaleX = in.ScaleX
-	s.scaleY = in.ScaleY
+func (s *sprite) All(in spritesmodels.SpriteState) error {
+	if math.IsNaN(in.Z) {
+		return fmt.Errorf("Z must be a number")
+	}
 
-	s.fullUpdate()
+	kinematicsChanged := false
+	err := s.update(true, func() {
+		s.spriteType = in.SpriteType
+		s.costumeName = in.CostumeName
+		s.z = in.Z
+		s.x = in.X
+		s.y = in.Y
+		s.angleRad = in.AngleDegrees * (math.Pi / 180.0)
+		s.generation++
+		kinematicsChanged = s.kinematics != in.Kinematics
+		s.visible = in.Visible
+		s.opacity = in.Opacity
+		s.scaleX = in.ScaleX
+		s.scaleY = in.ScaleY
+		s.flipX = in.FlipX
+		s.flipY = in.FlipY
+		s.pivotX = in.PivotX
+		s.pivotY = in.PivotY
+		s.skewXRad = in.SkewX * (math.Pi / 180.0)
+		s.skewYRad = in.SkewY * (math.Pi / 180.0)
+		s.effects = in.Effects
+	})
+	if err != nil {
+		return err
+	}
 
-	s.clickBody.Pos(s.x, s.y)
-	s.clickBody.Angle(s.angleRad)
+	if kinematicsChanged {
+		return s.SetKinematics(in.Kinematics)
+	}
+	return nil
 }
 
 func (s *sprite) GetState() spritesmodels.SpriteState {
+	state := s.localState()
+	if p := s.getParent(); p != nil {
+		state = spritestools.ApplyParentState(p.GetState(), state)
+	}
+	return state
+}
+
+func (s *sprite) localState() spritesmodels.SpriteState {
+	s.stateMutex.RLock()
+	defer s.stateMutex.RUnlock()
 	return spritesmodels.SpriteState{
 		SpriteID:     s.spriteID,
 		SpriteType:   s.spriteType,
@@ -212,27 +469,103 @@ func (s *sprite) GetState() spritesmodels.SpriteState {
 		ScaleX:       s.scaleX,
 		ScaleY:       s.scaleY,
 		Opacity:      s.opacity,
+		Effects:      s.effects,
+		FlipX:        s.flipX,
+		FlipY:        s.flipY,
+		PivotX:       s.pivotX,
+		PivotY:       s.pivotY,
+		SkewX:        s.skewXRad * (180.0 / math.Pi),
+		SkewY:        s.skewYRad * (180.0 / math.Pi),
+		Kinematics:   s.kinematics,
 		Deleted:      s.deleted,
 	}
 }
 
-func (s *sprite) DeleteSprite() {
-	if s.deleted {
-		log.Printf("Error: sprite %d is deleted but being deleted again\n", s.spriteID)
-		return
+func (s *sprite) LocalTransform() spritesmodels.SpriteTransform {
+	s.stateMutex.RLock()
+	defer s.stateMutex.RUnlock()
+	return spritesmodels.SpriteTransform{
+		X:        s.x,
+		Y:        s.y,
+		AngleRad: s.angleRad,
+		ScaleX:   s.scaleX,
+		ScaleY:   s.scaleY,
+		FlipX:    s.flipX,
+		FlipY:    s.flipY,
+		PivotX:   s.pivotX,
+		PivotY:   s.pivotY,
+		SkewX:    s.skewXRad,
+		SkewY:    s.skewYRad,
 	}
+}
+
+func (s *sprite) WorldTransform() spritesmodels.SpriteTransform {
+	return spritestools.SpriteStateTransform(s.GetState())
+}
+
+func (s *sprite) updateClickBody() {
+	s.GetClickBody().Transform(s.WorldTransform())
+}
+
+func (s *sprite) DeleteSprite() error {
+	s.updateMutex.Lock()
+	defer s.updateMutex.Unlock()
+	return s.deleteLocked()
+}
+
+// The caller holds updateMutex.
+func (s *sprite) deleteLocked() error {
+	if err := s.changeState(func() { s.deleted = true }); err != nil {
+		return err
+	}
+
+	for _, child := range s.getChildren() {
+		child.deleteWithParent(s)
+	}
+	if p := s.getParent(); p != nil {
+		p.removeChild(s)
+	}
+
+	if sim, ok := s.sim.(*simState); ok {
+		sim.removeSprite(s)
+	} else {
+		s.sim.DeleteSprite(s)
+	}
+	return nil
+}
 
-	s.sim.DeleteSprite(s)
+// Does nothing if the sprite was moved to another parent in the meantime.
+func (s *sprite) deleteWithParent(parent *sprite) {
+	s.updateMutex.Lock()
+	defer s.updateMutex.Unlock()
+	if s.getParent() == parent {
+		s.deleteLocked()
+	}
+}
+
+// For when the sim deletes every sprite at once. Nothing is sent for the sprite after this returns.
+func (s *sprite) markDeleted() {
+	s.updateMutex.Lock()
+	defer s.updateMutex.Unlock()
+	s.stateMutex.Lock()
+	defer s.stateMutex.Unlock()
+	s.deleted = true
 }
 
 func (s *sprite) GetClickBody() spritesmodels.ClickOnBody {
+	s.stateMutex.RLock()
+	defer s.stateMutex.RUnlock()
 	return s.clickBody
 }
 
-func (s *sprite) ReplaceClickBody(in spritesmodels.ClickOnBody) {
-	s.clickBody = in
-	s.clickBody.Pos(s.x, s.y)
-	s.clickBody.Angle(s.angleRad)
+func (s *sprite) ReplaceClickBody(in spritesmodels.ClickOnBody) error {
+	s.updateMutex.Lock()
+	defer s.updateMutex.Unlock()
+	if err := s.changeState(func() { s.clickBody = in }); err != nil {
+		return err
+	}
+	s.updateClickBody()
+	return nil
 }
 
 func (s *sprite) PressedUserInput() *spritesmodels.UserInput {
@@ -240,12 +573,15 @@ func (s *sprite) PressedUserInput() *spritesmodels.UserInput {
 }
 
 func (s *sprite) JustPressedUserInput() *spritesmodels.UserInput {
+	s.stateMutex.Lock()
 	if s.userInputChan == nil {
 		s.userInputChan = s.sim.SubscribeToJustPressedUserInput()
 	}
+	userInputChan := s.userInputChan
+	s.stateMutex.Unlock()
 
 	select {
-	case i := <-s.userInputChan:
+	case i := <-userInputChan:
 		return i
 	default:
 		// receiving from chan would block without this
@@ -255,7 +591,168 @@ func (s *sprite) JustPressedUserInput() *spritesmodels.UserInput {
 }
 
 func (s *sprite) WhoIsNearMe(distance float64) []spritesmodels.NearMeInfo {
-	return s.sim.WhoIsNearMe(s.x, s.y, distance)
+	state := s.GetState()
+	return s.sim.WhoIsNearMe(state.X, state.Y, distance)
+}
+
+// The caller's slice is not modified.
+func (s *sprite) excludeSelf(filter spritesmodels.NearMeFilter) spritesmodels.NearMeFilter {
+	filter.ExcludeIDs = append(slices.Clip(filter.ExcludeIDs), s.spriteID)
+	return filter
+}
+
+func (s *sprite) WhoIsWithin(distance float64, filter spritesmodels.NearMeFilter) []spritesmodels.NearMeInfo {
+	state := s.GetState()
+	return s.sim.WhoIsWithin(state.X, state.Y, distance, s.excludeSelf(filter))
+}
+
+func (s *sprite) NearestSprites(k int, filter spritesmodels.NearMeFilter) []spritesmodels.NearMeInfo {
+	state := s.GetState()
+	return s.sim.NearestSprites(state.X, state.Y, k, s.excludeSelf(filter))
+}
+
+func (s *sprite) WhoIsAhead(maxDistance, width float64, filter spritesmodels.NearMeFilter) []spritesmodels.NearMeInfo {
+	state := s.GetState()
+	angleRad := state.AngleDegrees * (math.Pi / 180.0)
+	return s.sim.WhoIsAlongRay(state.X, state.Y, math.Cos(angleRad), math.Sin(angleRad), maxDistance, width, s.excludeSelf(filter))
+}
+
+func (s *sprite) RaycastAhead(maxDistance float64, filter spritesmodels.NearMeFilter) (spritesmodels.RayHit, bool) {
+	state := s.GetState()
+	angleRad := state.AngleDegrees * (math.Pi / 180.0)
+	return s.sim.Raycast(state.X, state.Y, math.Cos(angleRad), math.Sin(angleRad), maxDistance, s.excludeSelf(filter))
+}
+
+func (s *sprite) CanSee(x, y, width float64, filter spritesmodels.NearMeFilter) bool {
+	state := s.GetState()
+	return s.sim.HasLineOfSight(state.X, state.Y, x, y, width, s.excludeSelf(filter))
+}
+
+// One step per frame.
+const pathStepPeriod = time.Second / 60
+
+func (s *sprite) MoveAlongPath(path []spritesmodels.Point, speed float64) error {
+	if !(speed > 0) {
+		return nil
+	}
+	step := speed * pathStepPeriod.Seconds()
+	ticker := time.NewTicker(pathStepPeriod)
+	defer ticker.Stop()
+
+	i := 0
+	for i < len(path) {
+		// Corners closer together than one step are passed in the same frame.
+		err := s.update(false, func() {
+			s.generation++
+			remaining := step
+			for i < len(path) && remaining > 0 {
+				dx, dy := path[i].X-s.x, path[i].Y-s.y
+				dist := math.Hypot(dx, dy)
+				if dist > 0 {
+					s.angleRad = math.Atan2(dy, dx)
+				}
+				if dist <= remaining {
+					s.x, s.y = path[i].X, path[i].Y
+					remaining -= dist
+					i++
+					continue
+				}
+				s.x += dx / dist * remaining
+				s.y += dy / dist * remaining
+				remaining = 0
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		if i < len(path) {
+			<-ticker.C
+		}
+	}
+	return nil
+}
+
+func (s *sprite) SetVelocity(velX, velY float64) error {
+	return s.changeKinematics(func(k *spritesmodels.Kinematics) { k.VelX, k.VelY = velX, velY })
+}
+
+func (s *sprite) SetAcceleration(accelX, accelY float64) error {
+	return s.changeKinematics(func(k *spritesmodels.Kinematics) { k.AccelX, k.AccelY = accelX, accelY })
+}
+
+func (s *sprite) SetAngularVelocity(degreesPerSecond float64) error {
+	return s.changeKinematics(func(k *spritesmodels.Kinematics) { k.AngularVelocity = degreesPerSecond })
+}
+
+func (s *sprite) SetDrag(drag float64) error {
+	return s.changeKinematics(func(k *spritesmodels.Kinematics) { k.Drag = drag })
+}
+
+func (s *sprite) SetMaxSpeed(maxSpeed float64) error {
+	return s.changeKinematics(func(k *spritesmodels.Kinematics) { k.MaxSpeed = maxSpeed })
+}
+
+func (s *sprite) SetEdgeBehavior(edge spritesmodels.EdgeBehavior) error {
+	return s.changeKinematics(func(k *spritesmodels.Kinematics) { k.Edge = edge })
+}
+
+func (s *sprite) SetKinematics(in spritesmodels.Kinematics) error {
+	return s.changeKinematics(func(k *spritesmodels.Kinematics) { *k = in })
+}
+
+func (s *sprite) changeKinematics(change func(k *spritesmodels.Kinematics)) error {
+	if s.getParent() != nil {
+		return fmt.Errorf("Sprite %d has a parent, so it can't move on its own", s.spriteID)
+	}
+	s.updateMutex.Lock()
+	defer s.updateMutex.Unlock()
+	var k spritesmodels.Kinematics
+	err := s.changeState(func() {
+		change(&s.kinematics)
+		k = s.kinematics
+		s.generation++
+	})
+	if err != nil {
+		return err
+	}
+	s.sim.SetSpriteKinematics(s, k)
+	return nil
+}
+
+// Takes the position the game moved the sprite to, or deletes it if it left the window with EdgeDestroy. Returns
+// false if the update no longer applies.
+func (s *sprite) applyKinematicsUpdate(u spritesmodels.KinematicsUpdate) bool {
+	if s.getParent() != nil {
+		return false
+	}
+	s.updateMutex.Lock()
+	defer s.updateMutex.Unlock()
+	applied := false
+	s.changeState(func() {
+		if u.Generation != s.generation || !s.kinematics.Moving() {
+			// Moved or stopped since the game sent this.
+			return
+		}
+		applied = true
+		if u.Destroyed {
+			return
+		}
+		s.x, s.y, s.angleRad = u.X, u.Y, u.AngleRad
+		s.kinematics.VelX, s.kinematics.VelY = u.VelX, u.VelY
+	})
+	if !applied {
+		return false
+	}
+	if u.Destroyed {
+		s.deleteLocked()
+		return true
+	}
+
+	// The game already has the new position, but the children don't.
+	s.updateClickBody()
+	s.updateChildren(false)
+	return true
 }
 
 func (s *sprite) SendMsg(toSpriteID int, msg any) {
@@ -282,20 +779,28 @@ func (s *sprite) AddMsg(msg any) {
 	s.receivedMsgs <- msg
 }
 
-func (s *sprite) minUpdate() {
-	if s.deleted {
-		log.Printf("Error: sprite %d is deleted but being updated\n", s.spriteID)
-		return
-	}
+func (s *sprite) PlaySound(name string) SoundHandle {
+	state := s.GetState()
+	return s.sim.PlaySoundAt(name, 1, state.X, state.Y)
+}
 
-	s.sim.SpriteUpdatePosAngle(s)
+// Sends the current state to the game. The caller holds updateMutex.
+func (s *sprite) send(full bool) {
+	s.updateClickBody()
+	if full {
+		s.sim.SpriteUpdateFull(s)
+	} else {
+		s.sim.SpriteUpdatePosAngle(s)
+	}
+	s.updateChildren(full)
 }
 
-func (s *sprite) fullUpdate() {
-	if s.deleted {
-		log.Printf("Error: sprite %d is deleted but being updated\n", s.spriteID)
+// Sends the state again without changing it, for when the parent changed.
+func (s *sprite) resend(full bool) {
+	s.updateMutex.Lock()
+	defer s.updateMutex.Unlock()
+	if s.isDeleted() {
 		return
 	}
-
-	s.sim.SpriteUpdateFull(s)
+	s.send(full)
 }
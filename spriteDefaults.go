@@ -2,16 +2,39 @@ package sprites
 
 import (
 	"bytes"
+	"embed"
 	"encoding/base64"
 	"image"
+	"io/fs"
 )
 
-// Created from ./cmd/convertimages/convertImages.go
+//go:embed defaultAssets
+var defaultAssetsDir embed.FS
+
+// The built in "turtle" and "arrow" costumes. Load them with sim.LoadAssets(sprites.DefaultAssets, "manifest.json", nil).
+var DefaultAssets fs.FS = func() fs.FS {
+	sub, err := fs.Sub(defaultAssetsDir, "defaultAssets")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}()
+
+// Deprecated: Load DefaultAssets instead.
 var (
-	TurtleImage string = "iVBORw0KGgoAAAANSUhEUgAAAEAAAABACAYAAACqaXHeAAAAAXNSR0IArs4c6QAAAARnQU1BAACxjwv8YQUAAAAJcEhZcwAADsMAAA7DAcdvqGQAABqpSURBVHhe7VsLmNRluf/mfr/szOxl9r7cQZB7GpjcTEmwBI8cLTyF1mNPPofK6mRxTujJ9FErTEujIgpNk0SPR9MS0AxFUWFh2WWBXdj77szOzM7O/T5zfr9vFh5JNBcofJ7jV9OwM//5/t/7vr/39/7edybx0fpo/f9eipHn877uXn/bmM7evgtCQZ86nU4Ls9kiairrvOq88lD99OnJNWvWJEcuPafrQ+GAG2+80XJgz1+3J9LpmdlcVr6mVquFRqsdcpe7vRMnTf5j7Zjan99667pehUJRkBeco/WhcMDChbNdPe1d+/JKTY3BaBJajVpkczmRSiZFLp8Xep027Cix7W0YO/bx2VNm/e/X1q3zjnz0rNeHwgHXL1/uOtx5eG86J2qrqmtESUmJQKQFUyESiYiA3y8ioWGhUimSDQ31b0yaPOmBqdMv3gHkRM8WEaqR5/O6lq1YoclmUkt8Pl+D2WRRGPQGoVFrRCaTETqtVljMZmGxGEUum1N7PAO1wYDvExaz0RX3Dx/4/bZtsZFtzmh9KBzw1a9+NavI5/NdXV1zhoPDNq1GI3Q6nVCr1NIBQhREhbtclFeUCb1ep/B6B63dXZ0XpjMJwz2337n3t48/Hi/uNPr1oXDAH/7wh8K3v7vuSCIeTfV0dS8MBIY06XRGlJfDYAOcoYcjdFqhUqpEVVUV0KEVfn9AC4dNHwwGzP+1/jvNW7c+FR7ZblRLOfJ83teVV16ZumbVZx+fM+djT6lVqnQOJBiJhBH7PAxWgawUolAoSE5wlTrFtAunCqVaZdy7b89Ne17f/eWtW7eeUTA/NA7gWrRokb9+fMM9NbXV/YlEXMQTCRGNxEQ0GscjKomRvIBskSkyZcpkkc3mzHvf2vu5ZDIyc2SbUa339dqjjz5qralwXqjKZSddPGdW7eevX5mbv/Cy+CuvvJIfueScrxUr/qXg6etcFAgE60xGo1IPQmRJ1Gm0yH89jM9JJBgMBmE0GvEJpejt7bOoVcrIlcs/sxNnG1VVeE8EPPTQj8c89ptfPvjSjh0vBAL+/2lrP/rM83/esamvo/W6e+9dX4FD/ENK6LRp04J1DQ33G/Rafw6iKBYDv8HdGjggGimigIar1Ur8uyCqq6qhG3Sqo0daL6qvLysb2eYDr9Ma8aMf3TVh2xNPPDzoDSxUKJRKjQY5iCvT6VRBo1Ul6+qrDlx51bJfXH31kt/X1MxLjHzsfReCpli16tp3OXzKlCmFO+644xRE7d6927Dhvh/8sru763pHiRP314L83IIOETDaaDTgqoJUi8lERhw82Czi8ejA3Nlzlzy06ZHW4i4fbL3LAevXr1ce2Pvq3c3Nrd9QqzQql8slSktdQqlUiEDAJ4aCAZFMxQsN9Q0Dly9ddt+scdN+s2jFiuGRj8u1det6bUuLUCJQjt6Ojuq+vn6lWql2KJT5hQMDXguBh6gJo14rrA57Kp/LviwU2t6yqvpj8+bNi6xatSr3i59tmPHY47+7FwFYbDKaVSaTGXmvFgajHmhQy+gzFeKxFM40JI4cbvWUuhyL//pG81k7QHvk0L7tfb39l/q8fgFCEkYTcg/eN5lN8qYDAx7R3z9QqK2tCSy94lN3zp23aNPBp57KqMeW1Xu8/oWBIf/i9qNHTPF4siGTzlSlUkk6UAkQmBWgHYPBhDspQGBZRDWDLXMxnc4Y1Ov0jWD4HTV1NW+Z7O6WttbGWR2dXb8p5EWDlhxg0AmHs0SUupzCZDKI4WGqQ5Xw+fyiuanJU+mqWLzjjX1n7gCcRLnhvrvnd3R33X9gX+PM/v4+xYTxY4TVZpK5x8t5Q7VKLzyeQXH8+DFR6rT7x4wZ82QoHHQHA/5ZqUymAteqYYzCZrMKKDsJWYoa5pFSocIeauR2AhBGaiEpuHMazgiHwwWPdyCTSqf8Fqu1ua5u/Jtg/2ubmponmM1GRYnDAefpRX1dLc5kFV1dnQJECQf4xNHWo57xE8YvfvqPO87cAT/84V1Tnn5y6xO9fZ4LRL4AA8xi3ry5QgvoMYlTqQzKUA5MrBKRcFQcP9aO11mS1PlCPquAhleYIFvLwEWo5SKJZiaBUpZMJJGjcZHJpkU2k2fpwt1Q2WG9Eg5QgdB0YHstVB8hngBihoLDhXAoUkgk08pEMjsih81wqEGUQQeYgUbuXeF2i2PHjgsIqIH58+cvuf+hTWfmgAceeED37NOPbTnadmylwWhR261WwehbrIieRgCqLD9K6PG8GAqERHd3j0SDGdEtoC5bcb0L0Ewk48If8IvwcEgqNxUsPPG+QqVAi6uTTqDSI5Pr9DqZz8PDYTg4JdKo80aTSRiQ87xnKBQRg74g4pEXFWWloqa6EjyYk5+12dE0qTTiwP4m8sLBz39xzRU33HDzwIhJH2idZOX+nrYFPT39S4Fetd1Wgs1tyDk9opaBofAAql4qkYbxQdHZeRyuywm73SLlqhNESaXW2noYaXFc8gQNttlswsZni1VqepKXEXvarGZAt1jTtagw0PfoAK142BFpK14XYnhoWIqd6upqMXniBFECyEejEaSQEA4Hzod9ZcOUzopoLFKwWi27V99ws2fEnA+8Tjrg4IEDNclUyki2j8eieKUgjYctIo/qk4xnRDAQFsfbOkUhlxduQM8EQwAKkUNE+vp7hQF63Wa1CD2izFxljseg4PjAAUV5WTnINCfbXBqfw/7JZAKvEQ0qSYoalDazqWiczzsoEjC6xGoSE8c1AG160dfbiyqUFiqQohr36ejoZBomps+cuQ2+GXVrfFIJXrvi6lw4HLKBsd0w3ADkKtiV2e12HDIFp8RFx7Eu6ZRqwNAJQgJZQaVlhae/H8brZdQZaX6OrB2DIznQ0MEZOTiNbI1GB6/HAHc6IS8S4AYj8jkCJ6USKcAfqQKyJFqIjBi0PzwFNBUR1dPfh/NkQJ5q0d3TByLsys+f/4k/r7h29cObNm36QJrkneukA3bt3uP9wo1f2nnBtMm7s4mEDzq8ur+vzxwJRZWMSn+fB/AfkpF3VyKSyElGMzg0BFgqRQkcpYTXVBKWQEtwWDK9EjxAQ5nflLJmkBkdRUSosC/hTodwVVZWomLogRDYAV7QQyeQPJPJtEQI91fDuf0owwMeL/uE7EUXX/TsZZ+6/OtLly4n/MlpJx4faJ32QnRWhv7ujkv2vb3nhpbmg8ujkXgJU8HpdIixIEatViX8KD3FGuwTdptdGGAcWZz5z46NzQsjxuaFi7281LQji1Emi/P6YRAm859o498sidyDyk+RV+M6oKWQlaWPJ+7t98gmacmST7bX19V/e3/z/rGZTNqRBlJJqlXVdQGtXv+S01nRvnbtWkDovVPjPT2Fgyie3rLFsf2v25/ZuWPnfHp/xszpUgxF0aZmkMdBIEIDo60WizSIpMWDU6CwFGoQLf7N10sccEY2KZ1GI7noHKaG1+sTjhIHnODASSGXcC9WANkBFlQiHML9wBNMCzPuFUJPEBwKgQxdyZ6e7lgun7MXRE5RyGNfoFGnMyThCL/JZHyzoqJ6u81iOnTl0qt8aSHaqTLlzUfW34OKYuODG777yJbNd0CIqFzlLskHCUA2jhY1j0jZrTahAfllEK008joBUnOAH3QgKCIiFApJR9hLLJIv9HAGjWMKqVRKwD8uK4vVapezQL5HqmfaRfFeAlI3BIe6qyuE3++TnSF5ogf57/cNyUpVVuYC6WqRPiakFFyBUs0W2gsSjcXjWZTivL3E7qmtrfv99NkzNguhbUf/IcfPf3eIUOayTfL7vMscTocyFo+CtBIwQi/FjQVogGKTOZ7OZCFg4nJYwTxlzS8gj2kkyVIJg4wGIwibMYZnESnmfyQcwTUokeAOLZzGRd7gIlrIJ5wNsP0lvPNADwFER0EkoQyXg5TdSEOLPI8WwXAglZxIqQKcXOosVTqdpZScNqBljnfAM8lmNhzc89Y+L5zw/g4gF7yy84XbAL3JiIiCxqdx01ioSFo8kFqrgYFJEUZakAipAFnmMjC6CGchx9tZKEgVHKNBE0TDqAZZEVgNNNAZJDumC1OKUaVcTsSTHH0JJZzJgs36T5RzfxMkdgQ8wP7AOqIrVECOFo7m+EyjKsAhRuGucAiLSSdq6+uZQuqe3p6GWDhcHQr6W154cefg+zrAadYu7eo8/i2H06kzQPExl9WIDksie3IFbogWGaSVlhGi3qdBXDSS4kmBB3UDS2NwKAh4osdHbrOiUCUyTTRwogJIISnSEcx9VgZCX6+D0mTFgdbQoSoQJSk4QA3EBEGeehhrNhtAwjqkHYzHfbR4pnajpuE8kc/kHaahzWJR9vb0jYMKsQdDXS++pwO2bNliemnnC3ejxE0FmyvAshKKMpoULDoNIgMY4z8GOIM34DUsc2Bg+ZoWhpE8eWCihfAkHxDG1BMcf1M+W6A66QTqBaKGJZO9Pp1mggiiAUwVEySyFkHgYEQFHcDqkMU+lONWXMeyyfShCINHZd/CHoPPWnjEhOvsULnhSFw55Pc5ClnltpNK8J2LFWDf63/5QsDnv9xms0uiZBTkQAKf0ANurMs6wg+ljl0aI072LkVOOmCUDTlYgmcdHMGy5oJkrqyqEhVVlaKqBi028lWPxobPhD3ZnfqApZBag6lUAsgTaXxf8go7SdwD/kBFLQgDxFYuDWWJJg23wOOEekXXCQexOvE5BbmcAnkTodQs7CBB2EZsgRCeZt1z223W5uamm8xmi47ihSsajcFIpSxFbERMyC+VbG60MqJUfPLfuJYkl4HDyNgsdVqghfWGspnsj1OBO9D5IaIKGMp9SaRkfqYEkUWkwPnyb6KKfxNlRAb7DqKLqMJW8rMUZSypJB3YX5TcIGY+U56rgQ5sLKtVn6dPWBy2Pnfd+NBpHXCgpUUTDkdsKBu4gZJyUz6TA2CqZHh6Oosb0ngeAOGRBhRwQx5UAffy9SwcQIZHFhYfqAy4CHfhAZP4XAF7FzORzH5CJ7BK0JWSI2A8U0S+j4iqgD46toB9ciP6gOikc6lHmIr8+DsfRIUfvLN3XyPHZ6G6uro7161b5+FdTrs0em2eG7GVZe1WYmMSFEdZ9Cp5gIdkWSNj5+EMiiMegNfmwPJoJ+RB+XkVrlUw+HjQSSTTDEopr8Hl0jgaLp2GvZliXCccwv0ZBBIvF8QP/pcG0zhCPyvvSf7gXnydKxSKCs+AX/T1DYjdr+0RXd3d6clTpvz8uvmXPYO3Ccp3r1kLJiXKSst39Pf3ZkkoZkA1A3anAWrU7HQqDyNgJHIrg7KYS2UlyVCA0CBUIFwLdMD4dCopc46VgIbzXBxxF3BtBrlLUqV4kQbj1DQwjgpAY2kIDaKYOoFAOoivsdkiIZEXiBJmFvwAZxsl6WUyBRFHc8Vqc/jwYdHYuL+AStM2Z87s76yct+SORSO/NzhtFdi+fXd20YL5Eb9v8DOIoJGNC1UcazdJhTnIiDB3s8gzdn0kF0abZVFCHificCSLEslcJkEyfTj9YUkLo+ZTOaYyKWHG/nk4BBeM7If6DrFFBPK6UGhY3pvlkVqB6tGgN6PhCkOZJpECWqlQtVp0nXDmMCrGgNcrjhw5Io51dBTS6czg+Injn124cMm37v/Zr56aOm9esUHBGgHKu9ejj/6y+vVXdm18+603L58wfryacAtAlCTQirL2MvfMFg43qcyKOVt0QFETcGgZi8dkbpoMYHhUC5Watyvm+UC/F5FEGoFPOAFylDiJHakFOE1KpdgRFisPiZiKk3OAQCAgU85mdYpj7cdxDzgf6UT+4eSYqIkDdfl8No1eIFnhrniprq7+JxOmWF5fu/bBlDTuHes9HQCjFDuff378ffd9/37k/hW1tdXKCOovZ4GszxCGMD0vbHarjDKRkUMZIjw55KAaoxYgX8RjnAqrZOXAoSSpsV0ma6cRZTrN5SqXhvoGvfJQJC6p8Fj7AXEKrAT2PuEAymCf359Gh/oKVCeOpKvLJDIFpUrdZbGa33bXVL82ZcLE1suWreiYOnVqUZ2dZr2nA7gghVXNjXtWb3/xTw+VOOzGclepbIMJzRMkZESkOaDkjI8EWVRzCYkIGspo0wDKaMKZAxMjnHBiSlzU9nggA4ggSmgHOkdyj6u0VDp0aGgI+Yyo4jpygA5Q7+zuY7kM3XjTTf9uUBp3dPa06Rne2WMvCK5Ys+aU7yneb510AI0NBoPKeHyo6ljrET0Q5B4ejlwRS8Y+09vdMyGTzyjHNtTJ2RyRwPkcjWVdJkcQ9ixPRAjJiFKVbM7RF99jntJA6nu2y+XlpVL8kBu4H2eA5BQORMrQdVLWxhHlcCgiP4fUlvvzyJxOR6JJ4Qv4cuPHjt244LJl37z11ltHPQ3ikg648851daFAYGXbkbaLcvnsXM+A1w7jIOLA30YTtIpKhMLDyDOFGDe2AVDVwbgM0OAX0AuAt0J+YUHYU5WhH5ekxO2Zk2YLlSPSBhWEQ0yPZ0A4XSXScUSJD22tb9APHiiRo2+jUSf8Q34YmpMpwPuzoyTrkwhJiGXuKtHZ2YEUS7fNm7fws/du+OnbtGW0S7V+/dcdf9mxfdOeN966ecDjnRaLJRwGdD61tXXaMWPHKpxOl4wUmT2MmhoFQ5P8qLvZnFABEgk5RIncwJaXByYMKJ8pUgoFEhkQAthz9heLcUhCJYdqgVyXs0MYxZ/GkT+CQIOs5wg7p0qsOvxcGOiiIrWV2CCZ0T9gb5/XZ8Zeu5pajjaP2DSqpdLrVWP6evq/l84UTJWVNYqx48bgpmbocPsIrBUw2IzXrDKaQzIyCUlmrM10DA3mgIQRKuYzGiR8lu/jv7L00TlsZLwoT7kcFZ5O1mx6IIAcp4Aid7BTpPSlIzkhovjq7e2TYzPyTFlZuSRek0kv+4Turh5ldVVtoLGp5bmiSaNbqutXrrCHwpGro5G4g8yvVBYkvFlfWer4bQ4hT6FCGPPf7NF5MBrOqAIxMK44JAmg5U0A/hxkxHANmZ0kyCgSumRzfj3Gn7nwb6YQeUOqPHIASNVis6BRMkrDPR4vruX90c3xWyFwh1aHRozqEvfu6epRwNnBQ0ePPTJi06iWas2XvhzPp1N+76AH8I85YLiCNdvhdAJ2YdmREWpZOIM31COy6JAQqTjQUOzvGXWrxSajQ2NpGMscmZ9OGQ7BKfJ5WMKYZY+5HI6EZPrQqfwihiMx4Eeyfk9Pr2xmlAo1HJmQZ+Doq7zCJTtCCG7JJ11d3fw67Xjb8e4zc8AzzzyTXbrsqkO5bHwAh/p4Kpm2EoqMFBVcRUU5/p0EExebDfYxRqNZsjdJ0OsbLIRD4Yzf78vjOiVnB5WVVThoOQjNKpmbYob70YGErQWcwhzmD57clRWyLBJxPp+vgAqRBRpyQBWaALa4EFJ43wZUVKKVJq+QXHVAHB3f092Le7nbWw63P1o0aXRLSmH+5OWb//HZY7mczhOJJC6GAywcbzEqlLy8Eb/hxZOEL7V7a+sREY1EsrW1ta/P/djcuyF6/gQyi3kHB409Pd3mcHhYCcmrYD6TSE+MvekU5jurRGDIX/B4vQVEPAx+CDtdrkZ7iX3jvI9f0un1eKb7/X5kmVKUljqFq6xUDjRYEkmo1Bl+X4BIydePaXhqf9OhHdKiUa5ThNAtt3xpwmu7Xt2uUmtqmbMsaxMmjpOMzf47ieZiOBhi3nFCWxg7btzTC5Ys/MZtt93eRVFz+/qvmOLh/MyujrZPgicm5vOZjwGmKjK87NAIXBjE8ZrJiB5dozys0xvfrKqpedldXu6tGz+jP+LrnfPktq0/Q+6P4z1r62pEdXWVPAtFEB1nQbqp0XwdONAkjnccTy1YsGD1gxu3PDlixqjWKQ645pqrLmhtOfhnBK7KYrWhlFGn6+UXIsxNktXAQD+6uGR23IRxzy65/AoIkO8cH/n4ycVfmdRCQBzrbzMOomwOQt6ifgp+40jVSK4oM5WJkupqZF00cfvttxeAisL99/+g/MXnnn824PfNpXSurqmCKrQDfdQRHIEVJbXNWiId8dJLLzOdmlevvmHFF2+5tb1499GtUxzw6AMPWJ979cUb9jc2fS+VzJRxXF2c63H4mZRcYNDrgjNnz9527XWfX7dy5crBkY+e9aIS/cuLz9z12qu7vonmSOmucIv6+lq8k4Pri20x29zyskqcRy327t3HQU168WWXrfvJQ7/+ER1Y3Gl065R5wOq1a8OLJs/evGTxJ39cXVXVC7tzqMt55HoerU/a7S5rXHDpgrWz5l7ytXNpPNfeN3ZObW5qug6prWS547CUozRWFaYPvx80kHxxAX8QwSnVuHHjWxZf+oknztR4rlMQcGJt3rxZHwp45xw82HRxf3+/ygaF5q5yD1qd1j8KYfL/7a+6znY9/utf1zzyu189PDAw8Cmj2aCsqqwUfHA4QsUpvxtA1Mkbfv+QaG5uZlk8vOzT19zyn7d//+Vz7oB/5np7167a3/5u04Ov7dq1vMThUKo0SjFp4gRhBvdQK7DipFHvDXoj1GBUNDY2Ahnq6OLFS758z4aHHzsb47nOqwM2btyo2fv6zgebDjTdhI5LTZHldpdDVJlRbjkDpIbgMLX4S7CjR9spp1NXLl++YeWq1XdOnz79rH4qz3XeHMCf47Xs2/WVA00H/ttiLbEUxZVAl+gQZaUuYecYDqTH3G9ra5e/SSotLetf/W9f+OnKf/3cg2VlZSwqZ71OIcF/1gLja/s6D910qLVlPTpAC5seTo9qamqBgEpIXbTfwxG0uz3i9Tfe5M9gcjV1tbuWffrqmyZOnXHfuTKe67RD0X/0mjapbg7U58ZcDt12aTm/50dt548pGPGE6EUf0N52DEzfw+lv/JL5l2ydf9GlN0czuaY1a9ZwFHXO1nlJgdXXLlu2b9/+bXmh4NBFdnocjMTjMdn3Z9PpvNlk9E6aMqlxzqy5vxgzefrOVatWnbOov3OdFwdsuHf9jOefe/6nA4ODF0JcqfmtDdttrUZXgEPa6+vrdlVV1WyecMGM1ptvvvmM/+8wH2SdFwdQ9XV0tLhbG/c3+IcDKpGFRLab0XlWZabO+Hir1WoN/e1PWT5aH62P1j9gCfF/F/Z2btrsCIkAAAAASUVORK5CYII="
-	ArrowImage  string = "iVBORw0KGgoAAAANSUhEUgAAACAAAAAgCAYAAABzenr0AAABbmlDQ1BpY2MAACiRdZE7SwNBFIU/EyXigyCKiFik8AUmEBTEUiKYJlokEXw1m81LSOKymyBiK9hYCBaija/Cf6CtYKsgCIogYmfvq5Gw3kkCCZLMMns/zsy5zJwBRyijZ61mP2RzeTMcDHgWl5Y9rnfa6KKXMUY03TLmIrNRGo6fR5pUffCpXo331R3t8YSlQ1Or8KRumHnhaeHQRt5QvCvco6e1uPCJsNeUAwrfKj1W5jfFqTJ/KTaj4RlwqJ6eVA3HalhPm1nhUeHBbKagV86jbtKRyC1EpPbLHMAiTJAAHmIUWCNDHp/UnGRW3+cv+eZZF48uf4NNTHGkSIvXK2pBuiakJkVPyJdhU+X+P08rOTFe7t4RgJZX2/4cAtc+FPds+/fUtotn4HyB61zVvy45TX2LvlfVBo/BvQ2XN1UtdgBXO9D3bGimVpKcMh3JJHxcQOcSdN9D20o5q8o6508Q3ZInuoPDIxiW/e7VP0c9aCv2snWBAAAACXBIWXMAAC4jAAAuIwF4pT92AAABJ0lEQVRYR2NgYGD4j4TXAdl0B8gOALHp7gh0B4D4G+gZDNgcABLbTC9H1KKlA2QHbaWXI2rwOGIbvRxRORgcUTYYHFGKxxE76RUd+Byxm16OKMETEnvp5YjiweCIAgKOYKJHaOTiccRRoBzzQDviMD0cALIjB0dI/CbXAaTGny4Oi36R6wBS9E3Akw5mkWIQOWo78VjeTo6BpOhpwmN5LykGkaO2Co/l08gxkBQ9RXgsn0+KQeSozcRj+XJyDCRFTxwey2neYo7AYznN24cBQMv/4XAAzdsAHkCLv+Gw/AhQnIWUOCRVrR1Qw3sclp8BivOQaiCp6l/hsPwyUFyEVMPIUY+tZ3QLaJAcOYaRowfdAfeBhmiQYxC5epAd8AxoiBG5BpGjDwDlF85InLe3TgAAAABJRU5ErkJggg=="
+	TurtleImage string = encodeDefaultAsset("turtle.png")
+	ArrowImage  string = encodeDefaultAsset("arrow.png")
 )
 
+func encodeDefaultAsset(path string) string {
+	fileData, err := fs.ReadFile(DefaultAssets, path)
+	if err != nil {
+		panic(err)
+	}
+	return base64.StdEncoding.EncodeToString(fileData)
+}
+
+// Deprecated: Use LoadSpriteFS or LoadAssets with an embed.FS instead of base64 strings.
 func DecodeCodedSprite(in string) image.Image {
 	fileData, err := base64.StdEncoding.DecodeString(in)
 	if err != nil {
@@ -0,0 +1,121 @@
+package sprites
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/gary23b/sprites/spritesmodels"
+)
+
+// Held while a parent is changed, so two SetParent calls can't make a loop between them.
+var hierarchyMutex sync.Mutex
+
+func (s *sprite) SetParent(parent Sprite) error {
+	hierarchyMutex.Lock()
+	defer hierarchyMutex.Unlock()
+
+	var newParent *sprite
+	if parent != nil {
+		var ok bool
+		newParent, ok = parent.(*sprite)
+		if !ok {
+			return fmt.Errorf("Sprite %d: parents must be made by the sim", s.spriteID)
+		}
+		// Walk up from the new parent to make sure we don't make a loop.
+		for p := newParent; p != nil; p = p.getParent() {
+			if p == s {
+				return fmt.Errorf("Sprite %d can't be a child of itself or its own children", s.spriteID)
+			}
+		}
+		if newParent.isDeleted() {
+			return fmt.Errorf("Failed to set the parent of sprite %d: %w", s.spriteID, ErrSpriteDeleted)
+		}
+	}
+
+	if newParent != nil && s.GetState().Kinematics.Moving() {
+		// Children move with their parent instead.
+		if err := s.SetKinematics(spritesmodels.Kinematics{}); err != nil {
+			return err
+		}
+	}
+
+	s.updateMutex.Lock()
+	defer s.updateMutex.Unlock()
+	if err := s.changeState(nil); err != nil {
+		return err
+	}
+
+	old := s.getParent()
+	if newParent != nil && newParent != old {
+		newParent.familyMutex.Lock()
+		newParent.children = append(newParent.children, s)
+		newParent.familyMutex.Unlock()
+
+		// The parent may have been deleted since it was checked. Its children are only deleted while they still
+		// point at it, which this one doesn't yet.
+		if newParent.isDeleted() {
+			newParent.familyMutex.Lock()
+			newParent.children = slices.DeleteFunc(newParent.children, func(c *sprite) bool { return c == s })
+			newParent.familyMutex.Unlock()
+			return fmt.Errorf("Failed to set the parent of sprite %d: %w", s.spriteID, ErrSpriteDeleted)
+		}
+	}
+
+	if old != nil && old != newParent {
+		old.removeChild(s)
+	}
+
+	s.familyMutex.Lock()
+	s.parent = newParent
+	s.familyMutex.Unlock()
+
+	s.send(true)
+	return nil
+}
+
+func (s *sprite) GetParent() Sprite {
+	if p := s.getParent(); p != nil {
+		return p
+	}
+	return nil
+}
+
+func (s *sprite) GetChildren() []Sprite {
+	children := s.getChildren()
+	ret := make([]Sprite, len(children))
+	for i := range children {
+		ret[i] = children[i]
+	}
+	return ret
+}
+
+func (s *sprite) getParent() *sprite {
+	s.familyMutex.Lock()
+	defer s.familyMutex.Unlock()
+	return s.parent
+}
+
+// A copy, so the caller can use it without holding the lock.
+func (s *sprite) getChildren() []*sprite {
+	s.familyMutex.Lock()
+	defer s.familyMutex.Unlock()
+	return slices.Clone(s.children)
+}
+
+func (s *sprite) removeChild(child *sprite) {
+	s.familyMutex.Lock()
+	s.children = slices.DeleteFunc(s.children, func(c *sprite) bool { return c == child })
+	s.familyMutex.Unlock()
+
+	child.familyMutex.Lock()
+	child.parent = nil
+	child.familyMutex.Unlock()
+}
+
+// Children only store values relative to this sprite, so their world values are sent again whenever this sprite changes.
+func (s *sprite) updateChildren(full bool) {
+	for _, child := range s.getChildren() {
+		child.resend(full)
+	}
+}
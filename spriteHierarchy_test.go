@@ -0,0 +1,65 @@
+package sprites
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetParentErrors(t *testing.T) {
+	sim := newTestSim()
+	a := sim.addTestSprite(1)
+	b := sim.addTestSprite(2)
+	c := sim.addTestSprite(3)
+
+	require.Error(t, a.SetParent(a))
+
+	// a <- b <- c, so a can't go under either of them.
+	require.NoError(t, b.SetParent(a))
+	require.NoError(t, c.SetParent(b))
+	require.Error(t, a.SetParent(b))
+	require.Error(t, a.SetParent(c))
+	require.Nil(t, a.GetParent())
+
+	// Setting the same parent again doesn't add the child twice.
+	require.NoError(t, c.SetParent(b))
+	require.Len(t, b.GetChildren(), 1)
+
+	// Moving to a deleted parent fails and leaves the sprite where it was.
+	d := sim.addTestSprite(4)
+	require.NoError(t, d.DeleteSprite())
+	require.ErrorIs(t, c.SetParent(d), ErrSpriteDeleted)
+	require.Equal(t, b, c.GetParent())
+	require.Empty(t, d.GetChildren())
+	require.False(t, c.GetState().Deleted)
+}
+
+// Run with -race.
+func TestSetParentConcurrently(t *testing.T) {
+	sim := newTestSim()
+	for i := range 100 {
+		a := sim.addTestSprite(3 * i)
+		b := sim.addTestSprite(3*i + 1)
+		runConcurrently(1,
+			func(int) { a.SetParent(b) },
+			func(int) { b.SetParent(a) },
+		)
+		require.False(t, a.GetParent() != nil && b.GetParent() != nil, "The two sprites are each other's parent")
+
+		// The child is either deleted with the parent or not moved under it.
+		parent := a
+		child := sim.addTestSprite(3*i + 2)
+		var err error
+		runConcurrently(1,
+			func(int) { err = child.SetParent(parent) },
+			func(int) { parent.DeleteSprite() },
+		)
+		if err == nil {
+			require.True(t, child.GetState().Deleted)
+		} else {
+			require.ErrorIs(t, err, ErrSpriteDeleted)
+			require.False(t, child.GetState().Deleted)
+			require.Nil(t, child.GetParent())
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package sprites
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/gary23b/sprites/spritestools"
+	"github.com/stretchr/testify/require"
+)
+
+// A sim without a game. The commands are left in the queue for the test to look at.
+func newTestSim() *simState {
+	return &simState{
+		cmdQueue:          spritestools.NewCommandQueue(spritestools.DefaultCommandQueueSize, spritesmodels.OverflowBlock, nil),
+		justPressedBroker: spritestools.NewBroker[*spritesmodels.UserInput](100),
+		posBroker:         spritestools.NewPositionBroker(),
+		idToSpriteMap:     make(map[int]Sprite),
+		nameToSpriteMap:   make(map[string]Sprite),
+		callbackSprites:   make(map[int]*sprite),
+	}
+}
+
+func (sim *simState) addTestSprite(spriteID int) *sprite {
+	name := fmt.Sprintf("sprite%d", spriteID)
+	sim.posBroker.AddSprite(spriteID)
+	ret := NewSprite(sim, name, spriteID)
+	sim.idToSpriteMapMutex.Lock()
+	sim.idToSpriteMap[spriteID] = ret
+	sim.nameToSpriteMap[name] = ret
+	sim.idToSpriteMapMutex.Unlock()
+	return ret
+}
+
+// Runs each f on its own go routine, loops times with i counting up, and waits for them all to finish.
+func runConcurrently(loops int, fs ...func(i int)) {
+	var wg sync.WaitGroup
+	for _, f := range fs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range loops {
+				f(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (sim *simState) takeCmds() []any {
+	return slices.Clone(sim.cmdQueue.TakeAll())
+}
+
+// The sprite ID a command is about, or -1 for commands that aren't about one sprite.
+func cmdSpriteID(cmd any) int {
+	switch v := cmd.(type) {
+	case spritesmodels.CmdSpriteUpdateMin:
+		return v.SpriteID
+	case spritesmodels.CmdSpriteUpdateFull:
+		return v.SpriteID
+	case spritesmodels.CmdSpriteDelete:
+		return v.SpriteID
+	case spritesmodels.CmdSpriteKinematics:
+		return v.SpriteID
+	case spritesmodels.CmdSpriteDrawOrder:
+		return v.SpriteID
+	case spritesmodels.CmdSpriteShader:
+		return v.SpriteID
+	}
+	return -1
+}
+
+// Fails if anything for the sprite was sent after it was deleted.
+func requireNothingAfterDelete(t *testing.T, cmds []any, spriteID int) {
+	t.Helper()
+	deleted := false
+	for _, cmd := range cmds {
+		if cmdSpriteID(cmd) != spriteID {
+			continue
+		}
+		require.False(t, deleted, "%T sent after the delete", cmd)
+		_, deleted = cmd.(spritesmodels.CmdSpriteDelete)
+	}
+	require.True(t, deleted)
+}
+
+func TestSpriteDeleteStopsUpdates(t *testing.T) {
+	sim := newTestSim()
+	s := sim.addTestSprite(1)
+
+	require.NoError(t, s.Pos(10, 20))
+	require.NoError(t, s.DeleteSprite())
+	require.True(t, s.GetState().Deleted)
+
+	updates := []error{
+		s.Pos(1, 2),
+		s.Angle(90),
+		s.Costume("c"),
+		s.Z(3),
+		s.Visible(true),
+		s.BringToFront(),
+		s.SetShader("shader", nil),
+		s.SetVelocity(1, 0),
+		s.All(spritesmodels.SpriteState{}),
+		s.MoveAlongPath([]spritesmodels.Point{{X: 5, Y: 5}}, 100),
+		s.ReplaceClickBody(spritestools.NewTouchCollisionBody()),
+		s.DeleteSprite(),
+	}
+	for _, err := range updates {
+		require.ErrorIs(t, err, ErrSpriteDeleted)
+	}
+
+	requireNothingAfterDelete(t, sim.takeCmds(), 1)
+	require.Equal(t, 10.0, s.GetState().X)
+	require.Equal(t, -1, sim.GetSpriteID("sprite1"))
+}
+
+func TestSpriteDeleteChildren(t *testing.T) {
+	sim := newTestSim()
+	parent := sim.addTestSprite(1)
+	child := sim.addTestSprite(2)
+	require.NoError(t, child.SetParent(parent))
+
+	// Deleting through the sim marks the sprites too.
+	sim.DeleteSprite(parent)
+	require.True(t, parent.GetState().Deleted)
+	require.True(t, child.GetState().Deleted)
+	require.ErrorIs(t, child.Pos(1, 1), ErrSpriteDeleted)
+	require.ErrorIs(t, parent.Pos(1, 1), ErrSpriteDeleted)
+
+	cmds := sim.takeCmds()
+	requireNothingAfterDelete(t, cmds, 1)
+	requireNothingAfterDelete(t, cmds, 2)
+}
+
+func TestSpriteDeleteAll(t *testing.T) {
+	sim := newTestSim()
+	sprites := []*sprite{sim.addTestSprite(1), sim.addTestSprite(2)}
+
+	sim.DeleteAllSprites()
+	for _, s := range sprites {
+		require.True(t, s.GetState().Deleted)
+		require.ErrorIs(t, s.Scale(2), ErrSpriteDeleted)
+	}
+	cmds := sim.takeCmds()
+	require.Len(t, cmds, 1)
+	require.IsType(t, spritesmodels.CmdSpritesDeleteAll{}, cmds[0])
+}
+
+func TestSpriteDeleteTwice(t *testing.T) {
+	sim := newTestSim()
+	s := sim.addTestSprite(1)
+
+	sim.DeleteSprite(s)
+	sim.DeleteSprite(s)
+	require.ErrorIs(t, s.DeleteSprite(), ErrSpriteDeleted)
+	cmds := sim.takeCmds()
+	require.Equal(t, []any{spritesmodels.CmdSpriteDelete{SpriteID: 1}}, cmds)
+
+	// The delete all already took care of it.
+	s = sim.addTestSprite(2)
+	sim.DeleteAllSprites()
+	sim.DeleteSprite(s)
+	cmds = sim.takeCmds()
+	require.Equal(t, []any{spritesmodels.CmdSpritesDeleteAll{}}, cmds)
+}
+
+// Feeds the updates to the sim the way the game does.
+func (sim *simState) applyTestKinematics(updates ...spritesmodels.KinematicsUpdate) {
+	ch := make(chan []spritesmodels.KinematicsUpdate, 1)
+	ch <- updates
+	close(ch)
+	sim.applyKinematicsUpdates(ch)
+}
+
+func TestSpriteKinematicsGeneration(t *testing.T) {
+	sim := newTestSim()
+	s := sim.addTestSprite(1)
+	require.NoError(t, s.SetVelocity(10, 0))
+	cmds := sim.takeCmds()
+	require.Equal(t, []any{spritesmodels.CmdSpriteKinematics{SpriteID: 1, Kinematics: spritesmodels.Kinematics{VelX: 10}, Generation: 1}}, cmds)
+
+	sim.applyTestKinematics(spritesmodels.KinematicsUpdate{SpriteID: 1, X: 5, VelX: 10, Generation: 1})
+	require.Equal(t, 5.0, s.GetState().X)
+	require.Equal(t, 5.0, sim.GetSpriteInfoByID(1).X)
+
+	// The game worked these out before it heard about the move, so they are dropped.
+	require.NoError(t, s.Pos(-100, 0))
+	sim.applyTestKinematics(
+		spritesmodels.KinematicsUpdate{SpriteID: 1, X: 6, VelX: 10, Generation: 1},
+		spritesmodels.KinematicsUpdate{SpriteID: 1, X: 600, VelX: 10, Generation: 1, Destroyed: true},
+	)
+	require.Equal(t, -100.0, s.GetState().X)
+	require.Equal(t, -100.0, sim.GetSpriteInfoByID(1).X)
+	require.False(t, s.GetState().Deleted)
+	cmds = sim.takeCmds()
+	require.Len(t, cmds, 1)
+	require.Equal(t, 2, cmds[0].(spritesmodels.CmdSpriteUpdateMin).Generation)
+
+	sim.applyTestKinematics(spritesmodels.KinematicsUpdate{SpriteID: 1, X: -90, VelX: 10, Generation: 2})
+	require.Equal(t, -90.0, s.GetState().X)
+
+	// Batches count as moving it by hand too.
+	require.NoError(t, s.Batch(func(b SpriteBuilder) { b.Pos(0, 0) }))
+	sim.applyTestKinematics(spritesmodels.KinematicsUpdate{SpriteID: 1, X: -80, VelX: 10, Generation: 2})
+	require.Equal(t, 0.0, s.GetState().X)
+	cmds = sim.takeCmds()
+	require.Equal(t, 3, cmds[len(cmds)-1].(spritesmodels.CmdSpriteBatch).Updates[0].Generation)
+
+	sim.applyTestKinematics(spritesmodels.KinematicsUpdate{SpriteID: 1, X: 600, VelX: 10, Generation: 3, Destroyed: true})
+	require.True(t, s.GetState().Deleted)
+	requireNothingAfterDelete(t, sim.takeCmds(), 1)
+}
+
+// Run with -race.
+func TestSpriteConcurrentUse(t *testing.T) {
+	sim := newTestSim()
+	parent := sim.addTestSprite(1)
+	child := sim.addTestSprite(2)
+	other := sim.addTestSprite(3)
+	require.NoError(t, child.SetParent(parent))
+
+	const loops = 200
+	runConcurrently(loops,
+		func(i int) { parent.Pos(float64(i), 0) },
+		func(i int) { parent.Angle(float64(i)) },
+		func(i int) { parent.Costume(fmt.Sprint(i)) },
+		func(i int) { parent.Effects(spritesmodels.SpriteEffects{Ghost: float64(i)}) },
+		func(i int) { child.Scale(float64(i)) },
+		func(i int) { parent.SetVelocity(float64(i), 0) },
+		func(i int) {
+			parent.applyKinematicsUpdate(spritesmodels.KinematicsUpdate{SpriteID: 1, X: float64(i), VelX: 1})
+		},
+		func(i int) {
+			_ = child.GetState()
+			_ = parent.WorldTransform()
+			_ = parent.GetClickBody().IsMouseClickInBody(0, 0)
+			_ = other.WhoIsNearMe(100)
+		},
+		func(i int) {
+			if i%20 == 0 {
+				other.SendMsg(1, i) // Few enough to fit in the buffer if nobody reads them.
+			}
+		},
+		func(i int) { parent.GetMsgs() },
+		func(i int) {
+			if i == loops/2 {
+				parent.DeleteSprite()
+			}
+		},
+	)
+
+	require.True(t, parent.GetState().Deleted)
+	require.True(t, child.GetState().Deleted)
+	cmds := sim.takeCmds()
+	requireNothingAfterDelete(t, cmds, 1)
+	requireNothingAfterDelete(t, cmds, 2)
+}
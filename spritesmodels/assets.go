@@ -0,0 +1,60 @@
+package spritesmodels
+
+// An asset manifest lists everything to load in one call. Paths are relative to the manifest file.
+//
+//	{
+//		"costumes":     [{"name": "turtle", "path": "turtle.png"}],
+//		"spriteSheets": [{"name": "walk", "path": "walk.png", "frameWidth": 32, "frameHeight": 32}],
+//		"sounds":       [{"name": "jab", "path": "jab.wav"}],
+//		"fonts":        [{"name": "title", "path": "title.ttf"}],
+//		"atlases":      [{"path": "atlas0.json"}]
+//	}
+type AssetManifest struct {
+	Costumes     []CostumeAsset     `json:"costumes"`
+	SpriteSheets []SpriteSheetAsset `json:"spriteSheets"`
+	Sounds       []SoundAsset       `json:"sounds"`
+	Fonts        []FontAsset        `json:"fonts"`
+	Atlases      []AtlasAsset       `json:"atlases"`
+}
+
+type CostumeAsset struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// A sprite sheet is cut into frames left to right, top to bottom.
+// Each frame becomes a costume named Name followed by the frame index, like "walk0", "walk1", ...
+type SpriteSheetAsset struct {
+	Name        string `json:"name"`
+	Path        string `json:"path"`
+	FrameWidth  int    `json:"frameWidth"`
+	FrameHeight int    `json:"frameHeight"`
+}
+
+type SoundAsset struct {
+	Name string `json:"name"`
+	Path string `json:"path"` // The extension picks the decoder: .wav, .ogg or .mp3
+}
+
+type FontAsset struct {
+	Name string `json:"name"`
+	Path string `json:"path"` // TrueType font file
+}
+
+// An atlas index made by cmd/packatlas. Every frame in it becomes a costume.
+type AtlasAsset struct {
+	Path string `json:"path"`
+}
+
+// Sent after each asset in a manifest is loaded.
+type AssetProgress struct {
+	Name   string // The asset that was just loaded
+	Loaded int    // Number of assets finished so far, including ones that failed
+	Total  int
+	Err    error // Set if this asset failed to load
+}
+
+// Total number of assets in the manifest.
+func (m *AssetManifest) Count() int {
+	return len(m.Costumes) + len(m.SpriteSheets) + len(m.Sounds) + len(m.Fonts) + len(m.Atlases)
+}
@@ -0,0 +1,15 @@
+package spritesmodels
+
+// The JSON index written next to each atlas page by cmd/packatlas.
+type AtlasIndex struct {
+	Image  string                `json:"image"` // The atlas page PNG, relative to the index file
+	Frames map[string]AtlasFrame `json:"frames"`
+}
+
+// Where a costume is inside the atlas page, in pixels from the top left.
+type AtlasFrame struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
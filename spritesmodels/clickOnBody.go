@@ -6,10 +6,16 @@ type ClickOnBody interface {
 
 	IsMouseClickInBody(x, y float64) bool
 	GetMousePosRelativeToOriginalSprite(x, y float64) (float64, float64)
+	Outlines() [][]Point // Each shape as a closed polygon in world coordinates. Used by the debug overlay.
+	// Where the ray first enters the body within maxDistance. The direction doesn't need to be normalized.
+	// SpriteID and SpriteType are left for the caller to fill in.
+	Raycast(x, y, dirX, dirY, maxDistance float64) (hit RayHit, ok bool)
+	BoundingRadius() float64 // How far from the sprite's position any part of the body can reach, in world units.
 
 	Clone() ClickOnBody
 
 	// Should only be used by the sim.
 	Pos(x, y float64)
 	Angle(RadAngle float64)
+	Transform(in SpriteTransform) // Includes the position and angle
 }
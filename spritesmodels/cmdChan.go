@@ -2,6 +2,7 @@ package spritesmodels
 
 import (
 	"image"
+	"time"
 )
 
 type CmdAddNewSprite struct {
@@ -9,10 +10,11 @@ type CmdAddNewSprite struct {
 }
 
 type CmdSpriteUpdateMin struct {
-	SpriteID int
-	X        float64
-	Y        float64
-	AngleRad float64
+	SpriteID   int
+	X          float64
+	Y          float64
+	AngleRad   float64
+	Generation int // See KinematicsUpdate
 }
 
 type CmdSpriteUpdateFull struct {
@@ -20,12 +22,44 @@ type CmdSpriteUpdateFull struct {
 	CostumeName string
 	X           float64
 	Y           float64
-	Z           int
+	Z           float64
 	Angle       float64
 	Visible     bool
 	XScale      float64
 	YScale      float64
 	Opacity     float64
+	Effects     SpriteEffects
+	FlipX       bool
+	FlipY       bool
+	PivotX      float64
+	PivotY      float64
+	SkewX       float64 // radians
+	SkewY       float64 // radians
+	Generation  int     // See KinematicsUpdate
+}
+
+// Applied all in the same frame, so no sprite is drawn half changed. The sprites are added before any are updated.
+type CmdSpriteBatch struct {
+	Adds    []CmdAddNewSprite
+	Updates []CmdSpriteUpdateFull
+}
+
+type CmdSpriteKinematics struct {
+	SpriteID   int
+	Kinematics Kinematics
+	Generation int // See KinematicsUpdate
+}
+
+type CmdSpriteDrawOrder struct {
+	SpriteID int
+	Action   DrawOrderAction
+	Steps    int // OrderForward and OrderBackward
+}
+
+// Sprites with this Z are drawn from the top of the screen down, so lower sprites cover the ones behind them.
+type CmdSetYSort struct {
+	Z       float64
+	Enabled bool
 }
 
 type CmdSpriteDelete struct {
@@ -41,14 +75,112 @@ type CmdAddCostume struct {
 	Img         image.Image
 }
 
+// A pre-packed atlas page. Each frame becomes a costume that shares the page's texture.
+type CmdAddAtlas struct {
+	Img    image.Image
+	Frames map[string]image.Rectangle
+}
+
+// Reserves a costume name with a placeholder image while the real image is loading.
+type CmdAddPlaceholderCostume struct {
+	CostumeName string
+}
+
+// Compiles Kage source and registers it under ShaderName. The result is sent on ErrChan.
+type CmdAddShader struct {
+	ShaderName string
+	Source     []byte
+	ErrChan    chan error
+}
+
+// An empty ShaderName removes the sprite's shader.
+type CmdSpriteShader struct {
+	SpriteID   int
+	ShaderName string
+	Uniforms   ShaderUniforms
+}
+
+// Replaces the whole post-processing chain. The passes run in order. An empty list turns post-processing off.
+type CmdSetPostProcessing struct {
+	Passes []ShaderPass
+}
+
+// The default mixer buses. Any other name creates a new bus.
+const (
+	BusMusic = "music"
+	BusSfx   = "sfx"
+	BusUI    = "ui"
+)
+
+// Deprecated: The game decodes this on its own loop, which stalls it for large files. Decode the sound first with
+// game.DecodeSound and send CmdAddSoundData instead.
 type CmdAddSound struct {
-	Path      string
+	Path      string // The extension picks the decoder: .wav, .ogg or .mp3
+	SoundName string
+	Data      []byte // The encoded file contents. If nil, Path is read from disk.
+}
+
+// Already decoded 16 bit stereo PCM at the game sample rate.
+type CmdAddSoundData struct {
 	SoundName string
+	Data      []byte
 }
 
 type CmdPlaySound struct {
 	SoundName string
 	Volume    float64 // between 0 and 1.
+	HandleID  int     // Used by CmdSoundControl to refer to this playing sound.
+	Loop      bool
+	Bus       string // Defaults to BusSfx
+
+	Positional bool    // Pan and attenuate based on X and Y
+	X, Y       float64 // Cartesian position of the sound source
+}
+
+type SoundAction int
+
+const (
+	SoundStop SoundAction = iota
+	SoundPause
+	SoundResume
+	SoundSetVolume
+	SoundFade
+	SoundSeek
+	SoundLoop
+)
+
+type CmdSoundControl struct {
+	HandleID int
+	Action   SoundAction
+	Volume   float64       // SoundSetVolume and SoundFade
+	Duration time.Duration // SoundFade length or SoundSeek position
+	Loop     bool          // SoundLoop
+}
+
+type CmdPlayMusic struct {
+	Path   string
+	Loop   bool
+	Volume float64 // between 0 and 1.
+}
+
+type CmdStopMusic struct{}
+
+type CmdSetBusVolume struct {
+	Bus    string // An empty name sets the master volume.
+	Volume float64
+}
+
+type CmdMuteBus struct {
+	Bus  string // An empty name mutes the master, so everything.
+	Mute bool
+}
+
+type CmdShowDebugHUD struct {
+	Show bool
+}
+
+type CmdShowDebugOverlay struct {
+	Show bool
 }
 
 type CmdGetScreenshot struct {
@@ -0,0 +1,23 @@
+package spritesmodels
+
+import "time"
+
+// What happens when the sim sends commands faster than the game takes them.
+type OverflowPolicy int
+
+const (
+	OverflowBlock      OverflowPolicy = iota // Wait for room. Nothing is lost.
+	OverflowDropOldest                       // Make room by dropping the oldest position update that a newer one replaces. Otherwise wait for room.
+	OverflowCoalesce                         // A position update replaces the one still queued for the same sprite.
+)
+
+type CommandQueueStats struct {
+	Depth         int // Commands waiting right now
+	MaxDepth      int // The most that have ever been waiting at once
+	Capacity      int
+	Pushed        int // Every command the sim has sent
+	Coalesced     int // Position updates that replaced a queued one
+	Dropped       int // Position updates dropped to make room
+	BlockedPushes int // Sends that had to wait for room
+	BlockedTime   time.Duration
+}
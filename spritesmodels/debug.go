@@ -0,0 +1,23 @@
+package spritesmodels
+
+type Point struct {
+	X, Y float64
+}
+
+type Rect struct {
+	MinX, MinY float64
+	MaxX, MaxY float64
+}
+
+// What the sim shows in the debug overlay for one sprite. Coordinates are Cartesian world coordinates.
+type DebugSpriteInfo struct {
+	SpriteID      int
+	UniqueName    string
+	ClickOutlines [][]Point // Closed polygons
+}
+
+// Collected by the sim each frame while the debug overlay is showing.
+type DebugOverlayInfo struct {
+	Sprites   []DebugSpriteInfo
+	GridCells []Rect // The PositionBroker cells that have sprites in them
+}
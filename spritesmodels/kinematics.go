@@ -0,0 +1,40 @@
+package spritesmodels
+
+// What happens when a moving sprite's position leaves the window.
+type EdgeBehavior int
+
+const (
+	EdgeNone    EdgeBehavior = iota // Keep going
+	EdgeBounce                      // Reflect the velocity back into the window
+	EdgeWrap                        // Come back in on the opposite side
+	EdgeClamp                       // Stop at the edge
+	EdgeDestroy                     // Delete the sprite
+)
+
+// Motion the game integrates every tick, so a sprite can move without its go routine calling Pos.
+// Distances are in pixels and times are in seconds.
+type Kinematics struct {
+	VelX, VelY      float64
+	AccelX, AccelY  float64
+	AngularVelocity float64 // Degrees per second
+	Drag            float64 // The fraction of the velocity lost each second, from 0 to 1.
+	MaxSpeed        float64 // 0 for no limit
+	Edge            EdgeBehavior
+}
+
+// True if the sprite will ever move on its own.
+func (k Kinematics) Moving() bool {
+	return k.VelX != 0 || k.VelY != 0 || k.AccelX != 0 || k.AccelY != 0 || k.AngularVelocity != 0
+}
+
+// Sent back from the game after a tick moved a sprite. Coordinates are Cartesian world coordinates.
+type KinematicsUpdate struct {
+	SpriteID   int
+	X, Y       float64
+	AngleRad   float64
+	VelX, VelY float64
+	Destroyed  bool // Left the window with EdgeDestroy
+	// The sim counts up each time the sprite is moved or its kinematics are changed by hand. An update from an older
+	// generation was worked out before that, so it is dropped.
+	Generation int
+}
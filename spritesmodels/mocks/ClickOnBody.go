@@ -124,6 +124,51 @@ func (_c *ClickOnBody_Angle_Call) RunAndReturn(run func(float64)) *ClickOnBody_A
 	return _c
 }
 
+// BoundingRadius provides a mock function with given fields:
+func (_m *ClickOnBody) BoundingRadius() float64 {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for BoundingRadius")
+	}
+
+	var r0 float64
+	if rf, ok := ret.Get(0).(func() float64); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(float64)
+	}
+
+	return r0
+}
+
+// ClickOnBody_BoundingRadius_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'BoundingRadius'
+type ClickOnBody_BoundingRadius_Call struct {
+	*mock.Call
+}
+
+// BoundingRadius is a helper method to define mock.On call
+func (_e *ClickOnBody_Expecter) BoundingRadius() *ClickOnBody_BoundingRadius_Call {
+	return &ClickOnBody_BoundingRadius_Call{Call: _e.mock.On("BoundingRadius")}
+}
+
+func (_c *ClickOnBody_BoundingRadius_Call) Run(run func()) *ClickOnBody_BoundingRadius_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *ClickOnBody_BoundingRadius_Call) Return(_a0 float64) *ClickOnBody_BoundingRadius_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *ClickOnBody_BoundingRadius_Call) RunAndReturn(run func() float64) *ClickOnBody_BoundingRadius_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Clone provides a mock function with given fields:
 func (_m *ClickOnBody) Clone() spritesmodels.ClickOnBody {
 	ret := _m.Called()
@@ -275,6 +320,53 @@ func (_c *ClickOnBody_IsMouseClickInBody_Call) RunAndReturn(run func(float64, fl
 	return _c
 }
 
+// Outlines provides a mock function with given fields:
+func (_m *ClickOnBody) Outlines() [][]spritesmodels.Point {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Outlines")
+	}
+
+	var r0 [][]spritesmodels.Point
+	if rf, ok := ret.Get(0).(func() [][]spritesmodels.Point); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([][]spritesmodels.Point)
+		}
+	}
+
+	return r0
+}
+
+// ClickOnBody_Outlines_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Outlines'
+type ClickOnBody_Outlines_Call struct {
+	*mock.Call
+}
+
+// Outlines is a helper method to define mock.On call
+func (_e *ClickOnBody_Expecter) Outlines() *ClickOnBody_Outlines_Call {
+	return &ClickOnBody_Outlines_Call{Call: _e.mock.On("Outlines")}
+}
+
+func (_c *ClickOnBody_Outlines_Call) Run(run func()) *ClickOnBody_Outlines_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *ClickOnBody_Outlines_Call) Return(_a0 [][]spritesmodels.Point) *ClickOnBody_Outlines_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *ClickOnBody_Outlines_Call) RunAndReturn(run func() [][]spritesmodels.Point) *ClickOnBody_Outlines_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Pos provides a mock function with given fields: x, y
 func (_m *ClickOnBody) Pos(x float64, y float64) {
 	_m.Called(x, y)
@@ -309,6 +401,99 @@ func (_c *ClickOnBody_Pos_Call) RunAndReturn(run func(float64, float64)) *ClickO
 	return _c
 }
 
+// Raycast provides a mock function with given fields: x, y, dirX, dirY, maxDistance
+func (_m *ClickOnBody) Raycast(x float64, y float64, dirX float64, dirY float64, maxDistance float64) (spritesmodels.RayHit, bool) {
+	ret := _m.Called(x, y, dirX, dirY, maxDistance)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Raycast")
+	}
+
+	var r0 spritesmodels.RayHit
+	var r1 bool
+	if rf, ok := ret.Get(0).(func(float64, float64, float64, float64, float64) (spritesmodels.RayHit, bool)); ok {
+		return rf(x, y, dirX, dirY, maxDistance)
+	}
+	if rf, ok := ret.Get(0).(func(float64, float64, float64, float64, float64) spritesmodels.RayHit); ok {
+		r0 = rf(x, y, dirX, dirY, maxDistance)
+	} else {
+		r0 = ret.Get(0).(spritesmodels.RayHit)
+	}
+
+	if rf, ok := ret.Get(1).(func(float64, float64, float64, float64, float64) bool); ok {
+		r1 = rf(x, y, dirX, dirY, maxDistance)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	return r0, r1
+}
+
+// ClickOnBody_Raycast_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Raycast'
+type ClickOnBody_Raycast_Call struct {
+	*mock.Call
+}
+
+// Raycast is a helper method to define mock.On call
+//   - x float64
+//   - y float64
+//   - dirX float64
+//   - dirY float64
+//   - maxDistance float64
+func (_e *ClickOnBody_Expecter) Raycast(x interface{}, y interface{}, dirX interface{}, dirY interface{}, maxDistance interface{}) *ClickOnBody_Raycast_Call {
+	return &ClickOnBody_Raycast_Call{Call: _e.mock.On("Raycast", x, y, dirX, dirY, maxDistance)}
+}
+
+func (_c *ClickOnBody_Raycast_Call) Run(run func(x float64, y float64, dirX float64, dirY float64, maxDistance float64)) *ClickOnBody_Raycast_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(float64), args[1].(float64), args[2].(float64), args[3].(float64), args[4].(float64))
+	})
+	return _c
+}
+
+func (_c *ClickOnBody_Raycast_Call) Return(hit spritesmodels.RayHit, ok bool) *ClickOnBody_Raycast_Call {
+	_c.Call.Return(hit, ok)
+	return _c
+}
+
+func (_c *ClickOnBody_Raycast_Call) RunAndReturn(run func(float64, float64, float64, float64, float64) (spritesmodels.RayHit, bool)) *ClickOnBody_Raycast_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Transform provides a mock function with given fields: in
+func (_m *ClickOnBody) Transform(in spritesmodels.SpriteTransform) {
+	_m.Called(in)
+}
+
+// ClickOnBody_Transform_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Transform'
+type ClickOnBody_Transform_Call struct {
+	*mock.Call
+}
+
+// Transform is a helper method to define mock.On call
+//   - in spritesmodels.SpriteTransform
+func (_e *ClickOnBody_Expecter) Transform(in interface{}) *ClickOnBody_Transform_Call {
+	return &ClickOnBody_Transform_Call{Call: _e.mock.On("Transform", in)}
+}
+
+func (_c *ClickOnBody_Transform_Call) Run(run func(in spritesmodels.SpriteTransform)) *ClickOnBody_Transform_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(spritesmodels.SpriteTransform))
+	})
+	return _c
+}
+
+func (_c *ClickOnBody_Transform_Call) Return() *ClickOnBody_Transform_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *ClickOnBody_Transform_Call) RunAndReturn(run func(spritesmodels.SpriteTransform)) *ClickOnBody_Transform_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewClickOnBody creates a new instance of ClickOnBody. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewClickOnBody(t interface {
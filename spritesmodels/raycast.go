@@ -0,0 +1,11 @@
+package spritesmodels
+
+// Where a ray first enters a sprite's click body. Coordinates are Cartesian world coordinates.
+type RayHit struct {
+	SpriteID   int
+	SpriteType int
+	X, Y       float64 // The hit point
+	NormalX    float64 // Unit normal of the body's surface at the hit point, facing back along the ray.
+	NormalY    float64
+	Distance   float64 // From the start of the ray. 0 if the ray starts inside the body.
+}
@@ -0,0 +1,14 @@
+package spritesmodels
+
+import "time"
+
+// Numbers from the most recent update and frame.
+type RenderStats struct {
+	FPS, TPS          float64
+	SpritesDrawn      int
+	SpritesCulled     int // Visible sprites that were skipped because they are completely off the screen
+	DrawCalls         int // Draws issued by the game. Ebiten batches these further before they reach the GPU.
+	CommandQueueDepth int // Commands waiting at the start of the last update
+	UpdateTime        time.Duration
+	DrawTime          time.Duration
+}
@@ -0,0 +1,19 @@
+package spritesmodels
+
+// Shaders that are always registered. Missing uniforms use sensible defaults.
+const (
+	ShaderBloom      = "bloom"      // Uniforms: Threshold, Intensity, Radius
+	ShaderCRT        = "crt"        // Uniforms: Curvature, Scanlines
+	ShaderVignette   = "vignette"   // Uniforms: Strength
+	ShaderColorGrade = "colorgrade" // Uniforms: Brightness, Contrast, Saturation, Tint ([]float32 of length 3)
+)
+
+// Kage uniform values by variable name. Values are float32, float64, or int, or slices of them for vectors and matrices.
+// The uniform Time, in seconds, is filled in automatically when it is not given.
+type ShaderUniforms map[string]any
+
+// One step of the full-screen post-processing chain.
+type ShaderPass struct {
+	ShaderName string
+	Uniforms   ShaderUniforms
+}
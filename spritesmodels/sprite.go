@@ -1,21 +1,78 @@
 package spritesmodels
 
+import "image/color"
+
+type BlendMode int
+
+const (
+	BlendNormal BlendMode = iota
+	BlendAdditive
+	BlendMultiply
+	BlendScreen
+)
+
+// Color effects applied when drawing a sprite. The zero value draws the costume unchanged.
+type SpriteEffects struct {
+	Tint       color.RGBA // Multiplied with the costume colors. Ignored when A is 0.
+	Brightness float64    // -100 is black, 100 is white
+	Grayscale  float64    // 0 to 100, with 100 having no color at all
+	HueShift   float64    // Degrees around the color wheel
+	Ghost      float64    // 0 to 100, with 100 invisible. This stacks with Opacity.
+	Pixelate   float64    // Size of the blocks in costume pixels. 0 and 1 are off.
+	Mosaic     int        // The costume is repeated this many times across and down. 0 and 1 are off.
+	Blend      BlendMode
+}
+
+// Changes the order of sprites that have the same Z. Z always wins.
+type DrawOrderAction int
+
+const (
+	OrderFront DrawOrderAction = iota
+	OrderBack
+	OrderForward  // Swap places with the next sprite up, Steps times
+	OrderBackward // Swap places with the next sprite down, Steps times
+)
+
 type SpriteState struct {
 	SpriteID       int
 	SpriteType     int
 	UniqueName     string
 	CostumeName    string
 	X, Y           float64
-	Z              int // Effectively the layer index. 0 through 9 with 9 being the top.
+	Z              float64 // Higher is drawn on top. Any value is allowed.
 	AngleDegrees   float64
 	Visible        bool
 	ScaleX, ScaleY float64
 	Opacity        float64
+	Effects        SpriteEffects
+	FlipX, FlipY   bool       // Mirrors the costume around the pivot
+	PivotX, PivotY float64    // Cartesian offset from the costume center. This point sits at (X, Y) and is rotated around.
+	SkewX, SkewY   float64    // Degrees
+	Kinematics     Kinematics // The velocity is kept up to date as the game moves the sprite.
 	Deleted        bool
 }
 
+// Where and how a costume is placed in the world. Local coordinates are Cartesian, unscaled, and relative to the
+// costume center. A local point is moved by the pivot, flipped, skewed, scaled, rotated, and then moved to (X, Y).
+type SpriteTransform struct {
+	X, Y           float64
+	AngleRad       float64
+	ScaleX, ScaleY float64
+	FlipX, FlipY   bool
+	PivotX, PivotY float64
+	SkewX, SkewY   float64 // radians
+}
+
 type NearMeInfo struct {
 	SpriteID   int
 	SpriteType int
 	X, Y       float64
+	Distance   float64 // Filled in by the exact queries. For ray queries it is the distance along the ray.
+}
+
+// Narrows down a neighbor query. The zero value matches every sprite.
+type NearMeFilter struct {
+	ExcludeIDs  []int // Usually the sprite that is asking
+	SpriteTypes []int // Only sprites with one of these types. Empty means any type.
+	Limit       int   // At most this many results, nearest first. 0 is no limit.
 }
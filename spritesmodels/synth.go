@@ -0,0 +1,37 @@
+package spritesmodels
+
+import "time"
+
+type Waveform int
+
+const (
+	WaveSine Waveform = iota
+	WaveSquare
+	WaveSaw
+	WaveTriangle
+	WaveNoise
+)
+
+// Attack, decay, sustain, release. The release happens at the end of each note's duration.
+type Envelope struct {
+	Attack  time.Duration
+	Decay   time.Duration
+	Sustain float64 // Level held after the decay, between 0 and 1. Zero means 1 if Decay is also zero.
+	Release time.Duration
+}
+
+type SynthNote struct {
+	Note         string        // Scientific pitch like "C4", "F#3" or "Bb5". "R" is a rest. Ignored if Frequency is set.
+	Frequency    float64       // Hz
+	EndFrequency float64       // If set, the pitch sweeps linearly to this frequency over the note.
+	Duration     time.Duration // Includes the envelope release.
+	Volume       float64       // between 0 and 1. Zero means 1.
+}
+
+// Describes a sound made from oscillators instead of an asset file. The notes are played one after another.
+type SynthSpec struct {
+	Waveform Waveform
+	Envelope Envelope
+	Notes    []SynthNote
+	Volume   float64 // between 0 and 1. Zero means 1.
+}
@@ -0,0 +1,79 @@
+package spritestools
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"io/fs"
+	"path"
+
+	"github.com/gary23b/sprites/spritesmodels"
+)
+
+// Reads a JSON asset manifest from fsys. The asset paths are made relative to fsys instead of the manifest.
+func LoadAssetManifest(fsys fs.FS, manifestPath string) (spritesmodels.AssetManifest, error) {
+	var ret spritesmodels.AssetManifest
+
+	data, err := fs.ReadFile(fsys, manifestPath)
+	if err != nil {
+		return ret, fmt.Errorf("Failed to read asset manifest: %s, %w", manifestPath, err)
+	}
+	if err := json.Unmarshal(data, &ret); err != nil {
+		return ret, fmt.Errorf("Failed to decode asset manifest: %s, %w", manifestPath, err)
+	}
+
+	dir := path.Dir(manifestPath)
+	for i := range ret.Costumes {
+		ret.Costumes[i].Path = path.Join(dir, ret.Costumes[i].Path)
+	}
+	for i := range ret.SpriteSheets {
+		ret.SpriteSheets[i].Path = path.Join(dir, ret.SpriteSheets[i].Path)
+	}
+	for i := range ret.Sounds {
+		ret.Sounds[i].Path = path.Join(dir, ret.Sounds[i].Path)
+	}
+	for i := range ret.Fonts {
+		ret.Fonts[i].Path = path.Join(dir, ret.Fonts[i].Path)
+	}
+	for i := range ret.Atlases {
+		ret.Atlases[i].Path = path.Join(dir, ret.Atlases[i].Path)
+	}
+
+	return ret, nil
+}
+
+type subImager interface {
+	SubImage(r image.Rectangle) image.Image
+}
+
+// Cuts a sprite sheet into frames left to right, top to bottom. Partial frames at the edges are skipped.
+func SplitSpriteSheet(img image.Image, frameWidth, frameHeight int) ([]image.Image, error) {
+	if frameWidth <= 0 || frameHeight <= 0 {
+		return nil, fmt.Errorf("Frame size must be positive: %dx%d", frameWidth, frameHeight)
+	}
+	sub, ok := img.(subImager)
+	if !ok {
+		return nil, fmt.Errorf("Image type %T can't be split", img)
+	}
+
+	b := img.Bounds()
+	ret := []image.Image{}
+	for y := b.Min.Y; y+frameHeight <= b.Max.Y; y += frameHeight {
+		for x := b.Min.X; x+frameWidth <= b.Max.X; x += frameWidth {
+			ret = append(ret, sub.SubImage(image.Rect(x, y, x+frameWidth, y+frameHeight)))
+		}
+	}
+	return ret, nil
+}
+
+// The costume names of the frames SplitSpriteSheet cuts from a sheet image of the given size, in order.
+func SpriteSheetFrameNames(sheet spritesmodels.SpriteSheetAsset, width, height int) []string {
+	if sheet.FrameWidth <= 0 || sheet.FrameHeight <= 0 {
+		return nil
+	}
+	ret := make([]string, (width/sheet.FrameWidth)*(height/sheet.FrameHeight))
+	for i := range ret {
+		ret[i] = fmt.Sprintf("%s%d", sheet.Name, i)
+	}
+	return ret
+}
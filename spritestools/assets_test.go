@@ -0,0 +1,62 @@
+package spritestools
+
+import (
+	"image"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadAssetManifest(t *testing.T) {
+	fsys := fstest.MapFS{
+		"assets/manifest.json": {Data: []byte(`{
+			"costumes": [{"name": "turtle", "path": "turtle.png"}],
+			"spriteSheets": [{"name": "walk", "path": "sheets/walk.png", "frameWidth": 32, "frameHeight": 16}],
+			"sounds": [{"name": "jab", "path": "jab.wav"}],
+			"fonts": [{"name": "title", "path": "../fonts/title.ttf"}],
+			"atlases": [{"path": "atlas0.json"}]
+		}`)},
+		"bad.json": {Data: []byte(`{"costumes": 5}`)},
+	}
+
+	m, err := LoadAssetManifest(fsys, "assets/manifest.json")
+	require.NoError(t, err)
+	require.Equal(t, 5, m.Count())
+	require.Equal(t, "assets/turtle.png", m.Costumes[0].Path)
+	require.Equal(t, "assets/sheets/walk.png", m.SpriteSheets[0].Path)
+	require.Equal(t, 32, m.SpriteSheets[0].FrameWidth)
+	require.Equal(t, 16, m.SpriteSheets[0].FrameHeight)
+	require.Equal(t, "assets/jab.wav", m.Sounds[0].Path)
+	require.Equal(t, "fonts/title.ttf", m.Fonts[0].Path)
+	require.Equal(t, "assets/atlas0.json", m.Atlases[0].Path)
+
+	_, err = LoadAssetManifest(fsys, "bad.json")
+	require.Error(t, err)
+	_, err = LoadAssetManifest(fsys, "missing.json")
+	require.Error(t, err)
+}
+
+func TestSplitSpriteSheet(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 40))
+
+	frames, err := SplitSpriteSheet(img, 30, 20)
+	require.NoError(t, err)
+	require.Len(t, frames, 6)
+	require.Equal(t, image.Rect(0, 0, 30, 20), frames[0].Bounds())
+	require.Equal(t, image.Rect(60, 0, 90, 20), frames[2].Bounds())
+	require.Equal(t, image.Rect(30, 20, 60, 40), frames[4].Bounds())
+
+	_, err = SplitSpriteSheet(img, 0, 20)
+	require.Error(t, err)
+
+	sheet := spritesmodels.SpriteSheetAsset{Name: "walk", FrameWidth: 30, FrameHeight: 20}
+	names := SpriteSheetFrameNames(sheet, 100, 40)
+	require.Len(t, names, len(frames))
+	require.Equal(t, "walk0", names[0])
+	require.Equal(t, "walk5", names[5])
+	require.Empty(t, SpriteSheetFrameNames(sheet, 20, 40))
+	sheet.FrameHeight = 0
+	require.Empty(t, SpriteSheetFrameNames(sheet, 100, 40))
+}
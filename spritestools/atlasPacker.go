@@ -0,0 +1,206 @@
+package spritestools
+
+import (
+	"cmp"
+	"fmt"
+	"image"
+	"image/draw"
+	"slices"
+
+	"github.com/gary23b/sprites/spritesmodels"
+)
+
+type atlasShelf struct {
+	y, height int
+	x         int // Where the next image on this shelf goes
+}
+
+type atlasPage struct {
+	shelves []atlasShelf
+	bottom  int               // The bottom of the last shelf
+	free    []image.Rectangle // Space given back with Free, including the padding
+}
+
+// Packs rectangles into fixed size pages using shelves. Each image is surrounded by padding
+// so that filtering at the edges doesn't pick up its neighbors.
+type AtlasPacker struct {
+	pageWidth  int
+	pageHeight int
+	padding    int
+	pages      []*atlasPage
+}
+
+func NewAtlasPacker(pageWidth, pageHeight, padding int) *AtlasPacker {
+	ret := &AtlasPacker{
+		pageWidth:  pageWidth,
+		pageHeight: pageHeight,
+		padding:    padding,
+	}
+	return ret
+}
+
+func (p *AtlasPacker) PageCount() int {
+	return len(p.pages)
+}
+
+// Finds room for a w by h image. rect is where the image goes, not including the padding.
+// ok is false if the image is too big to ever fit on a page.
+func (p *AtlasPacker) Pack(w, h int) (page int, rect image.Rectangle, ok bool) {
+	pw := w + 2*p.padding
+	ph := h + 2*p.padding
+	if w <= 0 || h <= 0 || pw > p.pageWidth || ph > p.pageHeight {
+		return 0, image.Rectangle{}, false
+	}
+
+	if pageIndex, r, ok := p.packInFreeSpace(pw, ph); ok {
+		return pageIndex, r, true
+	}
+	for pageIndex, page := range p.pages {
+		if r, ok := p.packOnPage(page, pw, ph); ok {
+			return pageIndex, r, true
+		}
+	}
+
+	p.pages = append(p.pages, &atlasPage{})
+	pageIndex := len(p.pages) - 1
+	r, _ := p.packOnPage(p.pages[pageIndex], pw, ph)
+	return pageIndex, r, true
+}
+
+// Uses the smallest freed space the image fits in. What is left over stays free.
+func (p *AtlasPacker) packInFreeSpace(pw, ph int) (int, image.Rectangle, bool) {
+	bestPage, best := -1, -1
+	bestArea := 0
+	for pageIndex, page := range p.pages {
+		for i, f := range page.free {
+			area := f.Dx() * f.Dy()
+			if pw <= f.Dx() && ph <= f.Dy() && (best < 0 || area < bestArea) {
+				bestPage, best, bestArea = pageIndex, i, area
+			}
+		}
+	}
+	if best < 0 {
+		return 0, image.Rectangle{}, false
+	}
+
+	page := p.pages[bestPage]
+	f := page.free[best]
+	page.free = slices.Delete(page.free, best, best+1)
+	right := image.Rect(f.Min.X+pw, f.Min.Y, f.Max.X, f.Min.Y+ph)
+	below := image.Rect(f.Min.X, f.Min.Y+ph, f.Max.X, f.Max.Y)
+	for _, r := range []image.Rectangle{right, below} {
+		if !r.Empty() {
+			page.free = append(page.free, r)
+		}
+	}
+
+	x := f.Min.X + p.padding
+	y := f.Min.Y + p.padding
+	return bestPage, image.Rect(x, y, x+pw-2*p.padding, y+ph-2*p.padding), true
+}
+
+// Gives back the space of an image that Pack put at rect on page, so that later images can use it.
+func (p *AtlasPacker) Free(page int, rect image.Rectangle) {
+	if page < 0 || page >= len(p.pages) || rect.Empty() {
+		return
+	}
+	p.pages[page].free = append(p.pages[page].free, rect.Inset(-p.padding))
+}
+
+func (p *AtlasPacker) packOnPage(page *atlasPage, pw, ph int) (image.Rectangle, bool) {
+	// Use the existing shelf that wastes the least height.
+	best := -1
+	for i := range page.shelves {
+		s := &page.shelves[i]
+		if ph <= s.height && s.x+pw <= p.pageWidth {
+			if best < 0 || s.height < page.shelves[best].height {
+				best = i
+			}
+		}
+	}
+
+	if best < 0 {
+		if page.bottom+ph > p.pageHeight {
+			return image.Rectangle{}, false
+		}
+		page.shelves = append(page.shelves, atlasShelf{y: page.bottom, height: ph})
+		page.bottom += ph
+		best = len(page.shelves) - 1
+	}
+
+	s := &page.shelves[best]
+	x := s.x + p.padding
+	y := s.y + p.padding
+	s.x += pw
+	return image.Rect(x, y, x+pw-2*p.padding, y+ph-2*p.padding), true
+}
+
+// Packs named images into as few pages as possible. This is what cmd/packatlas uses.
+// The Image field of each returned index is left empty for the caller to fill in.
+func PackAtlas(images map[string]image.Image, pageWidth, pageHeight int) ([]*image.RGBA, []spritesmodels.AtlasIndex, error) {
+	names := make([]string, 0, len(images))
+	for name := range images {
+		names = append(names, name)
+	}
+	// Tallest first packs shelves tighter. Sorting by name too keeps the output the same every run.
+	slices.SortFunc(names, func(a, b string) int {
+		if c := cmp.Compare(images[b].Bounds().Dy(), images[a].Bounds().Dy()); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+
+	packer := NewAtlasPacker(pageWidth, pageHeight, 1)
+	pages := []*image.RGBA{}
+	indexes := []spritesmodels.AtlasIndex{}
+
+	for _, name := range names {
+		img := images[name]
+		b := img.Bounds()
+		pageIndex, r, ok := packer.Pack(b.Dx(), b.Dy())
+		if !ok {
+			return nil, nil, fmt.Errorf("%s is %dx%d and doesn't fit on a %dx%d page", name, b.Dx(), b.Dy(), pageWidth, pageHeight)
+		}
+
+		for len(pages) <= pageIndex {
+			pages = append(pages, image.NewRGBA(image.Rect(0, 0, pageWidth, pageHeight)))
+			indexes = append(indexes, spritesmodels.AtlasIndex{Frames: map[string]spritesmodels.AtlasFrame{}})
+		}
+
+		draw.Draw(pages[pageIndex], r, img, b.Min, draw.Src)
+		indexes[pageIndex].Frames[name] = spritesmodels.AtlasFrame{X: r.Min.X, Y: r.Min.Y, W: r.Dx(), H: r.Dy()}
+	}
+
+	return pages, indexes, nil
+}
+
+// The location of each named frame within an atlas page with the given bounds.
+func AtlasRects(bounds image.Rectangle, index spritesmodels.AtlasIndex) (map[string]image.Rectangle, error) {
+	ret := make(map[string]image.Rectangle, len(index.Frames))
+	for name, f := range index.Frames {
+		r := image.Rect(f.X, f.Y, f.X+f.W, f.Y+f.H).Add(bounds.Min)
+		if r.Empty() || !r.In(bounds) {
+			return nil, fmt.Errorf("Atlas frame %s is outside of the image", name)
+		}
+		ret[name] = r
+	}
+	return ret, nil
+}
+
+// Cuts an atlas page back into its named costumes.
+func SplitAtlas(img image.Image, index spritesmodels.AtlasIndex) (map[string]image.Image, error) {
+	sub, ok := img.(subImager)
+	if !ok {
+		return nil, fmt.Errorf("Image type %T can't be split", img)
+	}
+	rects, err := AtlasRects(img.Bounds(), index)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]image.Image, len(rects))
+	for name, r := range rects {
+		ret[name] = sub.SubImage(r)
+	}
+	return ret, nil
+}
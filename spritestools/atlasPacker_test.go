@@ -0,0 +1,118 @@
+package spritestools
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAtlasPacker(t *testing.T) {
+	p := NewAtlasPacker(100, 100, 1)
+
+	page, r, ok := p.Pack(20, 30)
+	require.True(t, ok)
+	require.Equal(t, 0, page)
+	require.Equal(t, image.Rect(1, 1, 21, 31), r)
+
+	// Same shelf
+	page, r, ok = p.Pack(20, 10)
+	require.True(t, ok)
+	require.Equal(t, 0, page)
+	require.Equal(t, image.Rect(23, 1, 43, 11), r)
+
+	// Too wide for the first shelf, so a new shelf starts below it.
+	page, r, ok = p.Pack(70, 10)
+	require.True(t, ok)
+	require.Equal(t, 0, page)
+	require.Equal(t, image.Rect(1, 33, 71, 43), r)
+
+	// Too tall for the rest of the page, so a new page is added.
+	page, r, ok = p.Pack(50, 70)
+	require.True(t, ok)
+	require.Equal(t, 1, page)
+	require.Equal(t, image.Rect(1, 1, 51, 71), r)
+	require.Equal(t, 2, p.PageCount())
+
+	// Never fits
+	_, _, ok = p.Pack(99, 10)
+	require.False(t, ok)
+	_, _, ok = p.Pack(0, 10)
+	require.False(t, ok)
+}
+
+func TestAtlasPacker_free(t *testing.T) {
+	p := NewAtlasPacker(100, 100, 1)
+	_, a, _ := p.Pack(30, 30)
+	_, b, _ := p.Pack(20, 20)
+	require.Equal(t, image.Rect(1, 1, 31, 31), a)
+	require.Equal(t, image.Rect(33, 1, 53, 21), b)
+
+	// A smaller image goes in the freed space, and the rest of that space can still be used.
+	p.Free(0, a)
+	page, r, ok := p.Pack(20, 30)
+	require.True(t, ok)
+	require.Equal(t, 0, page)
+	require.Equal(t, image.Rect(1, 1, 21, 31), r)
+	_, r, _ = p.Pack(8, 30)
+	require.Equal(t, image.Rect(23, 1, 31, 31), r)
+
+	// Too big for any freed space, so it goes on the shelf.
+	p.Free(0, b)
+	_, r, _ = p.Pack(25, 25)
+	require.Equal(t, image.Rect(55, 1, 80, 26), r)
+
+	// The same size again gets the same space back, so replacing an image over and over doesn't fill the page.
+	for range 100 {
+		p.Free(0, r)
+		_, r, ok = p.Pack(25, 25)
+		require.True(t, ok)
+		require.Equal(t, image.Rect(55, 1, 80, 26), r)
+	}
+	require.Equal(t, 1, p.PageCount())
+}
+
+func TestPackAtlas(t *testing.T) {
+	images := map[string]image.Image{}
+	colors := map[string]color.RGBA{
+		"red":   {0xFF, 0, 0, 0xFF},
+		"green": {0, 0xFF, 0, 0xFF},
+		"blue":  {0, 0, 0xFF, 0xFF},
+	}
+	for name, c := range colors {
+		img := image.NewRGBA(image.Rect(0, 0, 30, 20))
+		for y := 0; y < 20; y++ {
+			for x := 0; x < 30; x++ {
+				img.SetRGBA(x, y, c)
+			}
+		}
+		images[name] = img
+	}
+
+	pages, indexes, err := PackAtlas(images, 40, 50)
+	require.NoError(t, err)
+	require.Len(t, pages, 2)
+	require.Len(t, indexes, 2)
+	require.Len(t, indexes[0].Frames, 2)
+	require.Len(t, indexes[1].Frames, 1)
+
+	for i := range pages {
+		split, err := SplitAtlas(pages[i], indexes[i])
+		require.NoError(t, err)
+		for name, img := range split {
+			require.Equal(t, 30, img.Bounds().Dx())
+			require.Equal(t, 20, img.Bounds().Dy())
+			r, g, b, a := img.At(img.Bounds().Min.X+5, img.Bounds().Min.Y+5).RGBA()
+			er, eg, eb, ea := colors[name].RGBA()
+			require.Equal(t, []uint32{er, eg, eb, ea}, []uint32{r, g, b, a}, name)
+		}
+	}
+
+	_, _, err = PackAtlas(images, 16, 16)
+	require.Error(t, err)
+
+	_, err = SplitAtlas(pages[0], spritesmodels.AtlasIndex{Frames: map[string]spritesmodels.AtlasFrame{"x": {X: 60, Y: 60, W: 10, H: 10}}})
+	require.Error(t, err)
+}
@@ -3,6 +3,8 @@ package spritestools
 import (
 	"log"
 	"math"
+	"slices"
+	"sync"
 
 	"github.com/gary23b/sprites/spritesmodels"
 )
@@ -17,24 +19,29 @@ type rectangle struct {
 	y1, y2 float64
 }
 
+// Safe to use from many go routines. The sprite moves it while the sim and callbacks test against it.
 type ClickOnBody struct {
+	mutex          sync.RWMutex
 	radiusOfCaring float64
 	circles        []circle
 	rectangles     []rectangle
 
-	x, y     float64
-	radAngle float64
+	transform spritesmodels.SpriteTransform
 }
 
 var _ spritesmodels.ClickOnBody = &ClickOnBody{}
 
 func NewTouchCollisionBody() *ClickOnBody {
-	ret := &ClickOnBody{}
+	ret := &ClickOnBody{
+		transform: NewSpriteTransform(),
+	}
 
 	return ret
 }
 
 func (s *ClickOnBody) AddCircleBody(x, y, radius float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 	newC := circle{
 		x:      x,
 		y:      y,
@@ -57,6 +64,8 @@ func (s *ClickOnBody) AddRectangleBody(x1, x2, y1, y2 float64) {
 		log.Println("x2 must be greater than x1")
 		return
 	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
 	newR := rectangle{
 		x1: x1,
@@ -73,18 +82,33 @@ func (s *ClickOnBody) AddRectangleBody(x1, x2, y1, y2 float64) {
 }
 
 func (s *ClickOnBody) Pos(x, y float64) {
-	s.x = x
-	s.y = y
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.transform.X = x
+	s.transform.Y = y
 }
 
 func (s *ClickOnBody) Angle(radAngle float64) {
-	s.radAngle = radAngle
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.transform.AngleRad = radAngle
+}
+
+func (s *ClickOnBody) Transform(in spritesmodels.SpriteTransform) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.transform = in
 }
 
 func (s *ClickOnBody) IsMouseClickInBody(x, y float64) bool {
-	// get the mouse position relative to the origin centered body
-	x -= s.x
-	y -= s.y
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	// get the mouse position in the coordinates of the original, untransformed sprite
+	x, y, ok := WorldToLocal(s.transform, x, y)
+	if !ok {
+		return false
+	}
 
 	// Check if I care
 	distanceSquared := x*x + y*y
@@ -92,14 +116,6 @@ func (s *ClickOnBody) IsMouseClickInBody(x, y float64) bool {
 		return false
 	}
 
-	// Rotate the mouse around the origin based on the body angle
-	if s.radAngle != 0 {
-		sin, cos := math.Sincos(-s.radAngle)
-		x0 := x
-		x = cos*x0 - sin*y
-		y = sin*x0 + cos*y
-	}
-
 	// Loop through the circles
 	for i := range s.circles {
 		c := s.circles[i]
@@ -122,20 +138,178 @@ func (s *ClickOnBody) IsMouseClickInBody(x, y float64) bool {
 	return false
 }
 
+// Undoes the position, angle, scale, flip, skew, and pivot of the sprite.
 func (s *ClickOnBody) GetMousePosRelativeToOriginalSprite(x, y float64) (float64, float64) {
-	// get the mouse position relative to the origin centered body
-	x -= s.x
-	y -= s.y
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	x, y, _ = WorldToLocal(s.transform, x, y)
+	return x, y
+}
 
-	// Rotate the mouse around the origin based on the body angle
-	if s.radAngle != 0 {
-		sin, cos := math.Sincos(-s.radAngle)
-		x0 := x
-		x = cos*x0 - sin*y
-		y = sin*x0 + cos*y
+const outlineCircleSegments = 24
+
+func (s *ClickOnBody) Outlines() [][]spritesmodels.Point {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	ret := make([][]spritesmodels.Point, 0, len(s.circles)+len(s.rectangles))
+
+	for _, c := range s.circles {
+		outline := make([]spritesmodels.Point, outlineCircleSegments)
+		for i := range outline {
+			sin, cos := math.Sincos(2 * math.Pi * float64(i) / outlineCircleSegments)
+			x, y := LocalToWorld(s.transform, c.x+cos*c.radius, c.y+sin*c.radius)
+			outline[i] = spritesmodels.Point{X: x, Y: y}
+		}
+		ret = append(ret, outline)
 	}
 
-	return x, y
+	for _, r := range s.rectangles {
+		corners := [4][2]float64{{r.x1, r.y1}, {r.x2, r.y1}, {r.x2, r.y2}, {r.x1, r.y2}}
+		outline := make([]spritesmodels.Point, len(corners))
+		for i, corner := range corners {
+			x, y := LocalToWorld(s.transform, corner[0], corner[1])
+			outline[i] = spritesmodels.Point{X: x, Y: y}
+		}
+		ret = append(ret, outline)
+	}
+
+	return ret
+}
+
+// How far from the sprite's position any part of the body can reach, in world units.
+func (s *ClickOnBody) BoundingRadius() float64 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	r := s.radiusOfCaring
+	ret := 0.0
+	// The transformed square around the radius of caring holds the whole transformed body.
+	for _, corner := range [4][2]float64{{-r, -r}, {r, -r}, {r, r}, {-r, r}} {
+		x, y := LocalToWorld(s.transform, corner[0], corner[1])
+		ret = max(ret, math.Hypot(x-s.transform.X, y-s.transform.Y))
+	}
+	return ret
+}
+
+// Where the ray first enters the body within maxDistance. A ray that starts inside the body hits at distance 0.
+func (s *ClickOnBody) Raycast(x, y, dirX, dirY, maxDistance float64) (spritesmodels.RayHit, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	length := math.Hypot(dirX, dirY)
+	if length == 0 || len(s.circles)+len(s.rectangles) == 0 {
+		return spritesmodels.RayHit{}, false
+	}
+	dirX, dirY = dirX/length, dirY/length
+
+	// The transform is affine, so a point's distance along the ray is the same in local coordinates.
+	ox, oy, ok := WorldToLocal(s.transform, x, y)
+	if !ok {
+		return spritesmodels.RayHit{}, false
+	}
+	px, py, _ := WorldToLocal(s.transform, x+dirX, y+dirY)
+	dx, dy := px-ox, py-oy
+
+	found := false
+	best := maxDistance
+	var normalX, normalY float64
+	for _, c := range s.circles {
+		if t, nx, ny, ok := rayCircle(ox, oy, dx, dy, c); ok && t <= best {
+			best, normalX, normalY, found = t, nx, ny, true
+		}
+	}
+	for _, r := range s.rectangles {
+		if t, nx, ny, ok := rayRectangle(ox, oy, dx, dy, r); ok && t <= best {
+			best, normalX, normalY, found = t, nx, ny, true
+		}
+	}
+	if !found {
+		return spritesmodels.RayHit{}, false
+	}
+
+	ret := spritesmodels.RayHit{
+		X:        x + dirX*best,
+		Y:        y + dirY*best,
+		NormalX:  -dirX,
+		NormalY:  -dirY,
+		Distance: best,
+	}
+	if normalX == 0 && normalY == 0 {
+		// Started inside, so there is no surface. Face back along the ray.
+		return ret, true
+	}
+
+	// Normals don't transform like points. The surface tangent does, and the normal is perpendicular to it.
+	hitX, hitY := ox+dx*best, oy+dy*best
+	ax, ay := LocalToWorld(s.transform, hitX, hitY)
+	bx, by := LocalToWorld(s.transform, hitX-normalY, hitY+normalX)
+	nx, ny := -(by - ay), bx-ax
+	if nx*dirX+ny*dirY > 0 {
+		nx, ny = -nx, -ny
+	}
+	if n := math.Hypot(nx, ny); n > 0 {
+		ret.NormalX, ret.NormalY = nx/n, ny/n
+	}
+	return ret, true
+}
+
+// The ray distance and local normal where the ray enters the circle. The normal is zero if the ray starts inside.
+func rayCircle(ox, oy, dx, dy float64, c circle) (t, normalX, normalY float64, ok bool) {
+	fx, fy := ox-c.x, oy-c.y
+	a := dx*dx + dy*dy
+	b := fx*dx + fy*dy
+	cc := fx*fx + fy*fy - c.radius*c.radius
+	if cc < 0 {
+		return 0, 0, 0, true
+	}
+	disc := b*b - a*cc
+	if disc < 0 || a == 0 {
+		return 0, 0, 0, false
+	}
+	t = (-b - math.Sqrt(disc)) / a
+	if t < 0 {
+		return 0, 0, 0, false
+	}
+	return t, (fx + dx*t) / c.radius, (fy + dy*t) / c.radius, true
+}
+
+// Same as rayCircle, using the slab method.
+func rayRectangle(ox, oy, dx, dy float64, r rectangle) (t, normalX, normalY float64, ok bool) {
+	if ox >= r.x1 && ox <= r.x2 && oy >= r.y1 && oy <= r.y2 {
+		return 0, 0, 0, true
+	}
+
+	near, far := math.Inf(-1), math.Inf(1)
+	for axis := 0; axis < 2; axis++ {
+		o, d, lo, hi := ox, dx, r.x1, r.x2
+		if axis == 1 {
+			o, d, lo, hi = oy, dy, r.y1, r.y2
+		}
+		if d == 0 {
+			if o < lo || o > hi {
+				return 0, 0, 0, false
+			}
+			continue
+		}
+		t1, t2 := (lo-o)/d, (hi-o)/d
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		if t1 > near {
+			near = t1
+			normalX, normalY = 0, 0
+			if axis == 0 {
+				normalX = -math.Copysign(1, d)
+			} else {
+				normalY = -math.Copysign(1, d)
+			}
+		}
+		far = min(far, t2)
+	}
+	if near > far || near < 0 {
+		return 0, 0, 0, false
+	}
+	return near, normalX, normalY, true
 }
 
 /*
@@ -177,8 +351,12 @@ func overlapCircleRectangle(c *circle, r *rectangle) bool {
 */
 
 func (s *ClickOnBody) Clone() spritesmodels.ClickOnBody {
-	ret := *s
-	copy(ret.circles, s.circles)
-	copy(ret.rectangles, s.rectangles)
-	return &ret
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return &ClickOnBody{
+		radiusOfCaring: s.radiusOfCaring,
+		circles:        slices.Clone(s.circles),
+		rectangles:     slices.Clone(s.rectangles),
+		transform:      s.transform,
+	}
 }
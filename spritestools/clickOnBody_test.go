@@ -49,3 +49,109 @@ func TestClickOnBody(t *testing.T) {
 	require.False(t, b2.IsMouseClickInBody(10+1, 10-9.9))
 	require.True(t, b2.IsMouseClickInBody(10+1, 10-10.1))
 }
+
+func TestClickOnBody_transform(t *testing.T) {
+	b := NewTouchCollisionBody()
+	b.AddRectangleBody(0, 20, -5, 5)
+
+	tr := NewSpriteTransform()
+	tr.X, tr.Y = 100, 100
+	tr.FlipX = true
+	b.Transform(tr)
+	require.False(t, b.IsMouseClickInBody(110, 100))
+	require.True(t, b.IsMouseClickInBody(90, 100))
+
+	// Doubling the scale doubles the body
+	tr.ScaleX, tr.ScaleY = 2, 2
+	b.Transform(tr)
+	require.True(t, b.IsMouseClickInBody(65, 100))
+	require.False(t, b.IsMouseClickInBody(55, 100))
+
+	// With the pivot at the right end of the rectangle, the whole thing sits to the left of the position
+	tr = NewSpriteTransform()
+	tr.PivotX = 20
+	b.Transform(tr)
+	require.True(t, b.IsMouseClickInBody(-10, 0))
+	require.False(t, b.IsMouseClickInBody(10, 0))
+
+	x, y := b.GetMousePosRelativeToOriginalSprite(-10, 2)
+	require.InDelta(t, 10, x, 1e-9)
+	require.InDelta(t, 2, y, 1e-9)
+
+	// Squashed flat, nothing can be clicked
+	tr.ScaleY = 0
+	b.Transform(tr)
+	require.False(t, b.IsMouseClickInBody(-10, 0))
+}
+
+func TestClickOnBody_Outlines(t *testing.T) {
+	b := NewTouchCollisionBody()
+	b.AddCircleBody(0, 0, 10)
+	b.AddRectangleBody(0, 20, -5, 5)
+	b.Pos(100, 0)
+
+	outlines := b.Outlines()
+	require.Len(t, outlines, 2)
+
+	for _, p := range outlines[0] {
+		require.InDelta(t, 10, math.Hypot(p.X-100, p.Y), 1e-9)
+	}
+
+	require.Len(t, outlines[1], 4)
+	require.InDelta(t, 100, outlines[1][0].X, 1e-9)
+	require.InDelta(t, -5, outlines[1][0].Y, 1e-9)
+	require.InDelta(t, 120, outlines[1][2].X, 1e-9)
+	require.InDelta(t, 5, outlines[1][2].Y, 1e-9)
+}
+
+func TestClickOnBody_Raycast(t *testing.T) {
+	b := NewTouchCollisionBody()
+	_, ok := b.Raycast(0, 0, 1, 0, 100)
+	require.False(t, ok)
+
+	b.AddCircleBody(0, 0, 5)
+	b.AddRectangleBody(10, 20, -2, 2)
+	b.Pos(50, 0)
+
+	hit, ok := b.Raycast(0, 0, 2, 0, 100)
+	require.True(t, ok)
+	require.InDelta(t, 45, hit.Distance, 1e-9)
+	require.InDelta(t, 45, hit.X, 1e-9)
+	require.InDelta(t, -1, hit.NormalX, 1e-9)
+	require.InDelta(t, 0, hit.NormalY, 1e-9)
+
+	// Too short
+	_, ok = b.Raycast(0, 0, 1, 0, 40)
+	require.False(t, ok)
+
+	// Coming from the other side hits the rectangle first
+	hit, ok = b.Raycast(100, 1, -1, 0, 100)
+	require.True(t, ok)
+	require.InDelta(t, 30, hit.Distance, 1e-9)
+	require.InDelta(t, 1, hit.NormalX, 1e-9)
+
+	// Turned to face up, the rectangle is above the circle. Hitting it from below gives a normal pointing down.
+	b.Angle(math.Pi / 2)
+	hit, ok = b.Raycast(50, 7, 0, 1, 100)
+	require.True(t, ok)
+	require.InDelta(t, 3, hit.Distance, 1e-9)
+	require.InDelta(t, 0, hit.NormalX, 1e-9)
+	require.InDelta(t, -1, hit.NormalY, 1e-9)
+
+	// Starting inside
+	hit, ok = b.Raycast(50, 0, 1, 0, 100)
+	require.True(t, ok)
+	require.Equal(t, 0.0, hit.Distance)
+	require.InDelta(t, -1, hit.NormalX, 1e-9)
+
+	// Scaled up the circle reaches further.
+	b.Angle(0)
+	tr := NewSpriteTransform()
+	tr.X, tr.ScaleX, tr.ScaleY = 50, 2, 2
+	b.Transform(tr)
+	hit, ok = b.Raycast(0, 0, 1, 0, 100)
+	require.True(t, ok)
+	require.InDelta(t, 40, hit.Distance, 1e-9)
+	// The radius of caring reaches the far corner of the rectangle, and the square around it is scaled by 2.
+	require.InDelta(t, 2*math.Sqrt2*math.Hypot(20, 2), b.BoundingRadius(), 1e-9)
+}
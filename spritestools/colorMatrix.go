@@ -0,0 +1,114 @@
+package spritestools
+
+import (
+	"math"
+
+	"github.com/gary23b/sprites/spritesmodels"
+)
+
+// Transforms a color the same way as ebiten's colorm.ColorM. Row i has the weights of r, g, b, and a for output
+// channel i, followed by a constant. Colors are from 0 to 1 and not premultiplied.
+type ColorMatrix [4][5]float64
+
+func IdentityColorMatrix() ColorMatrix {
+	return ColorMatrix{
+		{1, 0, 0, 0, 0},
+		{0, 1, 0, 0, 0},
+		{0, 0, 1, 0, 0},
+		{0, 0, 0, 1, 0},
+	}
+}
+
+// The matrix that applies c and then next.
+func (c ColorMatrix) Then(next ColorMatrix) ColorMatrix {
+	var ret ColorMatrix
+	for i := range 4 {
+		for j := range 5 {
+			sum := 0.0
+			for k := range 4 {
+				sum += next[i][k] * c[k][j]
+			}
+			if j == 4 {
+				sum += next[i][4]
+			}
+			ret[i][j] = sum
+		}
+	}
+	return ret
+}
+
+func (c ColorMatrix) Scale(r, g, b, a float64) ColorMatrix {
+	return c.Then(ColorMatrix{
+		{r, 0, 0, 0, 0},
+		{0, g, 0, 0, 0},
+		{0, 0, b, 0, 0},
+		{0, 0, 0, a, 0},
+	})
+}
+
+func (c ColorMatrix) Translate(r, g, b, a float64) ColorMatrix {
+	m := IdentityColorMatrix()
+	m[0][4], m[1][4], m[2][4], m[3][4] = r, g, b, a
+	return c.Then(m)
+}
+
+// Rotates the hue and scales the saturation and value the same way colorm.ColorM.ChangeHSV does, by going through
+// YCbCr.
+func (c ColorMatrix) ChangeHSV(hueRad, saturationScale, valueScale float64) ColorMatrix {
+	toYCbCr := ColorMatrix{
+		{0.2990, 0.5870, 0.1140, 0, 0},
+		{-0.1687, -0.3313, 0.5000, 0, 0},
+		{0.5000, -0.4187, -0.0813, 0, 0},
+		{0, 0, 0, 1, 0},
+	}
+	sin, cos := math.Sincos(hueRad)
+	rotate := ColorMatrix{
+		{1, 0, 0, 0, 0},
+		{0, cos, -sin, 0, 0},
+		{0, sin, cos, 0, 0},
+		{0, 0, 0, 1, 0},
+	}
+	toRGB := ColorMatrix{
+		{1, 0, 1.40200, 0, 0},
+		{1, -0.34414, -0.71414, 0, 0},
+		{1, 1.77200, 0, 0, 0},
+		{0, 0, 0, 1, 0},
+	}
+	s, v := saturationScale, valueScale
+	return c.Then(toYCbCr).Then(rotate).Scale(v, s*v, s*v, 1).Then(toRGB)
+}
+
+// Clamped between 0 and 1 like the GPU does.
+func (c ColorMatrix) Apply(r, g, b, a float64) (float64, float64, float64, float64) {
+	in := [4]float64{r, g, b, a}
+	var out [4]float64
+	for i := range 4 {
+		out[i] = c[i][4]
+		for k := range 4 {
+			out[i] += c[i][k] * in[k]
+		}
+		out[i] = max(0, min(1, out[i]))
+	}
+	return out[0], out[1], out[2], out[3]
+}
+
+// The color part of the sprite effects, with opacity from 0 to 100 like the sprite's.
+func EffectsColorMatrix(e spritesmodels.SpriteEffects, opacity float64) ColorMatrix {
+	clamp01 := func(x float64) float64 { return max(0, min(1, x)) }
+
+	m := IdentityColorMatrix()
+	if e.Tint.A != 0 {
+		// The same as colorm.ColorM.ScaleWithColor.
+		r, g, b, a := e.Tint.RGBA()
+		m = m.Scale(float64(r)/float64(a), float64(g)/float64(a), float64(b)/float64(a), float64(a)/0xffff)
+	}
+	if e.Grayscale != 0 || e.HueShift != 0 {
+		m = m.ChangeHSV(e.HueShift*(math.Pi/180.0), 1-clamp01(e.Grayscale/100), 1)
+	}
+	if e.Brightness != 0 {
+		b := max(-1, min(1, e.Brightness/100))
+		m = m.Translate(b, b, b, 0)
+	}
+	alpha := clamp01(opacity/100) * (1 - clamp01(e.Ghost/100))
+	return m.Scale(1, 1, 1, alpha)
+}
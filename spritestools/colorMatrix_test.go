@@ -0,0 +1,75 @@
+package spritestools
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/stretchr/testify/require"
+)
+
+func requireColor(t *testing.T, m ColorMatrix, in, expected [4]float64) {
+	t.Helper()
+	r, g, b, a := m.Apply(in[0], in[1], in[2], in[3])
+	require.InDeltaSlice(t, expected[:], []float64{r, g, b, a}, 0.01)
+}
+
+func TestColorMatrix(t *testing.T) {
+	red := [4]float64{1, 0, 0, 1}
+	requireColor(t, IdentityColorMatrix(), red, red)
+
+	// Scale then translate, not the other way around.
+	m := IdentityColorMatrix().Scale(0.5, 0.5, 0.5, 1).Translate(0.25, 0, 0, 0)
+	requireColor(t, m, [4]float64{1, 1, 1, 1}, [4]float64{0.75, 0.5, 0.5, 1})
+	m = IdentityColorMatrix().Translate(0.25, 0, 0, 0).Scale(0.5, 0.5, 0.5, 1)
+	requireColor(t, m, [4]float64{1, 1, 1, 1}, [4]float64{0.625, 0.5, 0.5, 1})
+
+	// Half way around the color wheel and back again.
+	m = IdentityColorMatrix().ChangeHSV(3.14159, 1, 1).ChangeHSV(-3.14159, 1, 1)
+	requireColor(t, m, [4]float64{0.2, 0.4, 0.6, 1}, [4]float64{0.2, 0.4, 0.6, 1})
+}
+
+func TestEffectsColorMatrix(t *testing.T) {
+	red := [4]float64{1, 0, 0, 1}
+	gray := [4]float64{0.5, 0.5, 0.5, 1}
+
+	// No effects change nothing.
+	requireColor(t, EffectsColorMatrix(spritesmodels.SpriteEffects{}, 100), red, red)
+
+	// Ghost stacks with the opacity.
+	m := EffectsColorMatrix(spritesmodels.SpriteEffects{Ghost: 25}, 100)
+	requireColor(t, m, red, [4]float64{1, 0, 0, 0.75})
+	m = EffectsColorMatrix(spritesmodels.SpriteEffects{Ghost: 50}, 50)
+	requireColor(t, m, red, [4]float64{1, 0, 0, 0.25})
+	m = EffectsColorMatrix(spritesmodels.SpriteEffects{Ghost: 200}, 100)
+	requireColor(t, m, red, [4]float64{1, 0, 0, 0})
+
+	// Greyscale takes the color away and leaves the brightness.
+	m = EffectsColorMatrix(spritesmodels.SpriteEffects{Grayscale: 100}, 100)
+	r, g, b, _ := m.Apply(1, 0, 0, 1)
+	require.InDelta(t, 0.299, r, 0.01)
+	require.InDelta(t, r, g, 1e-6)
+	require.InDelta(t, r, b, 1e-6)
+	requireColor(t, m, gray, gray)
+	m = EffectsColorMatrix(spritesmodels.SpriteEffects{Grayscale: 50}, 100)
+	r, g, _, _ = m.Apply(1, 0, 0, 1)
+	require.Greater(t, r, 0.5)
+	require.Greater(t, g, 0.0)
+
+	// Hue shift turns the color and leaves grey alone. YCbCr isn't exact, so 120 degrees lands close to green.
+	m = EffectsColorMatrix(spritesmodels.SpriteEffects{HueShift: 120}, 100)
+	r, g, b, _ = m.Apply(1, 0, 0, 1)
+	require.Greater(t, g, r)
+	require.Greater(t, g, b)
+	requireColor(t, m, gray, gray)
+	m = EffectsColorMatrix(spritesmodels.SpriteEffects{HueShift: 360}, 100)
+	requireColor(t, m, red, red)
+
+	// Tint and brightness
+	m = EffectsColorMatrix(spritesmodels.SpriteEffects{Tint: color.RGBA{0xFF, 0x80, 0, 0xFF}}, 100)
+	requireColor(t, m, [4]float64{1, 1, 1, 1}, [4]float64{1, 0.5, 0, 1})
+	m = EffectsColorMatrix(spritesmodels.SpriteEffects{Brightness: 50}, 100)
+	requireColor(t, m, gray, [4]float64{1, 1, 1, 1})
+	m = EffectsColorMatrix(spritesmodels.SpriteEffects{Brightness: -50}, 100)
+	requireColor(t, m, gray, [4]float64{0, 0, 0, 1})
+}
@@ -0,0 +1,256 @@
+package spritestools
+
+import (
+	"container/heap"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gary23b/sprites/spritesmodels"
+)
+
+const (
+	DefaultCommandQueueSize = 100000
+
+	fallingBehindFraction = 0.75 // The hook is called once the queue is this full.
+	fallingBehindInterval = time.Second
+)
+
+// Carries commands from the sim to the game. Any number of go routines can push, and the game takes everything once
+// per update. What happens when it is full depends on the overflow policy.
+type CommandQueue struct {
+	mutex           sync.Mutex
+	hasRoom         *sync.Cond
+	capacity        int
+	policy          spritesmodels.OverflowPolicy
+	onFallingBehind func(stats spritesmodels.CommandQueueStats)
+
+	items       []any // Dropped commands leave a nil behind.
+	spare       []any // The slice the game was last given. Reused once it asks again.
+	depth       int
+	latestMin   map[int]int // Sprite ID to the index of its queued position update, while nothing newer about it is queued.
+	latestPos   map[int]int // Sprite ID to the index of its newest queued position, for OverflowDropOldest.
+	droppable   indexHeap   // Position updates with a newer position for the same sprite queued after them.
+	stats       spritesmodels.CommandQueueStats
+	lastWarning time.Time
+}
+
+// onFallingBehind is called on its own go routine, at most once a second. nil logs a warning instead.
+func NewCommandQueue(capacity int, policy spritesmodels.OverflowPolicy, onFallingBehind func(stats spritesmodels.CommandQueueStats)) *CommandQueue {
+	if capacity <= 0 {
+		capacity = DefaultCommandQueueSize
+	}
+	if onFallingBehind == nil {
+		onFallingBehind = logFallingBehind
+	}
+
+	ret := &CommandQueue{
+		capacity:        capacity,
+		policy:          policy,
+		onFallingBehind: onFallingBehind,
+		latestMin:       make(map[int]int),
+		latestPos:       make(map[int]int),
+	}
+	ret.hasRoom = sync.NewCond(&ret.mutex)
+	ret.stats.Capacity = capacity
+	return ret
+}
+
+func logFallingBehind(stats spritesmodels.CommandQueueStats) {
+	log.Printf("Warning: the game is falling behind. %d of %d commands are waiting\n", stats.Depth, stats.Capacity)
+}
+
+func (q *CommandQueue) Push(cmd any) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.stats.Pushed++
+
+	if q.policy == spritesmodels.OverflowCoalesce {
+		if v, ok := cmd.(spritesmodels.CmdSpriteUpdateMin); ok {
+			if i, ok := q.latestMin[v.SpriteID]; ok {
+				q.items[i] = v
+				q.stats.Coalesced++
+				return
+			}
+		}
+	}
+
+	if q.depth >= q.capacity && !(q.policy == spritesmodels.OverflowDropOldest && q.dropOldest(cmd)) {
+		q.warn()
+		start := time.Now()
+		q.stats.BlockedPushes++
+		for q.depth >= q.capacity {
+			q.hasRoom.Wait()
+		}
+		q.stats.BlockedTime += time.Since(start)
+	}
+
+	q.add(cmd)
+	if float64(q.depth) >= fallingBehindFraction*float64(q.capacity) {
+		q.warn()
+	}
+}
+
+func (q *CommandQueue) add(cmd any) {
+	q.items = append(q.items, cmd)
+	q.depth++
+	q.stats.MaxDepth = max(q.stats.MaxDepth, q.depth)
+	switch q.policy {
+	case spritesmodels.OverflowDropOldest:
+		q.trackPositions(cmd)
+	case spritesmodels.OverflowCoalesce:
+		q.trackMins(cmd)
+	}
+}
+
+// Position updates can only be merged while nothing else about the sprite is queued after them.
+func (q *CommandQueue) trackMins(cmd any) {
+	switch v := cmd.(type) {
+	case spritesmodels.CmdSpriteUpdateMin:
+		q.latestMin[v.SpriteID] = len(q.items) - 1
+	case spritesmodels.CmdSpriteUpdateFull:
+		delete(q.latestMin, v.SpriteID)
+	case spritesmodels.CmdSpriteKinematics:
+		delete(q.latestMin, v.SpriteID)
+	case spritesmodels.CmdSpriteDrawOrder:
+		delete(q.latestMin, v.SpriteID)
+	case spritesmodels.CmdSpriteShader:
+		delete(q.latestMin, v.SpriteID)
+	case spritesmodels.CmdSpriteDelete:
+		delete(q.latestMin, v.SpriteID)
+	case spritesmodels.CmdSpriteBatch, spritesmodels.CmdSpritesDeleteAll:
+		clear(q.latestMin)
+	}
+}
+
+// A position update can only be dropped once something newer about where the sprite is, or that it is gone, is
+// queued after it. Otherwise the game would keep drawing the sprite where it was.
+func (q *CommandQueue) trackPositions(cmd any) {
+	index := len(q.items) - 1
+	switch v := cmd.(type) {
+	case spritesmodels.CmdSpriteUpdateMin:
+		q.newPosition(v.SpriteID, index)
+	case spritesmodels.CmdSpriteUpdateFull:
+		q.newPosition(v.SpriteID, index)
+	case spritesmodels.CmdSpriteBatch:
+		for _, u := range v.Updates {
+			q.newPosition(u.SpriteID, index)
+		}
+	case spritesmodels.CmdSpriteDelete:
+		q.newPosition(v.SpriteID, index)
+		delete(q.latestPos, v.SpriteID)
+	case spritesmodels.CmdSpritesDeleteAll:
+		for spriteID := range q.latestPos {
+			q.newPosition(spriteID, index)
+		}
+		clear(q.latestPos)
+	}
+}
+
+func positionSpriteID(cmd any) (int, bool) {
+	switch v := cmd.(type) {
+	case spritesmodels.CmdSpriteUpdateMin:
+		return v.SpriteID, true
+	case spritesmodels.CmdSpriteUpdateFull:
+		return v.SpriteID, true
+	case spritesmodels.CmdSpriteDelete:
+		return v.SpriteID, true
+	}
+	return 0, false
+}
+
+func (q *CommandQueue) newPosition(spriteID, index int) {
+	if i, ok := q.latestPos[spriteID]; ok && i != index {
+		if _, ok := q.items[i].(spritesmodels.CmdSpriteUpdateMin); ok {
+			heap.Push(&q.droppable, i)
+		}
+	}
+	q.latestPos[spriteID] = index
+}
+
+// Drops the oldest position update that has a newer one queued, or that the new command replaces. Returns false if
+// there aren't any.
+func (q *CommandQueue) dropOldest(cmd any) bool {
+	i := -1
+	if q.droppable.Len() > 0 {
+		i = heap.Pop(&q.droppable).(int)
+	} else if spriteID, ok := positionSpriteID(cmd); ok {
+		if j, ok := q.latestPos[spriteID]; ok {
+			if _, ok := q.items[j].(spritesmodels.CmdSpriteUpdateMin); ok {
+				i = j
+				delete(q.latestPos, spriteID)
+			}
+		}
+	}
+	if i < 0 {
+		return false
+	}
+	q.items[i] = nil
+	q.depth--
+	q.stats.Dropped++
+	return true
+}
+
+// Calls the hook on its own go routine, unless it was called too recently. The pusher may be holding sprite locks,
+// so the hook can't run on its go routine.
+func (q *CommandQueue) warn() {
+	now := time.Now()
+	if !q.lastWarning.IsZero() && now.Sub(q.lastWarning) < fallingBehindInterval {
+		return
+	}
+	q.lastWarning = now
+	go q.onFallingBehind(q.statsLocked())
+}
+
+// Everything that is queued, oldest first. The slice is only good until the next call.
+func (q *CommandQueue) TakeAll() []any {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	ret := q.items
+	if q.depth < len(ret) {
+		ret = slices.DeleteFunc(ret, func(cmd any) bool { return cmd == nil })
+	}
+	clear(q.spare)
+	q.items, q.spare = q.spare[:0], ret
+	q.depth = 0
+	clear(q.latestMin)
+	clear(q.latestPos)
+	q.droppable = q.droppable[:0]
+	q.hasRoom.Broadcast()
+	return ret
+}
+
+func (q *CommandQueue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.depth
+}
+
+func (q *CommandQueue) Stats() spritesmodels.CommandQueueStats {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.statsLocked()
+}
+
+func (q *CommandQueue) statsLocked() spritesmodels.CommandQueueStats {
+	ret := q.stats
+	ret.Depth = q.depth
+	return ret
+}
+
+// Queue indexes, smallest first.
+type indexHeap []int
+
+func (h indexHeap) Len() int           { return len(h) }
+func (h indexHeap) Less(i, j int) bool { return h[i] < h[j] }
+func (h indexHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *indexHeap) Push(x any)        { *h = append(*h, x.(int)) }
+
+func (h *indexHeap) Pop() any {
+	old := *h
+	ret := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return ret
+}
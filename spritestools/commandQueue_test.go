@@ -0,0 +1,237 @@
+package spritestools
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/stretchr/testify/require"
+)
+
+func minCmd(id int, x float64) spritesmodels.CmdSpriteUpdateMin {
+	return spritesmodels.CmdSpriteUpdateMin{SpriteID: id, X: x}
+}
+
+func fullCmd(id int) spritesmodels.CmdSpriteUpdateFull {
+	return spritesmodels.CmdSpriteUpdateFull{SpriteID: id}
+}
+
+func noWarning(stats spritesmodels.CommandQueueStats) {}
+
+func TestCommandQueueBlock(t *testing.T) {
+	q := NewCommandQueue(2, spritesmodels.OverflowBlock, noWarning)
+	q.Push(minCmd(1, 1))
+	q.Push(minCmd(1, 2))
+
+	pushed := make(chan struct{})
+	go func() {
+		q.Push(minCmd(1, 3))
+		close(pushed)
+	}()
+	select {
+	case <-pushed:
+		t.Fatal("Push should wait for room")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	require.Equal(t, []any{minCmd(1, 1), minCmd(1, 2)}, q.TakeAll())
+	<-pushed
+	require.Equal(t, []any{minCmd(1, 3)}, q.TakeAll())
+
+	stats := q.Stats()
+	require.Equal(t, 3, stats.Pushed)
+	require.Equal(t, 1, stats.BlockedPushes)
+	require.Equal(t, 2, stats.MaxDepth)
+	require.Equal(t, 0, stats.Depth)
+	require.Equal(t, 2, stats.Capacity)
+}
+
+func TestCommandQueueDropOldest(t *testing.T) {
+	q := NewCommandQueue(3, spritesmodels.OverflowDropOldest, noWarning)
+	q.Push(minCmd(1, 1))
+	q.Push(minCmd(2, 1))
+	q.Push(minCmd(1, 2))
+	q.Push(minCmd(1, 3)) // Drops the first position update
+	q.Push(fullCmd(1))   // Drops the second
+	require.Equal(t, 3, q.Len())
+	require.Equal(t, []any{minCmd(2, 1), minCmd(1, 3), fullCmd(1)}, q.TakeAll())
+	require.Equal(t, 2, q.Stats().Dropped)
+
+	// Nothing that can be dropped, so it waits.
+	q.Push(fullCmd(1))
+	q.Push(fullCmd(2))
+	q.Push(fullCmd(3))
+	pushed := make(chan struct{})
+	go func() {
+		q.Push(minCmd(1, 1))
+		close(pushed)
+	}()
+	select {
+	case <-pushed:
+		t.Fatal("Push should wait for room")
+	case <-time.After(20 * time.Millisecond):
+	}
+	require.Len(t, q.TakeAll(), 3)
+	<-pushed
+	require.Equal(t, []any{minCmd(1, 1)}, q.TakeAll())
+}
+
+// A sprite's only position update is never dropped, or the game would keep drawing it where it was.
+func TestCommandQueueDropOldestKeepsStationarySprites(t *testing.T) {
+	q := NewCommandQueue(4, spritesmodels.OverflowDropOldest, noWarning)
+
+	pushed := make(chan struct{})
+	go func() {
+		q.Push(minCmd(1, 7))
+		for i := range 100 {
+			for id := 2; id <= 4; id++ {
+				q.Push(minCmd(id, float64(i)))
+			}
+		}
+		close(pushed)
+	}()
+	select {
+	case <-pushed:
+	case <-time.After(time.Second):
+		t.Fatal("The movers should have made room by dropping their own updates")
+	}
+
+	require.Equal(t, []any{minCmd(1, 7), minCmd(2, 99), minCmd(3, 99), minCmd(4, 99)}, q.TakeAll())
+	require.Equal(t, 297, q.Stats().Dropped)
+	require.Zero(t, q.Stats().BlockedPushes)
+
+	// Only stationary sprites, so it waits.
+	for id := range 4 {
+		q.Push(minCmd(id, 1))
+	}
+	pushed = make(chan struct{})
+	go func() {
+		q.Push(minCmd(4, 1))
+		close(pushed)
+	}()
+	select {
+	case <-pushed:
+		t.Fatal("Push should wait for room")
+	case <-time.After(20 * time.Millisecond):
+	}
+	require.Len(t, q.TakeAll(), 4)
+	<-pushed
+}
+
+func TestCommandQueueCoalesce(t *testing.T) {
+	q := NewCommandQueue(10, spritesmodels.OverflowCoalesce, noWarning)
+	q.Push(minCmd(1, 1))
+	q.Push(minCmd(2, 1))
+	q.Push(minCmd(1, 2))
+	q.Push(fullCmd(1))
+	q.Push(minCmd(1, 3)) // Can't go before the full update
+	q.Push(minCmd(1, 4))
+	q.Push(minCmd(2, 2))
+	require.Equal(t, []any{minCmd(1, 2), minCmd(2, 2), fullCmd(1), minCmd(1, 4)}, q.TakeAll())
+
+	stats := q.Stats()
+	require.Equal(t, 7, stats.Pushed)
+	require.Equal(t, 3, stats.Coalesced)
+
+	// Nothing is merged with commands the game already took.
+	q.Push(minCmd(1, 5))
+	q.Push(spritesmodels.CmdSpritesDeleteAll{})
+	q.Push(minCmd(1, 6))
+	require.Equal(t, []any{minCmd(1, 5), spritesmodels.CmdSpritesDeleteAll{}, minCmd(1, 6)}, q.TakeAll())
+}
+
+func TestCommandQueueFallingBehind(t *testing.T) {
+	warnings := make(chan spritesmodels.CommandQueueStats, 10)
+	var q *CommandQueue
+	q = NewCommandQueue(4, spritesmodels.OverflowBlock, func(stats spritesmodels.CommandQueueStats) {
+		q.Push(minCmd(2, 1)) // The hook doesn't run while the queue is locked.
+		warnings <- stats
+	})
+	noWarnings := func() {
+		t.Helper()
+		select {
+		case <-warnings:
+			t.Fatal("Unexpected warning")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+
+	q.Push(minCmd(1, 1))
+	q.Push(minCmd(1, 2))
+	noWarnings()
+	q.Push(minCmd(1, 3))
+	select {
+	case stats := <-warnings:
+		require.Equal(t, 3, stats.Depth)
+	case <-time.After(time.Second):
+		t.Fatal("The hook should have been called")
+	}
+
+	// Not again so soon.
+	q.TakeAll()
+	for i := range 3 {
+		q.Push(minCmd(1, float64(i)))
+	}
+	noWarnings()
+}
+
+// Run with -race.
+func TestCommandQueueConcurrentUse(t *testing.T) {
+	q := NewCommandQueue(16, spritesmodels.OverflowCoalesce, noWarning)
+
+	var wg sync.WaitGroup
+	const pushers, loops = 8, 1000
+	for p := range pushers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range loops {
+				if i%10 == 0 {
+					q.Push(fullCmd(p))
+				} else {
+					q.Push(minCmd(p, float64(i)))
+				}
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	// Like the game, take everything once per update. Each sprite's updates have to stay in order.
+	last := make(map[int]float64)
+	fulls := 0
+	take := func() {
+		for _, cmd := range q.TakeAll() {
+			switch v := cmd.(type) {
+			case spritesmodels.CmdSpriteUpdateMin:
+				require.Greater(t, v.X, last[v.SpriteID])
+				last[v.SpriteID] = v.X
+			case spritesmodels.CmdSpriteUpdateFull:
+				fulls++
+			}
+		}
+	}
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+			time.Sleep(time.Millisecond)
+		}
+		take()
+	}
+	take()
+
+	require.Equal(t, pushers*loops/10, fulls)
+	for p := range pushers {
+		require.Equal(t, float64(loops-1), last[p])
+	}
+	stats := q.Stats()
+	require.Equal(t, pushers*loops, stats.Pushed)
+	require.LessOrEqual(t, stats.MaxDepth, 16)
+}
@@ -0,0 +1,59 @@
+package spritestools
+
+import (
+	"io/fs"
+	"time"
+)
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+	missing bool
+}
+
+// Polls a set of files for changes. No OS specific notifications are used, so it works anywhere fs.Stat does.
+// Files in an embed.FS never change.
+type FileWatcher struct {
+	fsys   fs.FS
+	stamps map[string]fileStamp
+}
+
+func NewFileWatcher(fsys fs.FS, paths []string) *FileWatcher {
+	ret := &FileWatcher{
+		fsys: fsys,
+	}
+	ret.SetPaths(paths)
+	return ret
+}
+
+func (w *FileWatcher) stat(path string) fileStamp {
+	info, err := fs.Stat(w.fsys, path)
+	if err != nil {
+		return fileStamp{missing: true}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+// Replaces the watched files. The current state of each file is the baseline for the next Poll.
+func (w *FileWatcher) SetPaths(paths []string) {
+	w.stamps = make(map[string]fileStamp, len(paths))
+	for _, p := range paths {
+		w.stamps[p] = w.stat(p)
+	}
+}
+
+// Returns the files that changed since the last call. A file that is deleted is not reported until it comes back.
+func (w *FileWatcher) Poll() []string {
+	changed := []string{}
+	for p, old := range w.stamps {
+		stamp := w.stat(p)
+		if stamp == old {
+			continue
+		}
+		w.stamps[p] = stamp
+		if !stamp.missing {
+			changed = append(changed, p)
+		}
+	}
+	return changed
+}
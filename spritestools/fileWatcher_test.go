@@ -0,0 +1,43 @@
+package spritestools
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileWatcher(t *testing.T) {
+	start := time.Now()
+	fsys := fstest.MapFS{
+		"a.png": {Data: []byte("a"), ModTime: start},
+		"b.wav": {Data: []byte("b"), ModTime: start},
+	}
+
+	w := NewFileWatcher(fsys, []string{"a.png", "b.wav", "c.png"})
+	require.Empty(t, w.Poll())
+
+	// Modified time changes
+	fsys["a.png"].ModTime = start.Add(time.Second)
+	require.Equal(t, []string{"a.png"}, w.Poll())
+	require.Empty(t, w.Poll())
+
+	// Size changes
+	fsys["b.wav"].Data = []byte("bb")
+	require.Equal(t, []string{"b.wav"}, w.Poll())
+
+	// Deleted and then created again
+	delete(fsys, "b.wav")
+	require.Empty(t, w.Poll())
+	fsys["b.wav"] = &fstest.MapFile{Data: []byte("b"), ModTime: start}
+	require.Equal(t, []string{"b.wav"}, w.Poll())
+
+	// A file that didn't exist at the start
+	fsys["c.png"] = &fstest.MapFile{Data: []byte("c"), ModTime: start}
+	require.Equal(t, []string{"c.png"}, w.Poll())
+
+	w.SetPaths([]string{"a.png"})
+	fsys["b.wav"].ModTime = start.Add(time.Hour)
+	require.Empty(t, w.Poll())
+}
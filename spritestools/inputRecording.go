@@ -0,0 +1,131 @@
+package spritestools
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/gary23b/sprites/spritesmodels"
+)
+
+// One line of an input recording. Only ticks where the input changed are written, plus the last tick so that the
+// replay knows how long the recording is.
+type inputFrame struct {
+	Tick        int
+	Pressed     spritesmodels.UserInput
+	JustPressed spritesmodels.UserInput
+}
+
+// Writes the per-tick user input stream as JSON lines so that a session can be replayed later.
+type InputRecorder struct {
+	w    io.Writer
+	enc  *json.Encoder
+	tick int
+	last *inputFrame
+}
+
+func NewInputRecorder(w io.Writer) *InputRecorder {
+	ret := &InputRecorder{
+		w:   w,
+		enc: json.NewEncoder(w),
+	}
+	return ret
+}
+
+// Record must be called once per tick.
+func (s *InputRecorder) Record(pressed, justPressed *spritesmodels.UserInput) error {
+	frame := inputFrame{Tick: s.tick}
+	if pressed != nil {
+		frame.Pressed = *pressed
+	}
+	if justPressed != nil {
+		frame.JustPressed = *justPressed
+	}
+	s.tick++
+
+	// Held keys don't need to be written every tick, but every just pressed event does.
+	if s.last != nil && s.last.Pressed == frame.Pressed && frame.JustPressed == (spritesmodels.UserInput{}) {
+		return nil
+	}
+	s.last = &frame
+
+	if err := s.enc.Encode(frame); err != nil {
+		return fmt.Errorf("Failed to write input frame %d: %w", frame.Tick, err)
+	}
+	return nil
+}
+
+// Close writes the last tick and closes the underlying writer if it is an io.Closer.
+func (s *InputRecorder) Close() error {
+	var err error
+	if s.last != nil && s.last.Tick < s.tick-1 {
+		// Nothing changed since the last frame written, so the keys held then are still held.
+		frame := inputFrame{Tick: s.tick - 1, Pressed: s.last.Pressed}
+		s.last = &frame
+		if err = s.enc.Encode(frame); err != nil {
+			err = fmt.Errorf("Failed to write input frame %d: %w", frame.Tick, err)
+		}
+	}
+
+	if c, ok := s.w.(io.Closer); ok {
+		if closeErr := c.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// Plays back a recording made by InputRecorder one tick at a time.
+type InputReplayer struct {
+	frames  []inputFrame
+	index   int
+	current *inputFrame
+	tick    int
+}
+
+func NewInputReplayer(r io.Reader) (*InputReplayer, error) {
+	ret := &InputReplayer{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var frame inputFrame
+		if err := json.Unmarshal(scanner.Bytes(), &frame); err != nil {
+			return nil, fmt.Errorf("Failed to decode input frame %d: %w", len(ret.frames), err)
+		}
+		ret.frames = append(ret.frames, frame)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Failed to read input recording: %w", err)
+	}
+
+	return ret, nil
+}
+
+// Next returns the input for the next tick. done is true once the last recorded tick has been played.
+func (s *InputReplayer) Next() (pressed, justPressed *spritesmodels.UserInput, done bool) {
+	tick := s.tick
+	s.tick++
+
+	for s.index < len(s.frames) && s.frames[s.index].Tick <= tick {
+		s.current = &s.frames[s.index]
+		s.index++
+	}
+
+	pressed = &spritesmodels.UserInput{}
+	justPressed = &spritesmodels.UserInput{}
+	if s.current != nil {
+		*pressed = s.current.Pressed
+		// Just pressed events only happen on the exact tick they were recorded.
+		if s.current.Tick == tick {
+			*justPressed = s.current.JustPressed
+		}
+	}
+
+	done = len(s.frames) == 0 || tick >= s.frames[len(s.frames)-1].Tick
+	return pressed, justPressed, done
+}
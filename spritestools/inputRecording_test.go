@@ -0,0 +1,96 @@
+package spritestools
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInputRecording(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r := NewInputRecorder(buf)
+
+	empty := &spritesmodels.UserInput{}
+	held := &spritesmodels.UserInput{AnyPressed: true}
+	held.Keys.A = true
+	click := &spritesmodels.UserInput{AnyPressed: true}
+	click.Mouse.Left = true
+	click.Mouse.MouseX = 10
+	click.Mouse.MouseY = -20
+
+	require.NoError(t, r.Record(empty, empty)) // tick 0
+	require.NoError(t, r.Record(held, held))   // tick 1
+	require.NoError(t, r.Record(held, empty))  // tick 2, not written
+	require.NoError(t, r.Record(held, empty))  // tick 3, not written
+	require.NoError(t, r.Record(empty, click)) // tick 4
+	require.NoError(t, r.Record(empty, click)) // tick 5
+	require.NoError(t, r.Close())
+
+	require.Equal(t, 4, bytes.Count(buf.Bytes(), []byte("\n")))
+
+	p, err := NewInputReplayer(buf)
+	require.NoError(t, err)
+
+	expected := []struct {
+		pressed, justPressed *spritesmodels.UserInput
+	}{
+		{empty, empty},
+		{held, held},
+		{held, empty},
+		{held, empty},
+		{empty, click},
+		{empty, click},
+	}
+	for i, e := range expected {
+		pressed, justPressed, done := p.Next()
+		require.Equal(t, *e.pressed, *pressed, "tick %d", i)
+		require.Equal(t, *e.justPressed, *justPressed, "tick %d", i)
+		require.Equal(t, i == len(expected)-1, done, "tick %d", i)
+	}
+
+	// After the end of the recording the last held state stays and nothing new is just pressed.
+	pressed, justPressed, done := p.Next()
+	require.True(t, done)
+	require.Equal(t, *empty, *pressed)
+	require.Equal(t, *empty, *justPressed)
+}
+
+// Keys held at the end stay held until the last tick, and the replay isn't done before then.
+func TestInputRecording_heldToTheEnd(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r := NewInputRecorder(buf)
+
+	empty := &spritesmodels.UserInput{}
+	held := &spritesmodels.UserInput{AnyPressed: true}
+	held.Keys.Space = true
+
+	require.NoError(t, r.Record(empty, empty)) // tick 0
+	require.NoError(t, r.Record(held, held))   // tick 1
+	for range 5 {
+		require.NoError(t, r.Record(held, empty)) // ticks 2 to 6, not written
+	}
+	require.NoError(t, r.Close()) // writes tick 6
+	require.Equal(t, 3, bytes.Count(buf.Bytes(), []byte("\n")))
+
+	p, err := NewInputReplayer(buf)
+	require.NoError(t, err)
+	for i := range 7 {
+		pressed, justPressed, done := p.Next()
+		if i == 0 {
+			require.Equal(t, *empty, *pressed)
+		} else {
+			require.Equal(t, *held, *pressed, "tick %d", i)
+		}
+		if i != 1 {
+			require.Equal(t, *empty, *justPressed, "tick %d", i)
+		}
+		require.Equal(t, i == 6, done, "tick %d", i)
+	}
+}
+
+func TestInputReplayer_badData(t *testing.T) {
+	_, err := NewInputReplayer(bytes.NewBufferString("not json\n"))
+	require.Error(t, err)
+}
@@ -0,0 +1,72 @@
+package spritestools
+
+import (
+	"math"
+
+	"github.com/gary23b/sprites/spritesmodels"
+)
+
+// Moves a sprite by dt seconds and keeps it inside a window of 2*halfW by 2*halfH centered on the origin. The
+// velocity in k is updated too. The returned update has everything but the sprite ID and generation filled in.
+func StepKinematics(k *spritesmodels.Kinematics, x, y, angleRad, dt, halfW, halfH float64) spritesmodels.KinematicsUpdate {
+	k.VelX += k.AccelX * dt
+	k.VelY += k.AccelY * dt
+	if k.Drag > 0 {
+		keep := math.Pow(1-max(0, min(1, k.Drag)), dt)
+		k.VelX *= keep
+		k.VelY *= keep
+	}
+	if speed := math.Hypot(k.VelX, k.VelY); k.MaxSpeed > 0 && speed > k.MaxSpeed {
+		k.VelX *= k.MaxSpeed / speed
+		k.VelY *= k.MaxSpeed / speed
+	}
+	x += k.VelX * dt
+	y += k.VelY * dt
+	angleRad += k.AngularVelocity * (math.Pi / 180.0) * dt
+
+	var outX, outY bool
+	x, k.VelX, outX = applyEdge(k.Edge, x, k.VelX, halfW)
+	y, k.VelY, outY = applyEdge(k.Edge, y, k.VelY, halfH)
+	return spritesmodels.KinematicsUpdate{
+		X:         x,
+		Y:         y,
+		AngleRad:  angleRad,
+		VelX:      k.VelX,
+		VelY:      k.VelY,
+		Destroyed: outX || outY,
+	}
+}
+
+// Keeps one coordinate inside -half to half.
+func applyEdge(edge spritesmodels.EdgeBehavior, pos, vel, half float64) (newPos, newVel float64, destroyed bool) {
+	if pos >= -half && pos <= half {
+		return pos, vel, false
+	}
+	switch edge {
+	case spritesmodels.EdgeBounce:
+		if half <= 0 {
+			return 0, vel, false
+		}
+		// Going back and forth repeats every 4*half, so big jumps bounce as many times as they need to.
+		outward := math.Copysign(math.Abs(vel), pos)
+		p := math.Mod(pos+half, 4*half)
+		if p < 0 {
+			p += 4 * half
+		}
+		if p <= 2*half {
+			return p - half, outward, false
+		}
+		return 3*half - p, -outward, false
+	case spritesmodels.EdgeWrap:
+		if half <= 0 {
+			return 0, vel, false
+		}
+		return math.Mod(math.Mod(pos+half, 2*half)+2*half, 2*half) - half, vel, false
+	case spritesmodels.EdgeClamp:
+		return max(-half, min(half, pos)), 0, false
+	case spritesmodels.EdgeDestroy:
+		return pos, vel, true
+	default:
+		return pos, vel, false
+	}
+}
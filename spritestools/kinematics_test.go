@@ -0,0 +1,119 @@
+package spritestools
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStepKinematics(t *testing.T) {
+	k := spritesmodels.Kinematics{VelX: 10, AccelY: 20, AngularVelocity: 90}
+	u := StepKinematics(&k, 1, 2, 0, 0.5, 100, 100)
+	require.InDelta(t, 6, u.X, 1e-9)
+	require.InDelta(t, 2+10*0.5, u.Y, 1e-9)
+	require.InDelta(t, math.Pi/4, u.AngleRad, 1e-9)
+	require.Equal(t, 10.0, k.VelX)
+	require.Equal(t, 10.0, k.VelY)
+	require.Equal(t, k.VelY, u.VelY)
+	require.False(t, u.Destroyed)
+
+	// Drag is the fraction lost each second.
+	k = spritesmodels.Kinematics{VelX: 100, Drag: 0.75}
+	u = StepKinematics(&k, 0, 0, 0, 0.5, 100, 100)
+	require.InDelta(t, 50, k.VelX, 1e-9)
+	require.InDelta(t, 25, u.X, 1e-9)
+	StepKinematics(&k, 0, 0, 0, 0.5, 100, 100)
+	require.InDelta(t, 25, k.VelX, 1e-9)
+
+	// More than 1 stops it outright.
+	k = spritesmodels.Kinematics{VelX: 100, Drag: 2}
+	StepKinematics(&k, 0, 0, 0, 0.5, 100, 100)
+	require.Equal(t, 0.0, k.VelX)
+
+	// The speed is limited after the acceleration, keeping the direction.
+	k = spritesmodels.Kinematics{VelX: 30, VelY: 40, AccelX: 60, AccelY: 80, MaxSpeed: 10}
+	u = StepKinematics(&k, 0, 0, 0, 1, 100, 100)
+	require.InDelta(t, 6, k.VelX, 1e-9)
+	require.InDelta(t, 8, k.VelY, 1e-9)
+	require.InDelta(t, 6, u.X, 1e-9)
+	require.InDelta(t, 8, u.Y, 1e-9)
+}
+
+func TestStepKinematics_edges(t *testing.T) {
+	step := func(edge spritesmodels.EdgeBehavior, x, velX float64) (spritesmodels.KinematicsUpdate, spritesmodels.Kinematics) {
+		k := spritesmodels.Kinematics{VelX: velX, Edge: edge}
+		u := StepKinematics(&k, x, 0, 0, 1, 100, 50)
+		return u, k
+	}
+
+	// Inside the window nothing happens.
+	for _, edge := range []spritesmodels.EdgeBehavior{spritesmodels.EdgeNone, spritesmodels.EdgeBounce, spritesmodels.EdgeWrap, spritesmodels.EdgeClamp, spritesmodels.EdgeDestroy} {
+		u, k := step(edge, 90, 10)
+		require.Equal(t, 100.0, u.X)
+		require.Equal(t, 10.0, k.VelX)
+		require.False(t, u.Destroyed)
+	}
+
+	u, k := step(spritesmodels.EdgeNone, 95, 10)
+	require.Equal(t, 105.0, u.X)
+	require.Equal(t, 10.0, k.VelX)
+	require.False(t, u.Destroyed)
+
+	u, k = step(spritesmodels.EdgeBounce, 95, 10)
+	require.Equal(t, 95.0, u.X)
+	require.Equal(t, -10.0, k.VelX)
+	require.Equal(t, -10.0, u.VelX)
+	u, k = step(spritesmodels.EdgeBounce, -95, -10)
+	require.Equal(t, -95.0, u.X)
+	require.Equal(t, 10.0, k.VelX)
+
+	u, k = step(spritesmodels.EdgeWrap, 95, 10)
+	require.Equal(t, -95.0, u.X)
+	require.Equal(t, 10.0, k.VelX)
+	u, _ = step(spritesmodels.EdgeWrap, -95, -10)
+	require.Equal(t, 95.0, u.X)
+
+	u, k = step(spritesmodels.EdgeClamp, 95, 10)
+	require.Equal(t, 100.0, u.X)
+	require.Equal(t, 0.0, k.VelX)
+	u, _ = step(spritesmodels.EdgeClamp, -95, -10)
+	require.Equal(t, -100.0, u.X)
+
+	u, k = step(spritesmodels.EdgeDestroy, 95, 10)
+	require.Equal(t, 105.0, u.X)
+	require.Equal(t, 10.0, k.VelX)
+	require.True(t, u.Destroyed)
+
+	// Big jumps bounce more than once.
+	u, k = step(spritesmodels.EdgeBounce, 340, 10)
+	require.Equal(t, -50.0, u.X)
+	require.Equal(t, 10.0, k.VelX)
+	u, k = step(spritesmodels.EdgeBounce, 390, 10)
+	require.Equal(t, 0.0, u.X)
+	require.Equal(t, 10.0, k.VelX)
+	u, k = step(spritesmodels.EdgeBounce, 590, 10)
+	require.Equal(t, 0.0, u.X)
+	require.Equal(t, -10.0, k.VelX)
+	u, k = step(spritesmodels.EdgeBounce, -340, -10)
+	require.Equal(t, 50.0, u.X)
+	require.Equal(t, -10.0, k.VelX)
+
+	u, _ = step(spritesmodels.EdgeWrap, 590, 10)
+	require.Equal(t, 0.0, u.X)
+
+	// A window with no room keeps the sprite in the middle instead of making the position NaN.
+	for _, edge := range []spritesmodels.EdgeBehavior{spritesmodels.EdgeBounce, spritesmodels.EdgeWrap, spritesmodels.EdgeClamp} {
+		k = spritesmodels.Kinematics{VelX: 10, Edge: edge}
+		u = StepKinematics(&k, 5, 0, 0, 1, 0, 0)
+		require.Equal(t, 0.0, u.X)
+		require.False(t, math.IsNaN(k.VelX))
+	}
+
+	// Each axis has its own half size.
+	k = spritesmodels.Kinematics{VelY: 10, Edge: spritesmodels.EdgeBounce}
+	u = StepKinematics(&k, 0, 45, 0, 1, 100, 50)
+	require.Equal(t, 45.0, u.Y)
+	require.Equal(t, -10.0, k.VelY)
+}
@@ -0,0 +1,383 @@
+package spritestools
+
+import (
+	"container/heap"
+	"log"
+	"math"
+	"sync"
+
+	"github.com/gary23b/sprites/spritesmodels"
+)
+
+// The cells of a NavGrid. Cell (i, j) covers the same world area as the PositionBroker cell (i, j) of the same size.
+type navGeometry struct {
+	cellSize      float64
+	minCell       cellKey
+	width, height int
+}
+
+func (g *navGeometry) cellIndex(v float64) int {
+	return int(math.Floor(v / g.cellSize))
+}
+
+// The index into the grid's slices. ok is false outside the grid.
+func (g *navGeometry) index(x, y float64) (int, bool) {
+	if math.IsNaN(x) || math.IsNaN(y) {
+		return 0, false
+	}
+	return g.cellToIndex(g.cellIndex(x), g.cellIndex(y))
+}
+
+func (g *navGeometry) cellToIndex(cx, cy int) (int, bool) {
+	cx -= g.minCell.x
+	cy -= g.minCell.y
+	if cx < 0 || cy < 0 || cx >= g.width || cy >= g.height {
+		return 0, false
+	}
+	return cy*g.width + cx, true
+}
+
+func (g *navGeometry) cellCenter(i int) (float64, float64) {
+	cx := float64(g.minCell.x+i%g.width) + .5
+	cy := float64(g.minCell.y+i/g.width) + .5
+	return cx * g.cellSize, cy * g.cellSize
+}
+
+var navNeighbors = [8][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}, {1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+
+// Calls f for each neighbor that can be stepped to from cell i. Diagonal steps can't cut the corner of a blocked cell.
+func (g *navGeometry) forNeighbors(i int, passable func(int) bool, f func(n int, cost float64)) {
+	cx, cy := i%g.width, i/g.width
+	for _, d := range navNeighbors {
+		nx, ny := cx+d[0], cy+d[1]
+		if nx < 0 || ny < 0 || nx >= g.width || ny >= g.height {
+			continue
+		}
+		n := ny*g.width + nx
+		if !passable(n) {
+			continue
+		}
+		cost := 1.0
+		if d[0] != 0 && d[1] != 0 {
+			if !passable(cy*g.width+nx) || !passable(ny*g.width+cx) {
+				continue
+			}
+			cost = math.Sqrt2
+		}
+		f(n, cost*g.cellSize)
+	}
+}
+
+// A grid of blocked and open cells over part of the world, for finding paths around obstacles.
+// It uses the same Cartesian world coordinates as the sprites.
+type NavGrid struct {
+	navGeometry
+	mutex   sync.RWMutex
+	blocked []bool
+}
+
+// Covers bounds, rounded out to whole cells.
+func NewNavGrid(bounds spritesmodels.Rect, cellSize float64) *NavGrid {
+	if !(cellSize > 0) {
+		log.Printf("Invalid nav grid cell size: %f, using %f\n", cellSize, DefaultPositionGridCellSize)
+		cellSize = DefaultPositionGridCellSize
+	}
+
+	ret := &NavGrid{}
+	ret.cellSize = cellSize
+	ret.minCell = cellKey{x: ret.cellIndex(bounds.MinX), y: ret.cellIndex(bounds.MinY)}
+	ret.width = max(1, ret.cellIndex(bounds.MaxX)-ret.minCell.x+1)
+	ret.height = max(1, ret.cellIndex(bounds.MaxY)-ret.minCell.y+1)
+	ret.blocked = make([]bool, ret.width*ret.height)
+	return ret
+}
+
+func (g *NavGrid) CellSize() float64 {
+	return g.cellSize
+}
+
+// The area covered by the cells.
+func (g *NavGrid) Bounds() spritesmodels.Rect {
+	return spritesmodels.Rect{
+		MinX: float64(g.minCell.x) * g.cellSize,
+		MinY: float64(g.minCell.y) * g.cellSize,
+		MaxX: float64(g.minCell.x+g.width) * g.cellSize,
+		MaxY: float64(g.minCell.y+g.height) * g.cellSize,
+	}
+}
+
+// Points outside the grid count as blocked.
+func (g *NavGrid) IsBlocked(x, y float64) bool {
+	i, ok := g.index(x, y)
+	if !ok {
+		return true
+	}
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return g.blocked[i]
+}
+
+func (g *NavGrid) SetBlocked(x, y float64, blocked bool) {
+	i, ok := g.index(x, y)
+	if !ok {
+		return
+	}
+	g.mutex.Lock()
+	g.blocked[i] = blocked
+	g.mutex.Unlock()
+}
+
+// Blocks every cell the rectangle touches.
+func (g *NavGrid) BlockRect(rect spritesmodels.Rect) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	minX := max(g.cellIndex(rect.MinX), g.minCell.x)
+	maxX := min(g.cellIndex(rect.MaxX), g.minCell.x+g.width-1)
+	minY := max(g.cellIndex(rect.MinY), g.minCell.y)
+	maxY := min(g.cellIndex(rect.MaxY), g.minCell.y+g.height-1)
+	for cy := minY; cy <= maxY; cy++ {
+		for cx := minX; cx <= maxX; cx++ {
+			i, _ := g.cellToIndex(cx, cy)
+			g.blocked[i] = true
+		}
+	}
+}
+
+// Blocks the cells with their center inside the closed polygon, and every cell its edges pass through.
+func (g *NavGrid) BlockPolygon(outline []spritesmodels.Point) {
+	if len(outline) == 0 {
+		return
+	}
+	bounds := spritesmodels.Rect{MinX: outline[0].X, MinY: outline[0].Y, MaxX: outline[0].X, MaxY: outline[0].Y}
+	for _, p := range outline {
+		bounds.MinX, bounds.MaxX = min(bounds.MinX, p.X), max(bounds.MaxX, p.X)
+		bounds.MinY, bounds.MaxY = min(bounds.MinY, p.Y), max(bounds.MaxY, p.Y)
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	minX := max(g.cellIndex(bounds.MinX), g.minCell.x)
+	maxX := min(g.cellIndex(bounds.MaxX), g.minCell.x+g.width-1)
+	minY := max(g.cellIndex(bounds.MinY), g.minCell.y)
+	maxY := min(g.cellIndex(bounds.MaxY), g.minCell.y+g.height-1)
+	for cy := minY; cy <= maxY; cy++ {
+		for cx := minX; cx <= maxX; cx++ {
+			i, _ := g.cellToIndex(cx, cy)
+			x, y := g.cellCenter(i)
+			if pointInPolygon(outline, x, y) {
+				g.blocked[i] = true
+			}
+		}
+	}
+
+	// Thin shapes might not cover any cell centers. Walk the edges in steps smaller than a cell.
+	for k := range outline {
+		a, b := outline[k], outline[(k+1)%len(outline)]
+		steps := int(math.Ceil(2*math.Hypot(b.X-a.X, b.Y-a.Y)/g.cellSize)) + 1
+		for s := 0; s <= steps; s++ {
+			f := float64(s) / float64(steps)
+			if i, ok := g.index(a.X+(b.X-a.X)*f, a.Y+(b.Y-a.Y)*f); ok {
+				g.blocked[i] = true
+			}
+		}
+	}
+}
+
+// Blocks the cells covered by each shape of the body, where the body is right now.
+func (g *NavGrid) BlockBody(body spritesmodels.ClickOnBody) {
+	for _, outline := range body.Outlines() {
+		g.BlockPolygon(outline)
+	}
+}
+
+// Even-odd rule
+func pointInPolygon(outline []spritesmodels.Point, x, y float64) bool {
+	inside := false
+	for i, j := 0, len(outline)-1; i < len(outline); j, i = i, i+1 {
+		a, b := outline[i], outline[j]
+		if (a.Y > y) != (b.Y > y) && x < (b.X-a.X)*(y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+type navQueueItem struct {
+	cell     int
+	priority float64
+}
+
+type navQueue []navQueueItem
+
+func (q navQueue) Len() int           { return len(q) }
+func (q navQueue) Less(i, j int) bool { return q[i].priority < q[j].priority }
+func (q navQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *navQueue) Push(x any)        { *q = append(*q, x.(navQueueItem)) }
+func (q *navQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// A* from one point to another, moving in 8 directions. The path is the corners to walk through, ending at (toX, toY).
+// The start point is not included. The start cell may be blocked, so a sprite standing on an obstacle can still leave.
+// ok is false if the target is blocked, outside the grid, or can't be reached.
+func (g *NavGrid) FindPath(fromX, fromY, toX, toY float64) (path []spritesmodels.Point, ok bool) {
+	start, ok1 := g.index(fromX, fromY)
+	goal, ok2 := g.index(toX, toY)
+	if !ok1 || !ok2 {
+		return nil, false
+	}
+
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	if g.blocked[goal] {
+		return nil, false
+	}
+	passable := func(i int) bool { return !g.blocked[i] || i == start }
+
+	// Octile distance, which never overestimates with these step costs.
+	goalX, goalY := goal%g.width, goal/g.width
+	heuristic := func(i int) float64 {
+		dx := math.Abs(float64(i%g.width - goalX))
+		dy := math.Abs(float64(i/g.width - goalY))
+		return (max(dx, dy) + (math.Sqrt2-1)*min(dx, dy)) * g.cellSize
+	}
+
+	cost := make(map[int]float64)
+	cameFrom := make(map[int]int)
+	cost[start] = 0
+	queue := &navQueue{{cell: start, priority: heuristic(start)}}
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(navQueueItem)
+		if item.cell == goal {
+			break
+		}
+		if item.priority > cost[item.cell]+heuristic(item.cell) {
+			continue // Already found a shorter way here.
+		}
+		g.forNeighbors(item.cell, passable, func(n int, stepCost float64) {
+			newCost := cost[item.cell] + stepCost
+			if old, seen := cost[n]; seen && old <= newCost {
+				return
+			}
+			cost[n] = newCost
+			cameFrom[n] = item.cell
+			heap.Push(queue, navQueueItem{cell: n, priority: newCost + heuristic(n)})
+		})
+	}
+	if _, found := cost[goal]; !found {
+		return nil, false
+	}
+
+	cells := []int{goal}
+	for c := goal; c != start; {
+		c = cameFrom[c]
+		cells = append(cells, c)
+	}
+	return g.corners(cells, toX, toY), true
+}
+
+// Turns cells from the goal back to the start into the points where the path changes direction, from the start on.
+func (g *NavGrid) corners(cells []int, toX, toY float64) []spritesmodels.Point {
+	ret := []spritesmodels.Point{}
+	for k := len(cells) - 2; k > 0; k-- {
+		prev, cur, next := cells[k+1], cells[k], cells[k-1]
+		if cur-prev == next-cur {
+			continue
+		}
+		x, y := g.cellCenter(cur)
+		ret = append(ret, spritesmodels.Point{X: x, Y: y})
+	}
+	return append(ret, spritesmodels.Point{X: toX, Y: toY})
+}
+
+// The path from every cell to one target, found all at once. Good for many sprites heading to the same place.
+type FlowField struct {
+	navGeometry
+	distance         []float64 // Path length to the target. Inf if it can't be reached.
+	targetX, targetY float64
+}
+
+// Later changes to the grid don't affect the returned field. It is nil if the target is blocked or outside the grid.
+func (g *NavGrid) FlowField(toX, toY float64) *FlowField {
+	goal, ok := g.index(toX, toY)
+	if !ok {
+		return nil
+	}
+
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	if g.blocked[goal] {
+		return nil
+	}
+
+	ret := &FlowField{
+		navGeometry: g.navGeometry,
+		distance:    make([]float64, len(g.blocked)),
+		targetX:     toX,
+		targetY:     toY,
+	}
+	for i := range ret.distance {
+		ret.distance[i] = math.Inf(1)
+	}
+	passable := func(i int) bool { return !g.blocked[i] }
+
+	// Dijkstra out from the target. Every step can be reversed, so this is also the distance to the target.
+	ret.distance[goal] = 0
+	queue := &navQueue{{cell: goal}}
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(navQueueItem)
+		if item.priority > ret.distance[item.cell] {
+			continue
+		}
+		g.forNeighbors(item.cell, passable, func(n int, stepCost float64) {
+			newDistance := item.priority + stepCost
+			if newDistance < ret.distance[n] {
+				ret.distance[n] = newDistance
+				heap.Push(queue, navQueueItem{cell: n, priority: newDistance})
+			}
+		})
+	}
+	return ret
+}
+
+// How far it is to walk from (x, y) to the target. Inf if it can't be reached.
+func (f *FlowField) Distance(x, y float64) float64 {
+	i, ok := f.index(x, y)
+	if !ok {
+		return math.Inf(1)
+	}
+	return f.distance[i]
+}
+
+// The unit direction to move from (x, y). In the target's cell it points at the target itself, and it is zero on top of it.
+// ok is false if the target can't be reached from there.
+func (f *FlowField) Direction(x, y float64) (dirX, dirY float64, ok bool) {
+	i, ok := f.index(x, y)
+	if !ok || math.IsInf(f.distance[i], 1) {
+		return 0, 0, false
+	}
+
+	toX, toY := f.targetX, f.targetY
+	if f.distance[i] > 0 {
+		best, bestDistance := i, f.distance[i]
+		reachable := func(n int) bool { return !math.IsInf(f.distance[n], 1) }
+		f.forNeighbors(i, reachable, func(n int, _ float64) {
+			if f.distance[n] < bestDistance {
+				best, bestDistance = n, f.distance[n]
+			}
+		})
+		toX, toY = f.cellCenter(best)
+	}
+
+	dx, dy := toX-x, toY-y
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return 0, 0, true
+	}
+	return dx / length, dy / length, true
+}
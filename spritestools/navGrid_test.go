@@ -0,0 +1,124 @@
+package spritestools
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/stretchr/testify/require"
+)
+
+// 10 by 10 cells of size 10 from (0, 0) to (100, 100), with a wall at x 50 from the bottom up to y 80.
+func newWallGrid() *NavGrid {
+	g := NewNavGrid(spritesmodels.Rect{MinX: 0, MinY: 0, MaxX: 99, MaxY: 99}, 10)
+	g.BlockRect(spritesmodels.Rect{MinX: 50, MinY: 0, MaxX: 55, MaxY: 75})
+	return g
+}
+
+func TestNavGrid_basics(t *testing.T) {
+	g := newWallGrid()
+	require.Equal(t, spritesmodels.Rect{MinX: 0, MinY: 0, MaxX: 100, MaxY: 100}, g.Bounds())
+	require.True(t, g.IsBlocked(55, 5))
+	require.True(t, g.IsBlocked(55, 75))
+	require.False(t, g.IsBlocked(55, 85))
+	require.False(t, g.IsBlocked(45, 5))
+	require.True(t, g.IsBlocked(-1, 5)) // outside
+
+	g.SetBlocked(55, 5, false)
+	require.False(t, g.IsBlocked(55, 5))
+
+	// A thin diagonal line doesn't cover any cell centers, but still blocks the cells it crosses.
+	g = NewNavGrid(spritesmodels.Rect{MinX: 0, MinY: 0, MaxX: 99, MaxY: 99}, 10)
+	g.BlockPolygon([]spritesmodels.Point{{X: 1, Y: 1}, {X: 99, Y: 99}, {X: 99, Y: 98}})
+	require.True(t, g.IsBlocked(55, 55))
+	require.False(t, g.IsBlocked(55, 25))
+
+	g = NewNavGrid(spritesmodels.Rect{MinX: -100, MinY: -100, MaxX: 99, MaxY: 99}, 10)
+	body := NewTouchCollisionBody()
+	body.AddCircleBody(0, 0, 30)
+	body.Pos(-50, -50)
+	g.BlockBody(body)
+	require.True(t, g.IsBlocked(-50, -50))
+	require.True(t, g.IsBlocked(-75, -50))
+	require.False(t, g.IsBlocked(-85, -85))
+	require.False(t, g.IsBlocked(50, 50))
+}
+
+func pathLength(fromX, fromY float64, path []spritesmodels.Point) float64 {
+	ret := 0.0
+	for _, p := range path {
+		ret += math.Hypot(p.X-fromX, p.Y-fromY)
+		fromX, fromY = p.X, p.Y
+	}
+	return ret
+}
+
+func TestNavGrid_FindPath(t *testing.T) {
+	g := newWallGrid()
+
+	path, ok := g.FindPath(15, 15, 85, 15)
+	require.True(t, ok)
+	require.Equal(t, spritesmodels.Point{X: 85, Y: 15}, path[len(path)-1])
+	// Over the top of the wall and back down.
+	maxY := 0.0
+	for _, p := range path {
+		require.False(t, g.IsBlocked(p.X, p.Y))
+		maxY = max(maxY, p.Y)
+	}
+	require.Equal(t, 85.0, maxY)
+	require.Less(t, pathLength(15, 15, path), 70+2*70.0)
+
+	// A straight shot is just the target.
+	path, ok = g.FindPath(15, 15, 15, 85)
+	require.True(t, ok)
+	require.Equal(t, []spritesmodels.Point{{X: 15, Y: 85}}, path)
+
+	// Blocked or outside target
+	_, ok = g.FindPath(15, 15, 55, 15)
+	require.False(t, ok)
+	_, ok = g.FindPath(15, 15, 150, 15)
+	require.False(t, ok)
+
+	// Walled in completely
+	g.BlockRect(spritesmodels.Rect{MinX: 50, MinY: 80, MaxX: 55, MaxY: 99})
+	_, ok = g.FindPath(15, 15, 85, 15)
+	require.False(t, ok)
+
+	// Starting on a blocked cell is allowed.
+	path, ok = g.FindPath(55, 15, 85, 15)
+	require.True(t, ok)
+	require.Equal(t, []spritesmodels.Point{{X: 85, Y: 15}}, path)
+}
+
+func TestNavGrid_FlowField(t *testing.T) {
+	g := newWallGrid()
+	require.Nil(t, g.FlowField(55, 15))
+
+	f := g.FlowField(85, 15)
+	require.NotNil(t, f)
+	require.Equal(t, 0.0, f.Distance(85, 15))
+	require.True(t, math.IsInf(f.Distance(55, 15), 1))
+
+	// Left of the wall the way is up.
+	dx, dy, ok := f.Direction(45, 15)
+	require.True(t, ok)
+	require.InDelta(t, 0, dx, 1e-9)
+	require.InDelta(t, 1, dy, 1e-9)
+
+	// Following the field gets to the target.
+	x, y := 15.0, 15.0
+	for i := 0; i < 1000 && math.Hypot(85-x, 15-y) > 1; i++ {
+		dx, dy, ok := f.Direction(x, y)
+		require.True(t, ok)
+		x, y = x+dx, y+dy
+		require.False(t, g.IsBlocked(x, y))
+	}
+	require.InDelta(t, 85, x, 1)
+	require.InDelta(t, 15, y, 1)
+
+	// The field doesn't change with the grid.
+	g.BlockRect(spritesmodels.Rect{MinX: 50, MinY: 80, MaxX: 55, MaxY: 99})
+	_, _, ok = f.Direction(15, 15)
+	require.True(t, ok)
+	require.True(t, math.IsInf(g.FlowField(85, 15).Distance(15, 15), 1))
+}
@@ -2,48 +2,121 @@ package spritestools
 
 import (
 	"log"
-	"math/rand"
+	"math"
 	"sync"
 
 	"github.com/gary23b/sprites/spritesmodels"
 )
 
-type gridBlock struct {
+const (
+	DefaultPositionGridCellSize = 20.0
+
+	brokerShardCount = 64 // Cells are spread over shards so that sprites in different places don't wait on each other.
+	maxCellIndex     = math.MaxInt32
+)
+
+type cellKey struct {
+	x, y int
+}
+
+// Packed into one integer since those are much faster map keys.
+func (k cellKey) packed() uint64 {
+	return uint64(uint32(int32(k.x)))<<32 | uint64(uint32(int32(k.y)))
+}
+
+type brokerCell struct {
 	sprites map[int]spritesmodels.NearMeInfo
-	mutex   sync.RWMutex
+}
+
+type brokerShard struct {
+	mutex sync.RWMutex
+	cells map[uint64]*brokerCell // Only cells with sprites in them exist
 }
 
 type brokerPosInfo struct {
 	state spritesmodels.SpriteState
-	yGrid int
-	xGrid int
-	mutex sync.RWMutex
+	key   cellKey
+	cell  *brokerCell // nil until the first update gives the sprite a position
+	mutex sync.Mutex
 }
 
+// A sparse hash grid of sprite positions. Memory grows with the number of occupied cells, not the size of the world,
+// and there are no limits on the coordinates.
 type PositionBroker struct {
-	sprites map[int]*brokerPosInfo
-	mutex   sync.RWMutex
+	cellSize float64
+	sprites  map[int]*brokerPosInfo
+	mutex    sync.RWMutex // Protects sprites. Each brokerPosInfo has its own lock for its contents.
 
-	grid [][]gridBlock
+	shards [brokerShardCount]brokerShard
 }
 
 func NewPositionBroker() *PositionBroker {
+	return NewPositionBrokerWithCellSize(DefaultPositionGridCellSize)
+}
+
+// Queries are fastest when the cell size is close to the distances that are usually asked about.
+func NewPositionBrokerWithCellSize(cellSize float64) *PositionBroker {
+	if !(cellSize > 0) {
+		log.Printf("Invalid position grid cell size: %f, using %f\n", cellSize, DefaultPositionGridCellSize)
+		cellSize = DefaultPositionGridCellSize
+	}
+
 	ret := &PositionBroker{
-		sprites: make(map[int]*brokerPosInfo),
-		grid:    make([][]gridBlock, 1000),
+		cellSize: cellSize,
+		sprites:  make(map[int]*brokerPosInfo),
+	}
+	for i := range ret.shards {
+		ret.shards[i].cells = make(map[uint64]*brokerCell)
+	}
+	return ret
+}
+
+func (s *PositionBroker) CellSize() float64 {
+	return s.cellSize
+}
+
+func (s *PositionBroker) cellIndex(v float64) int {
+	if math.IsNaN(v) {
+		return 0
 	}
+	return int(max(-maxCellIndex, min(maxCellIndex, math.Floor(v/s.cellSize))))
+}
 
-	for y := range ret.grid {
-		ret.grid[y] = make([]gridBlock, 1000)
-		for x := range ret.grid[y] {
-			g := &ret.grid[y][x]
-			g.sprites = make(map[int]spritesmodels.NearMeInfo)
-		}
+func (s *PositionBroker) cellFor(x, y float64) cellKey {
+	return cellKey{x: s.cellIndex(x), y: s.cellIndex(y)}
+}
+
+func (s *PositionBroker) shardFor(packedKey uint64) *brokerShard {
+	h := packedKey * 0x9E3779B97F4A7C15
+	return &s.shards[(h>>32)%brokerShardCount]
+}
+
+func (s *PositionBroker) putInCell(key cellKey, info spritesmodels.NearMeInfo) *brokerCell {
+	packedKey := key.packed()
+	shard := s.shardFor(packedKey)
+	shard.mutex.Lock()
+	cell, ok := shard.cells[packedKey]
+	if !ok {
+		cell = &brokerCell{sprites: make(map[int]spritesmodels.NearMeInfo)}
+		shard.cells[packedKey] = cell
 	}
+	cell.sprites[info.SpriteID] = info
+	shard.mutex.Unlock()
+	return cell
+}
 
-	return ret
+func (s *PositionBroker) removeFromCell(key cellKey, cell *brokerCell, id int) {
+	packedKey := key.packed()
+	shard := s.shardFor(packedKey)
+	shard.mutex.Lock()
+	delete(cell.sprites, id)
+	if len(cell.sprites) == 0 {
+		delete(shard.cells, packedKey)
+	}
+	shard.mutex.Unlock()
 }
 
+// The sprite isn't in any cell until its first UpdateSpriteInfo.
 func (s *PositionBroker) AddSprite(id int) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
@@ -54,22 +127,7 @@ func (s *PositionBroker) AddSprite(id int) {
 		return
 	}
 
-	x := rand.Intn(1000)
-	y := rand.Intn(1000)
-	s.sprites[id] = &brokerPosInfo{
-		xGrid: x,
-		yGrid: y,
-	}
-
-	g := &s.grid[y][x]
-	g.mutex.Lock()
-	g.sprites[id] = spritesmodels.NearMeInfo{
-		SpriteID:   id,
-		SpriteType: 0,
-		X:          float64(x-500) * 20,
-		Y:          float64(y-500) * 20,
-	}
-	g.mutex.Unlock()
+	s.sprites[id] = &brokerPosInfo{}
 }
 
 func (s *PositionBroker) RemoveSprite(id int) {
@@ -80,10 +138,11 @@ func (s *PositionBroker) RemoveSprite(id int) {
 		return
 	}
 
-	g := &s.grid[item.yGrid][item.xGrid]
-	g.mutex.Lock()
-	delete(g.sprites, id)
-	g.mutex.Unlock()
+	item.mutex.Lock()
+	if item.cell != nil {
+		s.removeFromCell(item.key, item.cell, id)
+	}
+	item.mutex.Unlock()
 
 	delete(s.sprites, id)
 }
@@ -99,30 +158,29 @@ func (s *PositionBroker) UpdateSpriteInfo(id int, state spritesmodels.SpriteStat
 	item.mutex.Lock()
 	defer item.mutex.Unlock()
 
-	x := max(0, min(999, int(state.X/20+500)))
-	y := max(0, min(999, int(state.Y/20+500)))
-
-	if x != item.xGrid || y != item.yGrid {
-		g := &s.grid[item.yGrid][item.xGrid]
-		g.mutex.Lock()
-		delete(g.sprites, id)
-		g.mutex.Unlock()
-
-		item.xGrid = x
-		item.yGrid = y
-	}
-
-	g := &s.grid[y][x]
-	g.mutex.Lock()
-	g.sprites[id] = spritesmodels.NearMeInfo{
+	info := spritesmodels.NearMeInfo{
 		SpriteID:   id,
 		SpriteType: state.SpriteType,
 		X:          state.X,
 		Y:          state.Y,
 	}
-	g.mutex.Unlock()
-
 	item.state = state
+
+	key := s.cellFor(state.X, state.Y)
+	if item.cell != nil && key == item.key {
+		// Still in the same cell, which is by far the most common case.
+		shard := s.shardFor(key.packed())
+		shard.mutex.Lock()
+		item.cell.sprites[id] = info
+		shard.mutex.Unlock()
+		return
+	}
+
+	if item.cell != nil {
+		s.removeFromCell(item.key, item.cell, id)
+	}
+	item.cell = s.putInCell(key, info)
+	item.key = key
 }
 
 func (s *PositionBroker) GetSpriteInfo(id int) spritesmodels.SpriteState {
@@ -138,23 +196,82 @@ func (s *PositionBroker) GetSpriteInfo(id int) spritesmodels.SpriteState {
 	return item.state
 }
 
+// Every sprite in the cells that overlap the square around (x, y). Some may be a little further away than distance.
 func (s *PositionBroker) GetSpritesNearMe(x, y, distance float64) []spritesmodels.NearMeInfo {
-	xMin := max(0, min(999, int((x-distance)/20+500)))
-	yMin := max(0, min(999, int((y-distance)/20+500)))
-	xMax := max(0, min(999, int((x+distance)/20+500)))
-	yMax := max(0, min(999, int((y+distance)/20+500)))
+	minCell := s.cellFor(x-distance, y-distance)
+	maxCell := s.cellFor(x+distance, y+distance)
+
+	return s.spritesInCells(minCell, maxCell)
+}
 
+// With a huge area there can be far more cells to look at than there are occupied cells.
+func (s *PositionBroker) spritesInCells(minCell, maxCell cellKey) []spritesmodels.NearMeInfo {
 	ret := []spritesmodels.NearMeInfo{}
+	cellCount := float64(maxCell.x-minCell.x+1) * float64(maxCell.y-minCell.y+1)
+	if cellCount > brokerShardCount && cellCount > float64(s.occupiedCellCount()) {
+		return s.filterOccupiedCells(minCell, maxCell, ret)
+	}
+	return s.scanCells(minCell, maxCell, ret)
+}
+
+func (s *PositionBroker) occupiedCellCount() int {
+	count := 0
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mutex.RLock()
+		count += len(shard.cells)
+		shard.mutex.RUnlock()
+	}
+	return count
+}
 
-	for y := yMin; y <= yMax; y++ {
-		for x := xMin; x <= xMax; x++ {
-			g := &s.grid[y][x]
-			g.mutex.RLock()
-			for _, sprite := range g.sprites {
+func (s *PositionBroker) filterOccupiedCells(minCell, maxCell cellKey, ret []spritesmodels.NearMeInfo) []spritesmodels.NearMeInfo {
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mutex.RLock()
+		for packedKey, cell := range shard.cells {
+			x, y := int(int32(packedKey>>32)), int(int32(packedKey))
+			if x < minCell.x || x > maxCell.x || y < minCell.y || y > maxCell.y {
+				continue
+			}
+			for _, sprite := range cell.sprites {
 				ret = append(ret, sprite)
 			}
-			g.mutex.RUnlock()
 		}
+		shard.mutex.RUnlock()
+	}
+	return ret
+}
+
+func (s *PositionBroker) scanCells(minCell, maxCell cellKey, ret []spritesmodels.NearMeInfo) []spritesmodels.NearMeInfo {
+	for y := minCell.y; y <= maxCell.y; y++ {
+		for x := minCell.x; x <= maxCell.x; x++ {
+			packedKey := cellKey{x: x, y: y}.packed()
+			shard := s.shardFor(packedKey)
+			shard.mutex.RLock()
+			if cell, ok := shard.cells[packedKey]; ok {
+				for _, sprite := range cell.sprites {
+					ret = append(ret, sprite)
+				}
+			}
+			shard.mutex.RUnlock()
+		}
+	}
+	return ret
+}
+
+// The world area of every grid cell that has at least one sprite in it.
+func (s *PositionBroker) OccupiedCells() []spritesmodels.Rect {
+	ret := []spritesmodels.Rect{}
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mutex.RLock()
+		for packedKey := range shard.cells {
+			x := float64(int32(packedKey>>32)) * s.cellSize
+			y := float64(int32(packedKey)) * s.cellSize
+			ret = append(ret, spritesmodels.Rect{MinX: x, MinY: y, MaxX: x + s.cellSize, MaxY: y + s.cellSize})
+		}
+		shard.mutex.RUnlock()
 	}
 	return ret
 }
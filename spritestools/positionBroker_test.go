@@ -0,0 +1,155 @@
+package spritestools
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/stretchr/testify/require"
+)
+
+func nearIDs(in []spritesmodels.NearMeInfo) []int {
+	ret := make([]int, len(in))
+	for i := range in {
+		ret[i] = in[i].SpriteID
+	}
+	sort.Ints(ret)
+	return ret
+}
+
+func TestPositionBroker(t *testing.T) {
+	b := NewPositionBroker()
+	b.AddSprite(1)
+	b.AddSprite(2)
+	b.AddSprite(3)
+
+	// Not placed until the first update
+	require.Empty(t, b.GetSpritesNearMe(0, 0, 1e9))
+
+	b.UpdateSpriteInfo(1, spritesmodels.SpriteState{SpriteID: 1, X: 5, Y: 5})
+	b.UpdateSpriteInfo(2, spritesmodels.SpriteState{SpriteID: 2, X: 100, Y: -100})
+	// Far outside the old +-10000 limit
+	b.UpdateSpriteInfo(3, spritesmodels.SpriteState{SpriteID: 3, X: 1e7, Y: -3e7, SpriteType: 4})
+
+	require.Equal(t, []int{1}, nearIDs(b.GetSpritesNearMe(0, 0, 10)))
+	require.Equal(t, []int{1, 2}, nearIDs(b.GetSpritesNearMe(0, 0, 200)))
+	require.Equal(t, []int{1, 2, 3}, nearIDs(b.GetSpritesNearMe(0, 0, 1e9)))
+
+	far := b.GetSpritesNearMe(1e7, -3e7, 1)
+	require.Len(t, far, 1)
+	require.Equal(t, 4, far[0].SpriteType)
+	require.Equal(t, 1e7, b.GetSpriteInfo(3).X)
+
+	// Moving takes the sprite out of its old cell
+	b.UpdateSpriteInfo(1, spritesmodels.SpriteState{SpriteID: 1, X: 100, Y: -95})
+	require.Empty(t, b.GetSpritesNearMe(0, 0, 10))
+	require.Equal(t, []int{1, 2}, nearIDs(b.GetSpritesNearMe(100, -100, 10)))
+	require.Len(t, b.OccupiedCells(), 2)
+
+	b.RemoveSprite(2)
+	require.Equal(t, []int{1}, nearIDs(b.GetSpritesNearMe(100, -100, 10)))
+	require.True(t, b.GetSpriteInfo(2).Deleted)
+}
+
+func TestPositionBroker_cellSize(t *testing.T) {
+	b := NewPositionBrokerWithCellSize(100)
+	require.Equal(t, 100.0, b.CellSize())
+	b.AddSprite(1)
+	b.UpdateSpriteInfo(1, spritesmodels.SpriteState{X: -1, Y: 250})
+	require.Equal(t, []spritesmodels.Rect{{MinX: -100, MinY: 200, MaxX: 0, MaxY: 300}}, b.OccupiedCells())
+
+	require.Equal(t, DefaultPositionGridCellSize, NewPositionBrokerWithCellSize(0).CellSize())
+}
+
+////////////////////////////////////////////////////////////////////////////////////////
+
+// The part of the PositionBroker API used by the sim, so the old and new implementations can share benchmarks.
+type positionIndex interface {
+	AddSprite(id int)
+	UpdateSpriteInfo(id int, state spritesmodels.SpriteState)
+	GetSpritesNearMe(x, y, distance float64) []spritesmodels.NearMeInfo
+}
+
+var brokerBenchmarkSizes = []int{10000, 100000}
+
+func benchmarkBrokers(b *testing.B, run func(b *testing.B, broker positionIndex, n int)) {
+	for _, n := range brokerBenchmarkSizes {
+		b.Run(fmt.Sprintf("hashgrid/%d", n), func(b *testing.B) {
+			run(b, NewPositionBroker(), n)
+		})
+		b.Run(fmt.Sprintf("legacy/%d", n), func(b *testing.B) {
+			run(b, newLegacyPositionBroker(), n)
+		})
+	}
+}
+
+// n sprites spread over a 4000 by 4000 world
+func fillBroker(broker positionIndex, n int) []spritesmodels.SpriteState {
+	r := rand.New(rand.NewSource(1))
+	states := make([]spritesmodels.SpriteState, n)
+	for i := range states {
+		states[i] = spritesmodels.SpriteState{SpriteID: i, X: r.Float64()*4000 - 2000, Y: r.Float64()*4000 - 2000}
+		broker.AddSprite(i)
+		broker.UpdateSpriteInfo(i, states[i])
+	}
+	return states
+}
+
+func BenchmarkPositionBroker_New(b *testing.B) {
+	b.Run("hashgrid", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			NewPositionBroker()
+		}
+	})
+	b.Run("legacy", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			newLegacyPositionBroker()
+		}
+	})
+}
+
+// Every sprite takes a small step, like a frame of a busy simulation.
+func BenchmarkPositionBroker_MoveAll(b *testing.B) {
+	benchmarkBrokers(b, func(b *testing.B, broker positionIndex, n int) {
+		states := fillBroker(broker, n)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for j := range states {
+				states[j].X += 3
+				states[j].Y -= 2
+				broker.UpdateSpriteInfo(j, states[j])
+			}
+		}
+	})
+}
+
+// Many go routines moving their own sprites at the same time, which is how the sim uses the broker.
+func BenchmarkPositionBroker_MoveParallel(b *testing.B) {
+	benchmarkBrokers(b, func(b *testing.B, broker positionIndex, n int) {
+		states := fillBroker(broker, n)
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			r := rand.New(rand.NewSource(rand.Int63()))
+			for pb.Next() {
+				id := r.Intn(n)
+				state := states[id]
+				state.X += r.Float64()*10 - 5
+				state.Y += r.Float64()*10 - 5
+				broker.UpdateSpriteInfo(id, state)
+			}
+		})
+	})
+}
+
+func BenchmarkPositionBroker_Near(b *testing.B) {
+	benchmarkBrokers(b, func(b *testing.B, broker positionIndex, n int) {
+		states := fillBroker(broker, n)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			s := states[i%n]
+			broker.GetSpritesNearMe(s.X, s.Y, 50)
+		}
+	})
+}
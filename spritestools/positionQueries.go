@@ -0,0 +1,150 @@
+package spritestools
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"github.com/gary23b/sprites/spritesmodels"
+)
+
+func nearMeMatches(filter spritesmodels.NearMeFilter, info spritesmodels.NearMeInfo) bool {
+	if slices.Contains(filter.ExcludeIDs, info.SpriteID) {
+		return false
+	}
+	if len(filter.SpriteTypes) > 0 && !slices.Contains(filter.SpriteTypes, info.SpriteType) {
+		return false
+	}
+	return true
+}
+
+// Nearest first. Ties go to the lower ID so results don't change from call to call.
+func sortByDistance(in []spritesmodels.NearMeInfo) {
+	slices.SortFunc(in, func(a, b spritesmodels.NearMeInfo) int {
+		if c := cmp.Compare(a.Distance, b.Distance); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.SpriteID, b.SpriteID)
+	})
+}
+
+func applyLimit[T any](in []T, limit int) []T {
+	if limit > 0 && len(in) > limit {
+		return in[:limit]
+	}
+	return in
+}
+
+// Every sprite within distance of (x, y), nearest first.
+func (s *PositionBroker) GetSpritesWithin(x, y, distance float64, filter spritesmodels.NearMeFilter) []spritesmodels.NearMeInfo {
+	ret := []spritesmodels.NearMeInfo{}
+	for _, info := range s.GetSpritesNearMe(x, y, distance) {
+		info.Distance = math.Hypot(info.X-x, info.Y-y)
+		if info.Distance <= distance && nearMeMatches(filter, info) {
+			ret = append(ret, info)
+		}
+	}
+	sortByDistance(ret)
+	return applyLimit(ret, filter.Limit)
+}
+
+// The k sprites nearest to (x, y), no matter how far away they are. filter.Limit is ignored.
+func (s *PositionBroker) GetNearestSprites(x, y float64, k int, filter spritesmodels.NearMeFilter) []spritesmodels.NearMeInfo {
+	if k <= 0 {
+		return []spritesmodels.NearMeInfo{}
+	}
+	filter.Limit = k
+
+	// Grow the search circle until it holds k sprites. Anything outside the circle is further than anything inside.
+	for distance := s.cellSize; ; distance *= 2 {
+		cells := (2*distance/s.cellSize + 1) * (2*distance/s.cellSize + 1)
+		if cells > float64(s.occupiedCellCount()) {
+			// Checking every occupied cell is now cheaper, and it is guaranteed to find everything.
+			return s.GetSpritesWithin(x, y, math.Inf(1), filter)
+		}
+		ret := s.GetSpritesWithin(x, y, distance, filter)
+		if len(ret) == k {
+			return ret
+		}
+	}
+}
+
+// Every sprite with its position inside rect. Distance is from the center of rect, and the results are nearest first.
+func (s *PositionBroker) GetSpritesInRect(rect spritesmodels.Rect, filter spritesmodels.NearMeFilter) []spritesmodels.NearMeInfo {
+	centerX, centerY := (rect.MinX+rect.MaxX)/2, (rect.MinY+rect.MaxY)/2
+	ret := []spritesmodels.NearMeInfo{}
+	for _, info := range s.spritesInCells(s.cellFor(rect.MinX, rect.MinY), s.cellFor(rect.MaxX, rect.MaxY)) {
+		if info.X < rect.MinX || info.X > rect.MaxX || info.Y < rect.MinY || info.Y > rect.MaxY {
+			continue
+		}
+		if !nearMeMatches(filter, info) {
+			continue
+		}
+		info.Distance = math.Hypot(info.X-centerX, info.Y-centerY)
+		ret = append(ret, info)
+	}
+	sortByDistance(ret)
+	return applyLimit(ret, filter.Limit)
+}
+
+// Every sprite within width of the line from (x1, y1) to (x2, y2), in order along the line.
+// Distance is how far along the line the sprite is.
+func (s *PositionBroker) GetSpritesAlongLine(x1, y1, x2, y2, width float64, filter spritesmodels.NearMeFilter) []spritesmodels.NearMeInfo {
+	length := math.Hypot(x2-x1, y2-y1)
+	dirX, dirY := 0.0, 0.0
+	if length > 0 {
+		dirX, dirY = (x2-x1)/length, (y2-y1)/length
+	}
+	return s.spritesAlongSegment(x1, y1, dirX, dirY, length, width, filter)
+}
+
+// Every sprite within width of the ray, in order along it. The direction doesn't need to be normalized.
+// maxDistance can be math.Inf(1).
+func (s *PositionBroker) GetSpritesAlongRay(x, y, dirX, dirY, maxDistance, width float64, filter spritesmodels.NearMeFilter) []spritesmodels.NearMeInfo {
+	length := math.Hypot(dirX, dirY)
+	if length == 0 || !(maxDistance >= 0) {
+		return []spritesmodels.NearMeInfo{}
+	}
+	return s.spritesAlongSegment(x, y, dirX/length, dirY/length, maxDistance, width, filter)
+}
+
+// The direction is normalized, or zero for a single point. length may be infinite, so the far end is never worked
+// out from it directly.
+func (s *PositionBroker) spritesAlongSegment(x, y, dirX, dirY, length, width float64, filter spritesmodels.NearMeFilter) []spritesmodels.NearMeInfo {
+	ret := []spritesmodels.NearMeInfo{}
+	if math.IsNaN(length) || math.IsNaN(width) {
+		return ret
+	}
+	end := func(start, dir float64) float64 {
+		if dir == 0 {
+			return start // Inf times 0 would be NaN
+		}
+		return start + dir*length
+	}
+	endX, endY := end(x, dirX), end(y, dirY)
+	minCell := s.cellFor(min(x, endX)-width, min(y, endY)-width)
+	maxCell := s.cellFor(max(x, endX)+width, max(y, endY)+width)
+
+	for _, info := range s.spritesInCells(minCell, maxCell) {
+		if !nearMeMatches(filter, info) {
+			continue
+		}
+		// The closest point on the line to the sprite
+		along := max(0, min(length, (info.X-x)*dirX+(info.Y-y)*dirY))
+		closestX, closestY := x+dirX*along, y+dirY*along
+		if math.Hypot(info.X-closestX, info.Y-closestY) > width {
+			continue
+		}
+		info.Distance = along
+		ret = append(ret, info)
+	}
+	sortByDistance(ret)
+	return applyLimit(ret, filter.Limit)
+}
+
+// True if no sprite matching filter is within width of the line between the two points.
+// Exclude the looker and the target with filter.ExcludeIDs.
+func (s *PositionBroker) HasLineOfSight(x1, y1, x2, y2, width float64, filter spritesmodels.NearMeFilter) bool {
+	filter.Limit = 1
+	return len(s.GetSpritesAlongLine(x1, y1, x2, y2, width, filter)) == 0
+}
@@ -0,0 +1,93 @@
+package spritestools
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/stretchr/testify/require"
+)
+
+// Sprites 1 through 5 in a row along the x axis, 10 apart, with sprite i having type i%2
+func newRowBroker() *PositionBroker {
+	b := NewPositionBroker()
+	for i := 1; i <= 5; i++ {
+		b.AddSprite(i)
+		b.UpdateSpriteInfo(i, spritesmodels.SpriteState{SpriteID: i, SpriteType: i % 2, X: float64(i * 10)})
+	}
+	return b
+}
+
+func TestPositionBroker_GetSpritesWithin(t *testing.T) {
+	b := newRowBroker()
+
+	got := b.GetSpritesWithin(30, 0, 10, spritesmodels.NearMeFilter{})
+	require.Equal(t, []int{3, 2, 4}, idsInOrder(got))
+	require.Equal(t, 0.0, got[0].Distance)
+	require.Equal(t, 10.0, got[1].Distance)
+
+	// The square of cells would include sprites 2 and 4, but they are just outside the circle
+	require.Equal(t, []int{3}, idsInOrder(b.GetSpritesWithin(30, 0, 9.9, spritesmodels.NearMeFilter{})))
+
+	got = b.GetSpritesWithin(30, 0, 100, spritesmodels.NearMeFilter{ExcludeIDs: []int{3}, SpriteTypes: []int{1}})
+	require.Equal(t, []int{1, 5}, idsInOrder(got))
+
+	got = b.GetSpritesWithin(0, 0, 100, spritesmodels.NearMeFilter{Limit: 2})
+	require.Equal(t, []int{1, 2}, idsInOrder(got))
+}
+
+func TestPositionBroker_GetNearestSprites(t *testing.T) {
+	b := newRowBroker()
+	b.AddSprite(6)
+	b.UpdateSpriteInfo(6, spritesmodels.SpriteState{X: 1e6, Y: 1e6})
+
+	require.Equal(t, []int{5, 4}, idsInOrder(b.GetNearestSprites(100, 0, 2, spritesmodels.NearMeFilter{})))
+	require.Equal(t, []int{6}, idsInOrder(b.GetNearestSprites(0, 0, 1, spritesmodels.NearMeFilter{SpriteTypes: []int{0}, ExcludeIDs: []int{2, 4}})))
+	// Asking for more than there are
+	require.Len(t, b.GetNearestSprites(0, 0, 100, spritesmodels.NearMeFilter{}), 6)
+	require.Empty(t, b.GetNearestSprites(0, 0, 0, spritesmodels.NearMeFilter{}))
+}
+
+func TestPositionBroker_GetSpritesInRect(t *testing.T) {
+	b := newRowBroker()
+	got := b.GetSpritesInRect(spritesmodels.Rect{MinX: 15, MinY: -1, MaxX: 40, MaxY: 1}, spritesmodels.NearMeFilter{})
+	require.Equal(t, []int{3, 2, 4}, idsInOrder(got))
+}
+
+func TestPositionBroker_lines(t *testing.T) {
+	b := newRowBroker()
+	b.AddSprite(6)
+	b.UpdateSpriteInfo(6, spritesmodels.SpriteState{SpriteID: 6, X: 30, Y: 8})
+
+	got := b.GetSpritesAlongRay(0, 1, 1, 0, 35, 2, spritesmodels.NearMeFilter{})
+	require.Equal(t, []int{1, 2, 3}, idsInOrder(got))
+	require.InDelta(t, 20, got[1].Distance, 1e-9)
+
+	got = b.GetSpritesAlongLine(30, 0, 30, 100, 1, spritesmodels.NearMeFilter{})
+	require.Equal(t, []int{3, 6}, idsInOrder(got))
+
+	require.False(t, b.HasLineOfSight(10, 0, 50, 0, 1, spritesmodels.NearMeFilter{ExcludeIDs: []int{1, 5}}))
+	require.True(t, b.HasLineOfSight(10, 0, 50, 0, 1, spritesmodels.NearMeFilter{ExcludeIDs: []int{1, 5}, SpriteTypes: []int{7}}))
+	require.True(t, b.HasLineOfSight(10, 20, 50, 20, 1, spritesmodels.NearMeFilter{}))
+
+	require.Empty(t, b.GetSpritesAlongRay(0, 0, 0, 0, 100, 1, spritesmodels.NearMeFilter{}))
+
+	// An endless ray only finds what is along it, at real distances.
+	got = b.GetSpritesAlongRay(0, 1, 1, 0, math.Inf(1), 2, spritesmodels.NearMeFilter{})
+	require.Equal(t, []int{1, 2, 3, 4, 5}, idsInOrder(got))
+	require.InDelta(t, 50, got[4].Distance, 1e-9)
+	got = b.GetSpritesAlongRay(30, -100, 0, 5, math.Inf(1), 1, spritesmodels.NearMeFilter{})
+	require.Equal(t, []int{3, 6}, idsInOrder(got))
+	require.InDelta(t, 108, got[1].Distance, 1e-9)
+	require.Empty(t, b.GetSpritesAlongRay(0, 0, -1, 0, math.Inf(1), 2, spritesmodels.NearMeFilter{}))
+	require.Empty(t, b.GetSpritesAlongRay(0, 0, 1, 0, math.NaN(), 2, spritesmodels.NearMeFilter{}))
+	require.Empty(t, b.GetSpritesAlongRay(0, 0, 1, 0, -1, 2, spritesmodels.NearMeFilter{}))
+}
+
+func idsInOrder(in []spritesmodels.NearMeInfo) []int {
+	ret := make([]int, len(in))
+	for i := range in {
+		ret[i] = in[i].SpriteID
+	}
+	return ret
+}
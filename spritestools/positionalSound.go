@@ -0,0 +1,26 @@
+package spritestools
+
+import "math"
+
+// Computes the left and right channel gains for a sound played at (x, y) in Cartesian coordinates
+// where the listener is at (listenerX, listenerY).
+// The sound is panned fully to one side once it is half a screen width away horizontally,
+// and it fades out linearly until it is silent at hearingDistance.
+func PositionalGains(x, y, listenerX, listenerY, screenWidth, hearingDistance float64) (left, right float64) {
+	dx := x - listenerX
+	dy := y - listenerY
+
+	attenuation := 1.0
+	if hearingDistance > 0 {
+		attenuation = Lerp(1, 0, math.Sqrt(dx*dx+dy*dy)/hearingDistance)
+	}
+
+	pan := 0.0
+	if screenWidth > 0 {
+		pan = max(-1, min(1, dx/(screenWidth/2)))
+	}
+
+	left = min(1, 1-pan) * attenuation
+	right = min(1, 1+pan) * attenuation
+	return left, right
+}
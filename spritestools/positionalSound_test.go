@@ -0,0 +1,34 @@
+package spritestools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPositionalGains(t *testing.T) {
+	// Centered
+	left, right := PositionalGains(0, 0, 0, 0, 1000, 1000)
+	require.InDelta(t, 1, left, 1e-9)
+	require.InDelta(t, 1, right, 1e-9)
+
+	// Halfway to the right edge
+	left, right = PositionalGains(250, 0, 0, 0, 1000, 1000)
+	require.InDelta(t, .5*.75, left, 1e-9)
+	require.InDelta(t, .75, right, 1e-9)
+
+	// Past the left edge the pan is capped
+	left, right = PositionalGains(-600, 0, 0, 0, 1000, 1000)
+	require.InDelta(t, .4, left, 1e-9)
+	require.InDelta(t, 0, right, 1e-9)
+
+	// Out of hearing distance
+	left, right = PositionalGains(0, 2000, 0, 0, 1000, 1000)
+	require.InDelta(t, 0, left, 1e-9)
+	require.InDelta(t, 0, right, 1e-9)
+
+	// The listener can move
+	left, right = PositionalGains(100, 100, 100, 100, 1000, 1000)
+	require.InDelta(t, 1, left, 1e-9)
+	require.InDelta(t, 1, right, 1e-9)
+}
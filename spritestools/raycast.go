@@ -0,0 +1,115 @@
+package spritestools
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"github.com/gary23b/sprites/spritesmodels"
+)
+
+// Tests the ray against one sprite's body. The hit's SpriteID and SpriteType are filled in by the caller.
+type RayTester func(info spritesmodels.NearMeInfo) (spritesmodels.RayHit, bool)
+
+// Nearest first. Ties go to the lower ID.
+func sortRayHits(in []spritesmodels.RayHit) {
+	slices.SortFunc(in, func(a, b spritesmodels.RayHit) int {
+		if c := cmp.Compare(a.Distance, b.Distance); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.SpriteID, b.SpriteID)
+	})
+}
+
+// Walks the grid cells along the ray from (x, y) and calls test for each sprite that could be hit, nearest first.
+// margin is how far from its position any sprite's body reaches. A sprite further than that from the ray is never tested.
+// With filter.Limit set, the walk stops as soon as nothing further along can beat the hits already found.
+func (s *PositionBroker) Raycast(x, y, dirX, dirY, maxDistance, margin float64, filter spritesmodels.NearMeFilter, test RayTester) []spritesmodels.RayHit {
+	ret := []spritesmodels.RayHit{}
+	length := math.Hypot(dirX, dirY)
+	if length == 0 || !(maxDistance >= 0) || math.IsNaN(margin) {
+		return ret
+	}
+	dirX, dirY = dirX/length, dirY/length
+	margin = max(0, margin)
+
+	testSprites := func(sprites []spritesmodels.NearMeInfo) {
+		for _, info := range sprites {
+			if !nearMeMatches(filter, info) {
+				continue
+			}
+			if hit, ok := test(info); ok && hit.Distance <= maxDistance {
+				hit.SpriteID = info.SpriteID
+				hit.SpriteType = info.SpriteType
+				ret = append(ret, hit)
+			}
+		}
+	}
+
+	// A long ray or a big margin can cover far more cells than are occupied. Then it is faster to test everything.
+	reach := math.Ceil(margin / s.cellSize)
+	steps := 2*math.Ceil(maxDistance/s.cellSize) + 1
+	if !(steps*(2*reach+1)*(2*reach+1) <= float64(s.occupiedCellCount())) {
+		all := cellKey{x: maxCellIndex, y: maxCellIndex}
+		testSprites(s.filterOccupiedCells(cellKey{x: -maxCellIndex, y: -maxCellIndex}, all, nil))
+		sortRayHits(ret)
+		return applyLimit(ret, filter.Limit)
+	}
+
+	// Step through the cells the ray passes through, looking at the cells within reach of each one.
+	cell := s.cellFor(x, y)
+	r := int(reach)
+	stepX, tMaxX, tDeltaX := rayAxisSetup(x, dirX, cell.x, s.cellSize)
+	stepY, tMaxY, tDeltaY := rayAxisSetup(y, dirY, cell.y, s.cellSize)
+	seen := make(map[cellKey]bool)
+	enter := 0.0 // How far along the ray the current cell starts
+	for enter <= maxDistance {
+		if filter.Limit > 0 && len(ret) >= filter.Limit {
+			// Any sprite not tested yet can only be hit past this point.
+			sortRayHits(ret)
+			if ret[filter.Limit-1].Distance <= enter {
+				break
+			}
+		}
+
+		var sprites []spritesmodels.NearMeInfo
+		for cy := cell.y - r; cy <= cell.y+r; cy++ {
+			for cx := cell.x - r; cx <= cell.x+r; cx++ {
+				key := cellKey{x: cx, y: cy}
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				sprites = s.scanCells(key, key, sprites)
+			}
+		}
+		testSprites(sprites)
+
+		if tMaxX < tMaxY {
+			enter = tMaxX
+			cell.x += stepX
+			tMaxX += tDeltaX
+		} else {
+			enter = tMaxY
+			cell.y += stepY
+			tMaxY += tDeltaY
+		}
+	}
+
+	sortRayHits(ret)
+	return applyLimit(ret, filter.Limit)
+}
+
+// For one axis: which way the ray steps, how far along the ray the first cell boundary is, and how far between boundaries.
+func rayAxisSetup(start, dir float64, cell int, cellSize float64) (step int, tMax, tDelta float64) {
+	if dir == 0 {
+		return 0, math.Inf(1), math.Inf(1)
+	}
+	boundary := float64(cell) * cellSize
+	step = -1
+	if dir > 0 {
+		boundary += cellSize
+		step = 1
+	}
+	return step, (boundary - start) / dir, cellSize / math.Abs(dir)
+}
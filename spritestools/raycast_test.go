@@ -0,0 +1,71 @@
+package spritestools
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/stretchr/testify/require"
+)
+
+// Tests the ray against the sprite's body.
+func circleTester(bodies map[int]*ClickOnBody, x, y, dirX, dirY, maxDistance float64) RayTester {
+	return func(info spritesmodels.NearMeInfo) (spritesmodels.RayHit, bool) {
+		body, ok := bodies[info.SpriteID]
+		if !ok {
+			return spritesmodels.RayHit{}, false
+		}
+		return body.Raycast(x, y, dirX, dirY, maxDistance)
+	}
+}
+
+func TestPositionBroker_Raycast(t *testing.T) {
+	b := NewPositionBroker()
+	bodies := map[int]*ClickOnBody{}
+	for i := 1; i <= 10; i++ {
+		body := NewTouchCollisionBody()
+		body.AddCircleBody(0, 0, 5)
+		body.Pos(float64(i*30), -4)
+		bodies[i] = body
+
+		b.AddSprite(i)
+		b.UpdateSpriteInfo(i, spritesmodels.SpriteState{SpriteID: i, SpriteType: i % 2, X: float64(i * 30), Y: -4})
+	}
+	// Lots of far away sprites so the grid walk is used.
+	for i := 100; i < 2000; i++ {
+		b.AddSprite(i)
+		b.UpdateSpriteInfo(i, spritesmodels.SpriteState{SpriteID: i, X: float64(i * 30), Y: 10000})
+	}
+
+	// Only the first one is tested.
+	tested := 0
+	hits := b.Raycast(0, 0, 1, 0, 1000, 5, spritesmodels.NearMeFilter{Limit: 1}, func(info spritesmodels.NearMeInfo) (spritesmodels.RayHit, bool) {
+		tested++
+		return circleTester(bodies, 0, 0, 1, 0, 1000)(info)
+	})
+	require.Len(t, hits, 1)
+	require.Equal(t, 1, hits[0].SpriteID)
+	require.InDelta(t, 30-3, hits[0].Distance, 1e-9)
+	require.Less(t, tested, 3)
+
+	hits = b.Raycast(0, 0, 1, 0, 100, 5, spritesmodels.NearMeFilter{SpriteTypes: []int{0}}, circleTester(bodies, 0, 0, 1, 0, 100))
+	require.Len(t, hits, 1)
+	require.Equal(t, 2, hits[0].SpriteID)
+	require.Equal(t, 0, hits[0].SpriteType)
+
+	// The ray passes 4 above the centers, which are in the row of cells below it. They are missed without the margin.
+	hits = b.Raycast(0, 0, 1, 0, 1000, 0, spritesmodels.NearMeFilter{}, circleTester(bodies, 0, 0, 1, 0, 1000))
+	require.Len(t, hits, 0)
+	hits = b.Raycast(0, 0, 1, 0, 1000, 5, spritesmodels.NearMeFilter{ExcludeIDs: []int{1}}, circleTester(bodies, 0, 0, 1, 0, 1000))
+	require.Len(t, hits, 9)
+	require.Equal(t, 2, hits[0].SpriteID)
+
+	// Backwards and diagonal
+	hits = b.Raycast(310, -4, -1, 0, math.Inf(1), 5, spritesmodels.NearMeFilter{Limit: 2}, circleTester(bodies, 310, -4, -1, 0, math.Inf(1)))
+	require.Equal(t, []int{10, 9}, []int{hits[0].SpriteID, hits[1].SpriteID})
+	hits = b.Raycast(0, -34, 1, 1, 1000, 5, spritesmodels.NearMeFilter{}, circleTester(bodies, 0, -34, 1, 1, 1000))
+	require.Len(t, hits, 1)
+	require.Equal(t, 1, hits[0].SpriteID)
+
+	require.Empty(t, b.Raycast(0, 0, 0, 0, 1000, 5, spritesmodels.NearMeFilter{}, circleTester(bodies, 0, 0, 0, 0, 1000)))
+}
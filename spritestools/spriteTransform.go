@@ -0,0 +1,115 @@
+package spritestools
+
+import (
+	"math"
+
+	"github.com/gary23b/sprites/spritesmodels"
+)
+
+// A transform that leaves local points where they are, other than moving them by the position.
+func NewSpriteTransform() spritesmodels.SpriteTransform {
+	return spritesmodels.SpriteTransform{ScaleX: 1, ScaleY: 1}
+}
+
+// Converts a Cartesian point relative to the costume center into world coordinates.
+func LocalToWorld(t spritesmodels.SpriteTransform, x, y float64) (float64, float64) {
+	x -= t.PivotX
+	y -= t.PivotY
+
+	if t.FlipX {
+		x = -x
+	}
+	if t.FlipY {
+		y = -y
+	}
+
+	if t.SkewX != 0 || t.SkewY != 0 {
+		x, y = x+math.Tan(t.SkewX)*y, y+math.Tan(t.SkewY)*x
+	}
+
+	x *= t.ScaleX
+	y *= t.ScaleY
+
+	if t.AngleRad != 0 {
+		sin, cos := math.Sincos(t.AngleRad)
+		x, y = cos*x-sin*y, sin*x+cos*y
+	}
+
+	return x + t.X, y + t.Y
+}
+
+// The reverse of LocalToWorld. ok is false if the transform squashes the costume flat, since then there is no answer.
+func WorldToLocal(t spritesmodels.SpriteTransform, x, y float64) (localX, localY float64, ok bool) {
+	x -= t.X
+	y -= t.Y
+
+	if t.AngleRad != 0 {
+		sin, cos := math.Sincos(-t.AngleRad)
+		x, y = cos*x-sin*y, sin*x+cos*y
+	}
+
+	if t.ScaleX == 0 || t.ScaleY == 0 {
+		return 0, 0, false
+	}
+	x /= t.ScaleX
+	y /= t.ScaleY
+
+	if t.SkewX != 0 || t.SkewY != 0 {
+		tanX, tanY := math.Tan(t.SkewX), math.Tan(t.SkewY)
+		det := 1 - tanX*tanY
+		if math.Abs(det) < 1e-12 {
+			return 0, 0, false
+		}
+		x, y = (x-tanX*y)/det, (y-tanY*x)/det
+	}
+
+	if t.FlipX {
+		x = -x
+	}
+	if t.FlipY {
+		y = -y
+	}
+
+	return x + t.PivotX, y + t.PivotY, true
+}
+
+// The transform of a sprite state, which stores its angles in degrees.
+func SpriteStateTransform(state spritesmodels.SpriteState) spritesmodels.SpriteTransform {
+	return spritesmodels.SpriteTransform{
+		X:        state.X,
+		Y:        state.Y,
+		AngleRad: state.AngleDegrees * (math.Pi / 180.0),
+		ScaleX:   state.ScaleX,
+		ScaleY:   state.ScaleY,
+		FlipX:    state.FlipX,
+		FlipY:    state.FlipY,
+		PivotX:   state.PivotX,
+		PivotY:   state.PivotY,
+		SkewX:    state.SkewX * (math.Pi / 180.0),
+		SkewY:    state.SkewY * (math.Pi / 180.0),
+	}
+}
+
+// Places a child into the world. The child's position, angle, scale, and flips are relative to the parent,
+// with (0, 0) at the parent's pivot. Visibility and opacity are combined. Everything else belongs to the child.
+// A parent with non-uniform scale stretches the child's position, but not its shape.
+func ApplyParentState(parent, child spritesmodels.SpriteState) spritesmodels.SpriteState {
+	ret := child
+
+	ret.X, ret.Y = LocalToWorld(SpriteStateTransform(parent), child.X+parent.PivotX, child.Y+parent.PivotY)
+
+	// A single mirror turns the child the other way.
+	angle := child.AngleDegrees
+	if parent.FlipX != parent.FlipY {
+		angle = -angle
+	}
+	ret.AngleDegrees = parent.AngleDegrees + angle
+	ret.FlipX = parent.FlipX != child.FlipX
+	ret.FlipY = parent.FlipY != child.FlipY
+
+	ret.ScaleX = parent.ScaleX * child.ScaleX
+	ret.ScaleY = parent.ScaleY * child.ScaleY
+	ret.Visible = parent.Visible && child.Visible
+	ret.Opacity = parent.Opacity * child.Opacity / 100
+	return ret
+}
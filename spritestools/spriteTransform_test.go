@@ -0,0 +1,110 @@
+package spritestools
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalToWorld(t *testing.T) {
+	tr := NewSpriteTransform()
+	tr.X, tr.Y = 100, 50
+	x, y := LocalToWorld(tr, 10, 5)
+	require.InDelta(t, 110, x, 1e-9)
+	require.InDelta(t, 55, y, 1e-9)
+
+	// The pivot lands on the position
+	tr.PivotX, tr.PivotY = 10, 5
+	x, y = LocalToWorld(tr, 10, 5)
+	require.InDelta(t, 100, x, 1e-9)
+	require.InDelta(t, 50, y, 1e-9)
+
+	// Rotating a quarter turn counter-clockwise around the pivot
+	tr.AngleRad = math.Pi / 2
+	x, y = LocalToWorld(tr, 20, 5)
+	require.InDelta(t, 100, x, 1e-9)
+	require.InDelta(t, 60, y, 1e-9)
+
+	// Flipping mirrors around the pivot
+	tr = NewSpriteTransform()
+	tr.FlipX = true
+	tr.PivotX = 10
+	x, y = LocalToWorld(tr, 20, 3)
+	require.InDelta(t, -10, x, 1e-9)
+	require.InDelta(t, 3, y, 1e-9)
+
+	// Skew moves x by y
+	tr = NewSpriteTransform()
+	tr.SkewX = math.Pi / 4
+	x, y = LocalToWorld(tr, 0, 10)
+	require.InDelta(t, 10, x, 1e-9)
+	require.InDelta(t, 10, y, 1e-9)
+}
+
+func TestWorldToLocal(t *testing.T) {
+	tr := spritesmodels.SpriteTransform{
+		X: -30, Y: 12,
+		AngleRad: 0.7,
+		ScaleX:   2, ScaleY: -0.5,
+		FlipX:  true,
+		PivotX: 4, PivotY: -8,
+		SkewX: 0.2, SkewY: -0.3,
+	}
+
+	for _, p := range [][2]float64{{0, 0}, {10, -3}, {-7, 22}} {
+		wx, wy := LocalToWorld(tr, p[0], p[1])
+		lx, ly, ok := WorldToLocal(tr, wx, wy)
+		require.True(t, ok)
+		require.InDelta(t, p[0], lx, 1e-9)
+		require.InDelta(t, p[1], ly, 1e-9)
+	}
+
+	tr.ScaleX = 0
+	_, _, ok := WorldToLocal(tr, 1, 1)
+	require.False(t, ok)
+}
+
+func TestApplyParentState(t *testing.T) {
+	parent := spritesmodels.SpriteState{
+		X: 100, Y: 50,
+		AngleDegrees: 90,
+		ScaleX:       2, ScaleY: 2,
+		Visible: true,
+		Opacity: 50,
+	}
+	child := spritesmodels.SpriteState{
+		X: 10, Y: 0,
+		AngleDegrees: 10,
+		ScaleX:       1.5, ScaleY: 1,
+		Visible: true,
+		Opacity: 50,
+	}
+
+	w := ApplyParentState(parent, child)
+	require.InDelta(t, 100, w.X, 1e-9)
+	require.InDelta(t, 70, w.Y, 1e-9)
+	require.InDelta(t, 100, w.AngleDegrees, 1e-9)
+	require.InDelta(t, 3, w.ScaleX, 1e-9)
+	require.InDelta(t, 2, w.ScaleY, 1e-9)
+	require.True(t, w.Visible)
+	require.InDelta(t, 25, w.Opacity, 1e-9)
+
+	parent.Visible = false
+	require.False(t, ApplyParentState(parent, child).Visible)
+
+	// The child is placed relative to the parent's pivot
+	parent = spritesmodels.SpriteState{X: 100, Y: 50, ScaleX: 1, ScaleY: 1, PivotX: 20}
+	w = ApplyParentState(parent, child)
+	require.InDelta(t, 110, w.X, 1e-9)
+	require.InDelta(t, 50, w.Y, 1e-9)
+
+	// A flipped parent mirrors the child's position, angle, and costume
+	parent.FlipX = true
+	w = ApplyParentState(parent, child)
+	require.InDelta(t, 90, w.X, 1e-9)
+	require.InDelta(t, -10, w.AngleDegrees, 1e-9)
+	require.True(t, w.FlipX)
+	require.False(t, w.FlipY)
+}
@@ -0,0 +1,236 @@
+package spritestools
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/gary23b/sprites/spritesmodels"
+)
+
+// Something that moves under steering forces. Each behaviour returns a force, the forces are added up with
+// whatever weights suit the game, and Apply moves the agent. Copy X, Y, and Heading onto the sprite afterwards.
+// Distances are in pixels and times are in seconds.
+type SteeringAgent struct {
+	X, Y       float64
+	VelX, VelY float64
+	MaxSpeed   float64
+	MaxForce   float64 // The most the velocity can change by in one second.
+
+	wanderAngle float64
+}
+
+// What the group behaviours need to know about another sprite. Build these from WhoIsNearMe or WhoIsWithin.
+type SteeringNeighbor struct {
+	X, Y       float64
+	VelX, VelY float64
+}
+
+// Turns the result of a neighbor query into flocking neighbors. velocity looks up how each sprite is moving.
+func SteeringNeighbors(near []spritesmodels.NearMeInfo, velocity func(spriteID int) (velX, velY float64)) []SteeringNeighbor {
+	ret := make([]SteeringNeighbor, len(near))
+	for i, n := range near {
+		ret[i] = SteeringNeighbor{X: n.X, Y: n.Y}
+		if velocity != nil {
+			ret[i].VelX, ret[i].VelY = velocity(n.SpriteID)
+		}
+	}
+	return ret
+}
+
+// A circle to steer around.
+type SteeringObstacle struct {
+	X, Y   float64
+	Radius float64
+}
+
+// How much each flocking behaviour counts towards the total.
+type FlockWeights struct {
+	Separation         float64
+	Alignment          float64
+	Cohesion           float64
+	SeparationDistance float64 // Only neighbors closer than this push the agent away.
+}
+
+// Good starting weights for a flock.
+func DefaultFlockWeights() FlockWeights {
+	return FlockWeights{Separation: 1.5, Alignment: 1, Cohesion: 1, SeparationDistance: 25}
+}
+
+// Shortens the vector to at most maxLength.
+func truncate(x, y, maxLength float64) (float64, float64) {
+	length := math.Hypot(x, y)
+	if length > maxLength && length > 0 {
+		return x / length * maxLength, y / length * maxLength
+	}
+	return x, y
+}
+
+// The vector with the given length, pointing the same way. Zero stays zero.
+func withLength(x, y, length float64) (float64, float64) {
+	l := math.Hypot(x, y)
+	if l == 0 {
+		return 0, 0
+	}
+	return x / l * length, y / l * length
+}
+
+// The force that turns the current velocity into the desired one.
+func (a *SteeringAgent) steerToward(desiredX, desiredY float64) (float64, float64) {
+	return desiredX - a.VelX, desiredY - a.VelY
+}
+
+// The direction of travel in degrees, for Sprite.Angle.
+func (a *SteeringAgent) Heading() float64 {
+	return math.Atan2(a.VelY, a.VelX) * (180.0 / math.Pi)
+}
+
+// Limits the force to MaxForce and the speed to MaxSpeed, then moves the agent forward dt seconds.
+func (a *SteeringAgent) Apply(forceX, forceY, dt float64) {
+	forceX, forceY = truncate(forceX, forceY, a.MaxForce)
+	a.VelX, a.VelY = truncate(a.VelX+forceX*dt, a.VelY+forceY*dt, a.MaxSpeed)
+	a.X += a.VelX * dt
+	a.Y += a.VelY * dt
+}
+
+// Full speed towards the target.
+func (a *SteeringAgent) Seek(x, y float64) (float64, float64) {
+	return a.steerToward(withLength(x-a.X, y-a.Y, a.MaxSpeed))
+}
+
+// Full speed away from the target while it is closer than panicDistance. A panicDistance of 0 always flees.
+func (a *SteeringAgent) Flee(x, y, panicDistance float64) (float64, float64) {
+	dx, dy := a.X-x, a.Y-y
+	if panicDistance > 0 && math.Hypot(dx, dy) > panicDistance {
+		return 0, 0
+	}
+	return a.steerToward(withLength(dx, dy, a.MaxSpeed))
+}
+
+// Like Seek, but slows down inside slowingDistance so that it stops on the target instead of overshooting.
+func (a *SteeringAgent) Arrive(x, y, slowingDistance float64) (float64, float64) {
+	dx, dy := x-a.X, y-a.Y
+	speed := a.MaxSpeed
+	if slowingDistance > 0 {
+		speed *= min(1, math.Hypot(dx, dy)/slowingDistance)
+	}
+	return a.steerToward(withLength(dx, dy, speed))
+}
+
+// Where a target moving in a straight line will be by the time the agent could get there.
+func (a *SteeringAgent) predict(x, y, velX, velY float64) (float64, float64) {
+	if a.MaxSpeed <= 0 {
+		return x, y
+	}
+	t := math.Hypot(x-a.X, y-a.Y) / a.MaxSpeed
+	return x + velX*t, y + velY*t
+}
+
+// Seeks where the moving target is going to be.
+func (a *SteeringAgent) Pursue(x, y, velX, velY float64) (float64, float64) {
+	return a.Seek(a.predict(x, y, velX, velY))
+}
+
+// Flees from where the moving target is going to be.
+func (a *SteeringAgent) Evade(x, y, velX, velY, panicDistance float64) (float64, float64) {
+	px, py := a.predict(x, y, velX, velY)
+	return a.Flee(px, py, panicDistance)
+}
+
+// Smooth random wandering. A point on a circle of the given radius, distance ahead of the agent, is sought.
+// Each call moves that point around the circle by up to jitter radians.
+func (a *SteeringAgent) Wander(radius, distance, jitter float64) (float64, float64) {
+	a.wanderAngle += (rand.Float64()*2 - 1) * jitter
+	heading := math.Atan2(a.VelY, a.VelX)
+	sin, cos := math.Sincos(heading)
+	targetSin, targetCos := math.Sincos(heading + a.wanderAngle)
+	return a.Seek(a.X+cos*distance+targetCos*radius, a.Y+sin*distance+targetSin*radius)
+}
+
+// Steers sideways around the closest obstacle in the way within lookAhead. agentRadius is how wide the agent is.
+func (a *SteeringAgent) AvoidObstacles(obstacles []SteeringObstacle, agentRadius, lookAhead float64) (float64, float64) {
+	speed := math.Hypot(a.VelX, a.VelY)
+	if speed == 0 || lookAhead <= 0 {
+		return 0, 0
+	}
+	hx, hy := a.VelX/speed, a.VelY/speed
+
+	closest := math.Inf(1)
+	var side float64
+	for _, o := range obstacles {
+		dx, dy := o.X-a.X, o.Y-a.Y
+		along := dx*hx + dy*hy
+		lateral := hx*dy - hy*dx // Positive when the obstacle is to the left.
+		if along < 0 || along > lookAhead+o.Radius || math.Abs(lateral) >= o.Radius+agentRadius {
+			continue
+		}
+		if along < closest {
+			closest = along
+			side = lateral
+			if side == 0 {
+				side = 1 // Dead ahead. Go right.
+			}
+		}
+	}
+	if math.IsInf(closest, 1) {
+		return 0, 0
+	}
+
+	// Push to the side away from the obstacle, harder the closer it is.
+	strength := a.MaxForce * (1 + max(0, 1-closest/lookAhead))
+	dir := -math.Copysign(1, side)
+	return -hy * dir * strength, hx * dir * strength
+}
+
+// Away from neighbors closer than distance. The closer they are, the harder the push.
+func (a *SteeringAgent) Separation(neighbors []SteeringNeighbor, distance float64) (float64, float64) {
+	var sumX, sumY float64
+	for _, n := range neighbors {
+		dx, dy := a.X-n.X, a.Y-n.Y
+		d := math.Hypot(dx, dy)
+		if d == 0 || d >= distance {
+			continue
+		}
+		sumX += dx / (d * d)
+		sumY += dy / (d * d)
+	}
+	if sumX == 0 && sumY == 0 {
+		return 0, 0
+	}
+	return a.steerToward(withLength(sumX, sumY, a.MaxSpeed))
+}
+
+// Towards the average direction of the neighbors.
+func (a *SteeringAgent) Alignment(neighbors []SteeringNeighbor) (float64, float64) {
+	var sumX, sumY float64
+	for _, n := range neighbors {
+		sumX += n.VelX
+		sumY += n.VelY
+	}
+	if sumX == 0 && sumY == 0 {
+		return 0, 0
+	}
+	return a.steerToward(withLength(sumX, sumY, a.MaxSpeed))
+}
+
+// Towards the middle of the neighbors.
+func (a *SteeringAgent) Cohesion(neighbors []SteeringNeighbor) (float64, float64) {
+	if len(neighbors) == 0 {
+		return 0, 0
+	}
+	var sumX, sumY float64
+	for _, n := range neighbors {
+		sumX += n.X
+		sumY += n.Y
+	}
+	count := float64(len(neighbors))
+	return a.Seek(sumX/count, sumY/count)
+}
+
+// Separation, alignment, and cohesion added up with the weights.
+func (a *SteeringAgent) Flock(neighbors []SteeringNeighbor, weights FlockWeights) (float64, float64) {
+	sx, sy := a.Separation(neighbors, weights.SeparationDistance)
+	ax, ay := a.Alignment(neighbors)
+	cx, cy := a.Cohesion(neighbors)
+	return sx*weights.Separation + ax*weights.Alignment + cx*weights.Cohesion,
+		sy*weights.Separation + ay*weights.Alignment + cy*weights.Cohesion
+}
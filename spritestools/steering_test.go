@@ -0,0 +1,108 @@
+package spritestools
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAgent() *SteeringAgent {
+	return &SteeringAgent{MaxSpeed: 100, MaxForce: 200}
+}
+
+func TestSteering_seekFleeArrive(t *testing.T) {
+	a := newTestAgent()
+	fx, fy := a.Seek(10, 0)
+	require.InDelta(t, 100, fx, 1e-9)
+	require.InDelta(t, 0, fy, 1e-9)
+
+	fx, _ = a.Flee(10, 0, 0)
+	require.InDelta(t, -100, fx, 1e-9)
+	fx, fy = a.Flee(10, 0, 5)
+	require.Equal(t, 0.0, fx)
+	require.Equal(t, 0.0, fy)
+
+	// Already moving at the desired velocity, so there is nothing to do.
+	a.VelX = 100
+	fx, _ = a.Seek(10, 0)
+	require.InDelta(t, 0, fx, 1e-9)
+
+	// Inside the slowing distance the desired speed drops.
+	fx, _ = a.Arrive(10, 0, 20)
+	require.InDelta(t, 50-100, fx, 1e-9)
+
+	// Arrive comes to a stop on the target.
+	a = newTestAgent()
+	for i := 0; i < 1000; i++ {
+		fx, fy := a.Arrive(100, 50, 60)
+		a.Apply(fx, fy, 1.0/60)
+		require.LessOrEqual(t, math.Hypot(a.VelX, a.VelY), a.MaxSpeed+1e-9)
+	}
+	require.InDelta(t, 100, a.X, 1)
+	require.InDelta(t, 50, a.Y, 1)
+	require.Less(t, math.Hypot(a.VelX, a.VelY), 1.0)
+}
+
+func TestSteering_pursueEvade(t *testing.T) {
+	a := newTestAgent()
+	// The target is 100 away, so it takes a second to get there. By then the target has moved up 50.
+	fx, fy := a.Pursue(100, 0, 0, 50)
+	require.InDelta(t, 100/math.Hypot(100, 50)*100, fx, 1e-9)
+	require.InDelta(t, 50/math.Hypot(100, 50)*100, fy, 1e-9)
+
+	fx, fy = a.Evade(100, 0, 0, 50, 0)
+	require.InDelta(t, -100/math.Hypot(100, 50)*100, fx, 1e-9)
+	require.InDelta(t, -50/math.Hypot(100, 50)*100, fy, 1e-9)
+}
+
+func TestSteering_wanderAndAvoid(t *testing.T) {
+	a := newTestAgent()
+	a.VelX = 50
+	for i := 0; i < 100; i++ {
+		fx, fy := a.Wander(20, 40, .5)
+		require.LessOrEqual(t, math.Hypot(fx, fy), 2*a.MaxSpeed+1e-9)
+		a.Apply(fx, fy, 1.0/60)
+	}
+
+	a = newTestAgent()
+	a.VelX = 100
+	obstacles := []SteeringObstacle{{X: 50, Y: 5, Radius: 10}, {X: 200, Y: 0, Radius: 10}}
+	fx, fy := a.AvoidObstacles(obstacles, 5, 100)
+	require.InDelta(t, 0, fx, 1e-9)
+	require.Less(t, fy, 0.0) // The first obstacle is a little to the left, so go right.
+
+	// Behind and off to the side don't matter.
+	fx, fy = a.AvoidObstacles([]SteeringObstacle{{X: -50, Y: 0, Radius: 10}, {X: 50, Y: 50, Radius: 10}}, 5, 100)
+	require.Equal(t, 0.0, fx)
+	require.Equal(t, 0.0, fy)
+}
+
+func TestSteering_flock(t *testing.T) {
+	near := []spritesmodels.NearMeInfo{{SpriteID: 1, X: 10, Y: 0}, {SpriteID: 2, X: 10, Y: 20}}
+	neighbors := SteeringNeighbors(near, func(id int) (float64, float64) { return 0, float64(id) })
+	require.Equal(t, []SteeringNeighbor{{X: 10, Y: 0, VelY: 1}, {X: 10, Y: 20, VelY: 2}}, neighbors)
+
+	a := newTestAgent()
+	fx, fy := a.Separation(neighbors, 15)
+	require.InDelta(t, -100, fx, 1e-9) // Only the first is close enough.
+	require.InDelta(t, 0, fy, 1e-9)
+
+	fx, fy = a.Alignment(neighbors)
+	require.InDelta(t, 0, fx, 1e-9)
+	require.InDelta(t, 100, fy, 1e-9)
+
+	fx, fy = a.Cohesion(neighbors)
+	require.InDelta(t, 100/math.Sqrt2, fx, 1e-9)
+	require.InDelta(t, 100/math.Sqrt2, fy, 1e-9)
+
+	w := FlockWeights{Separation: 1, Alignment: 0, Cohesion: 0, SeparationDistance: 15}
+	fx, fy = a.Flock(neighbors, w)
+	require.InDelta(t, -100, fx, 1e-9)
+	require.InDelta(t, 0, fy, 1e-9)
+
+	fx, fy = a.Flock(nil, DefaultFlockWeights())
+	require.Equal(t, 0.0, fx)
+	require.Equal(t, 0.0, fy)
+}
@@ -0,0 +1,154 @@
+package spritestools
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gary23b/sprites/spritesmodels"
+)
+
+var noteSemitones = map[byte]int{'C': 0, 'D': 2, 'E': 4, 'F': 5, 'G': 7, 'A': 9, 'B': 11}
+
+// Converts scientific pitch notation like "A4", "C#5" or "Eb3" to a frequency in Hz. "R" is a rest and returns 0.
+func NoteFrequency(note string) (float64, error) {
+	note = strings.TrimSpace(note)
+	if note == "" || strings.EqualFold(note, "R") {
+		return 0, nil
+	}
+
+	semitone, ok := noteSemitones[strings.ToUpper(note[:1])[0]]
+	if !ok {
+		return 0, fmt.Errorf("Invalid note: %s", note)
+	}
+	rest := note[1:]
+	switch {
+	case strings.HasPrefix(rest, "#"):
+		semitone++
+		rest = rest[1:]
+	case strings.HasPrefix(rest, "b"):
+		semitone--
+		rest = rest[1:]
+	}
+
+	octave, err := strconv.Atoi(rest)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid note octave: %s, %w", note, err)
+	}
+
+	midi := (octave+1)*12 + semitone
+	return 440 * math.Pow(2, float64(midi-69)/12), nil
+}
+
+func oscillator(waveform spritesmodels.Waveform, phase float64, noise *rand.Rand) float64 {
+	switch waveform {
+	case spritesmodels.WaveSquare:
+		if phase < .5 {
+			return 1
+		}
+		return -1
+	case spritesmodels.WaveSaw:
+		return 2*phase - 1
+	case spritesmodels.WaveTriangle:
+		return 1 - 4*math.Abs(phase-.5)
+	case spritesmodels.WaveNoise:
+		return noise.Float64()*2 - 1
+	default:
+		return math.Sin(2 * math.Pi * phase)
+	}
+}
+
+// The envelope level at time t into a note that lasts noteLength.
+func envelopeLevel(env spritesmodels.Envelope, t, noteLength float64) float64 {
+	attack := env.Attack.Seconds()
+	decay := env.Decay.Seconds()
+	release := min(env.Release.Seconds(), noteLength)
+	sustain := env.Sustain
+	if sustain == 0 && decay == 0 {
+		sustain = 1
+	}
+
+	level := sustain
+	switch {
+	case t < attack:
+		level = t / attack
+	case t < attack+decay:
+		level = Lerp(1, sustain, (t-attack)/decay)
+	}
+
+	releaseStart := noteLength - release
+	if t > releaseStart && release > 0 {
+		level *= Lerp(1, 0, (t-releaseStart)/release)
+	}
+	return level
+}
+
+func defaultOne(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return x
+}
+
+// Renders the spec to 16 bit little endian stereo PCM, the same format the sound files are decoded to.
+func RenderSynth(spec spritesmodels.SynthSpec, sampleRate int) ([]byte, error) {
+	env := spec.Envelope
+	if env.Attack < 0 || env.Decay < 0 || env.Release < 0 {
+		return nil, fmt.Errorf("Envelope durations can't be negative: %+v", env)
+	}
+
+	totalLength := time.Duration(0)
+	for i, n := range spec.Notes {
+		if n.Duration < 0 {
+			return nil, fmt.Errorf("Note %d has a negative duration: %v", i, n.Duration)
+		}
+		totalLength += n.Duration
+	}
+	totalSamples := int(totalLength.Seconds() * float64(sampleRate))
+	ret := make([]byte, 0, totalSamples*4)
+
+	noise := rand.New(rand.NewSource(1)) // Repeatable noise so the same spec always sounds the same.
+	specVolume := defaultOne(spec.Volume)
+	phase := 0.0
+
+	for _, n := range spec.Notes {
+		startFreq := n.Frequency
+		if startFreq == 0 {
+			var err error
+			startFreq, err = NoteFrequency(n.Note)
+			if err != nil {
+				return nil, err
+			}
+		}
+		endFreq := n.EndFrequency
+		if endFreq == 0 {
+			endFreq = startFreq
+		}
+
+		noteLength := n.Duration.Seconds()
+		samples := int(noteLength * float64(sampleRate))
+		volume := specVolume * defaultOne(n.Volume)
+
+		for i := 0; i < samples; i++ {
+			value := 0.0
+			if startFreq > 0 {
+				t := float64(i) / float64(sampleRate)
+				freq := Lerp(startFreq, endFreq, t/noteLength)
+				// Accumulate the phase so that sweeps don't click.
+				phase += freq / float64(sampleRate)
+				phase -= math.Floor(phase)
+				value = oscillator(spec.Waveform, phase, noise) * envelopeLevel(spec.Envelope, t, noteLength) * volume
+			}
+
+			sample := uint16(int16(max(-1, min(1, value)) * math.MaxInt16))
+			ret = binary.LittleEndian.AppendUint16(ret, sample) // left
+			ret = binary.LittleEndian.AppendUint16(ret, sample) // right
+		}
+	}
+
+	return ret, nil
+}
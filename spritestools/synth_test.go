@@ -0,0 +1,104 @@
+package spritestools
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoteFrequency(t *testing.T) {
+	tests := []struct {
+		note string
+		want float64
+	}{
+		{note: "A4", want: 440},
+		{note: "A5", want: 880},
+		{note: "C4", want: 261.6256},
+		{note: "C#4", want: 277.1826},
+		{note: "Db4", want: 277.1826},
+		{note: "R", want: 0},
+		{note: "", want: 0},
+	}
+	for _, tt := range tests {
+		got, err := NoteFrequency(tt.note)
+		require.NoError(t, err, tt.note)
+		require.InDelta(t, tt.want, got, 1e-3, tt.note)
+	}
+
+	_, err := NoteFrequency("H4")
+	require.Error(t, err)
+	_, err = NoteFrequency("C")
+	require.Error(t, err)
+}
+
+func sampleAt(data []byte, index int) int16 {
+	return int16(binary.LittleEndian.Uint16(data[index*4:]))
+}
+
+func TestRenderSynth(t *testing.T) {
+	spec := spritesmodels.SynthSpec{
+		Waveform: spritesmodels.WaveSquare,
+		Envelope: spritesmodels.Envelope{
+			Attack:  time.Millisecond * 100,
+			Release: time.Millisecond * 100,
+		},
+		Notes: []spritesmodels.SynthNote{
+			{Note: "A4", Duration: time.Millisecond * 500},
+			{Note: "R", Duration: time.Millisecond * 250},
+		},
+	}
+
+	data, err := RenderSynth(spec, 1000)
+	require.NoError(t, err)
+	require.Len(t, data, 750*4)
+
+	// Left and right are the same
+	require.Equal(t, data[0:2], data[2:4])
+
+	// Half way up the attack
+	require.InDelta(t, 0.5*32767, float64(sampleAt(data, 50)), 400)
+	// Sustain
+	require.InDelta(t, 32767, float64(sampleAt(data, 200)), 1)
+	// Half way through the release
+	require.InDelta(t, 0.5*32767, float64(sampleAt(data, 450)), 400)
+	// Rest is silent
+	for i := 500; i < 750; i++ {
+		require.Equal(t, int16(0), sampleAt(data, i))
+	}
+
+	_, err = RenderSynth(spritesmodels.SynthSpec{Notes: []spritesmodels.SynthNote{{Note: "X1", Duration: time.Second}}}, 1000)
+	require.Error(t, err)
+
+	_, err = RenderSynth(spritesmodels.SynthSpec{Notes: []spritesmodels.SynthNote{{Note: "A4", Duration: -time.Second}}}, 1000)
+	require.Error(t, err)
+	spec.Envelope.Release = -time.Millisecond
+	_, err = RenderSynth(spec, 1000)
+	require.Error(t, err)
+}
+
+func TestRenderSynth_waveforms(t *testing.T) {
+	for _, w := range []spritesmodels.Waveform{
+		spritesmodels.WaveSine,
+		spritesmodels.WaveSquare,
+		spritesmodels.WaveSaw,
+		spritesmodels.WaveTriangle,
+		spritesmodels.WaveNoise,
+	} {
+		spec := spritesmodels.SynthSpec{
+			Waveform: w,
+			Notes:    []spritesmodels.SynthNote{{Frequency: 100, EndFrequency: 200, Duration: time.Millisecond * 100, Volume: .5}},
+		}
+		data, err := RenderSynth(spec, 48000)
+		require.NoError(t, err)
+		require.Len(t, data, 4800*4)
+
+		peak := int16(0)
+		for i := 0; i < 4800; i++ {
+			peak = max(peak, sampleAt(data, i))
+		}
+		require.InDelta(t, 0.5*32767, float64(peak), 400, "waveform %d", w)
+	}
+}
@@ -68,11 +68,16 @@ func CreateTextBubble(width, height float64, inputText string, size float64) ima
 }
 
 func CreateTextImg(inputText string, width, height, size float64, c color.Color) image.Image {
-	// startTime := time.Now()
 	font, err := truetype.Parse(goregular.TTF)
 	if err != nil {
 		panic("")
 	}
+	return CreateTextImgWithFont(inputText, font, width, height, size, c)
+}
+
+// Same as CreateTextImg but with a font loaded from a TrueType file, like the fonts in an asset manifest.
+func CreateTextImgWithFont(inputText string, font *truetype.Font, width, height, size float64, c color.Color) image.Image {
+	// startTime := time.Now()
 	face := truetype.NewFace(font, &truetype.Options{
 		Size: size,
 	})
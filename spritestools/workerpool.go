@@ -6,6 +6,7 @@ import "sync"
 // WorkerPool is a contract for Worker Pool implementation
 type WorkerPool interface {
 	AddTask(task func())
+	Wait() // Waits for every task added so far. The pool keeps running and more tasks can be added.
 	WaitForCompletion()
 }
 
@@ -13,6 +14,7 @@ type workerPool struct {
 	maxWorker   int
 	queuedTaskC chan func()
 	wg          *sync.WaitGroup
+	pending     sync.WaitGroup // Tasks added but not finished
 }
 
 var _ WorkerPool = &workerPool{} // make the compiler check this struct implements the interface.
@@ -40,7 +42,15 @@ func NewWorkerPool(workerCount int) *workerPool {
 }
 
 func (s *workerPool) AddTask(task func()) {
-	s.queuedTaskC <- task
+	s.pending.Add(1)
+	s.queuedTaskC <- func() {
+		defer s.pending.Done()
+		task()
+	}
+}
+
+func (s *workerPool) Wait() {
+	s.pending.Wait()
 }
 
 func (s *workerPool) WaitForCompletion() {
@@ -0,0 +1,26 @@
+package spritestools
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerPool(t *testing.T) {
+	pool := NewWorkerPool(4)
+
+	var count atomic.Int32
+	for i := 0; i < 1000; i++ {
+		pool.AddTask(func() { count.Add(1) })
+	}
+	pool.Wait()
+	require.Equal(t, int32(1000), count.Load())
+
+	// Still running after Wait
+	for i := 0; i < 10; i++ {
+		pool.AddTask(func() { count.Add(1) })
+	}
+	pool.WaitForCompletion()
+	require.Equal(t, int32(1010), count.Load())
+}
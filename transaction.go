@@ -0,0 +1,304 @@
+package sprites
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+
+	"github.com/gary23b/sprites/spritesmodels"
+)
+
+// Collects changes to a sprite without sending them. The methods match the Sprite updates and can be chained.
+type SpriteBuilder interface {
+	Costume(name string) SpriteBuilder
+	SetType(newType int) SpriteBuilder
+	Angle(angleDegrees float64) SpriteBuilder
+	Pos(cartX, cartY float64) SpriteBuilder
+	Z(z float64) SpriteBuilder
+	Visible(visible bool) SpriteBuilder
+	Scale(scale float64) SpriteBuilder
+	XYScale(xScale, yScale float64) SpriteBuilder
+	Flip(flipX, flipY bool) SpriteBuilder
+	Pivot(x, y float64) SpriteBuilder
+	Skew(xAngleDegrees, yAngleDegrees float64) SpriteBuilder
+	Opacity(opacityPercent float64) SpriteBuilder
+	Effects(in spritesmodels.SpriteEffects) SpriteBuilder
+	GhostEffect(ghostPercent float64) SpriteBuilder
+	ColorEffect(amount float64) SpriteBuilder
+	ClearEffects() SpriteBuilder
+	All(in spritesmodels.SpriteState) SpriteBuilder // Everything but the kinematics.
+}
+
+// Hands out a builder for each sprite in a transaction.
+type Transaction interface {
+	Sprite(s Sprite) SpriteBuilder // The same builder is returned each time for the same sprite.
+}
+
+type spriteBuilder struct {
+	changes []func(s *sprite) // Run under the sprite's state lock.
+	err     error
+}
+
+var _ SpriteBuilder = &spriteBuilder{}
+
+func (b *spriteBuilder) add(change func(s *sprite)) SpriteBuilder {
+	b.changes = append(b.changes, change)
+	return b
+}
+
+func (b *spriteBuilder) Costume(name string) SpriteBuilder {
+	return b.add(func(s *sprite) { s.costumeName = name })
+}
+
+func (b *spriteBuilder) SetType(newType int) SpriteBuilder {
+	return b.add(func(s *sprite) { s.spriteType = newType })
+}
+
+func (b *spriteBuilder) Angle(angleDegrees float64) SpriteBuilder {
+	return b.add(func(s *sprite) {
+		s.angleRad = angleDegrees * (math.Pi / 180.0)
+		s.generation++
+	})
+}
+
+func (b *spriteBuilder) Pos(cartX, cartY float64) SpriteBuilder {
+	return b.add(func(s *sprite) {
+		s.x, s.y = cartX, cartY
+		s.generation++
+	})
+}
+
+func (b *spriteBuilder) Z(z float64) SpriteBuilder {
+	if math.IsNaN(z) {
+		b.err = fmt.Errorf("Z must be a number")
+		return b
+	}
+	return b.add(func(s *sprite) { s.z = z })
+}
+
+func (b *spriteBuilder) Visible(visible bool) SpriteBuilder {
+	return b.add(func(s *sprite) { s.visible = visible })
+}
+
+func (b *spriteBuilder) Scale(scale float64) SpriteBuilder {
+	return b.add(func(s *sprite) { s.scaleX, s.scaleY = scale, scale })
+}
+
+func (b *spriteBuilder) XYScale(xScale, yScale float64) SpriteBuilder {
+	return b.add(func(s *sprite) { s.scaleX, s.scaleY = xScale, yScale })
+}
+
+func (b *spriteBuilder) Flip(flipX, flipY bool) SpriteBuilder {
+	return b.add(func(s *sprite) { s.flipX, s.flipY = flipX, flipY })
+}
+
+func (b *spriteBuilder) Pivot(x, y float64) SpriteBuilder {
+	return b.add(func(s *sprite) { s.pivotX, s.pivotY = x, y })
+}
+
+func (b *spriteBuilder) Skew(xAngleDegrees, yAngleDegrees float64) SpriteBuilder {
+	return b.add(func(s *sprite) {
+		s.skewXRad = xAngleDegrees * (math.Pi / 180.0)
+		s.skewYRad = yAngleDegrees * (math.Pi / 180.0)
+	})
+}
+
+func (b *spriteBuilder) Opacity(opacityPercent float64) SpriteBuilder {
+	return b.add(func(s *sprite) { s.opacity = opacityPercent })
+}
+
+func (b *spriteBuilder) Effects(in spritesmodels.SpriteEffects) SpriteBuilder {
+	return b.add(func(s *sprite) { s.effects = in })
+}
+
+func (b *spriteBuilder) GhostEffect(ghostPercent float64) SpriteBuilder {
+	return b.add(func(s *sprite) { s.effects.Ghost = ghostPercent })
+}
+
+func (b *spriteBuilder) ColorEffect(amount float64) SpriteBuilder {
+	return b.add(func(s *sprite) { s.effects.HueShift = amount * 360.0 / 200.0 })
+}
+
+func (b *spriteBuilder) ClearEffects() SpriteBuilder {
+	return b.add(func(s *sprite) { s.effects = spritesmodels.SpriteEffects{} })
+}
+
+func (b *spriteBuilder) All(in spritesmodels.SpriteState) SpriteBuilder {
+	b.Z(in.Z)
+	return b.add(func(s *sprite) {
+		s.spriteType = in.SpriteType
+		s.costumeName = in.CostumeName
+		s.x, s.y = in.X, in.Y
+		s.angleRad = in.AngleDegrees * (math.Pi / 180.0)
+		s.generation++
+		s.visible = in.Visible
+		s.opacity = in.Opacity
+		s.scaleX, s.scaleY = in.ScaleX, in.ScaleY
+		s.flipX, s.flipY = in.FlipX, in.FlipY
+		s.pivotX, s.pivotY = in.PivotX, in.PivotY
+		s.skewXRad = in.SkewX * (math.Pi / 180.0)
+		s.skewYRad = in.SkewY * (math.Pi / 180.0)
+		s.effects = in.Effects
+	})
+}
+
+type transaction struct {
+	builders map[*sprite]*spriteBuilder
+	err      error
+}
+
+var _ Transaction = &transaction{}
+
+func (tx *transaction) Sprite(in Sprite) SpriteBuilder {
+	s, ok := in.(*sprite)
+	if !ok {
+		tx.err = fmt.Errorf("Sprites in a transaction must be made by the sim")
+		return &spriteBuilder{}
+	}
+	b, ok := tx.builders[s]
+	if !ok {
+		b = &spriteBuilder{}
+		tx.builders[s] = b
+	}
+	return b
+}
+
+func (s *sprite) Batch(f func(b SpriteBuilder)) error {
+	return s.sim.Transaction(func(tx Transaction) { f(tx.Sprite(s)) })
+}
+
+func (sim *simState) Transaction(f func(tx Transaction)) error {
+	tx := &transaction{builders: make(map[*sprite]*spriteBuilder)}
+	f(tx)
+	if tx.err != nil {
+		return tx.err
+	}
+	for _, b := range tx.builders {
+		if b.err != nil {
+			return b.err
+		}
+	}
+	if len(tx.builders) == 0 {
+		return nil
+	}
+	return sim.commit(tx.builders, nil)
+}
+
+// The sprites and all of their descendants, parents before children. That is the order updateChildren locks them in,
+// so locking them all in this order can't deadlock with it.
+func lockOrder(sprites []*sprite) []*sprite {
+	depths := make(map[*sprite]int)
+	var addFamily func(s *sprite)
+	addFamily = func(s *sprite) {
+		if _, ok := depths[s]; ok {
+			return
+		}
+		depth := 0
+		for p := s.getParent(); p != nil; p = p.getParent() {
+			depth++
+		}
+		depths[s] = depth
+		for _, child := range s.getChildren() {
+			addFamily(child)
+		}
+	}
+	for _, s := range sprites {
+		addFamily(s)
+	}
+
+	ret := make([]*sprite, 0, len(depths))
+	for s := range depths {
+		ret = append(ret, s)
+	}
+	slices.SortFunc(ret, func(a, b *sprite) int {
+		return cmp.Or(cmp.Compare(depths[a], depths[b]), cmp.Compare(a.spriteID, b.spriteID))
+	})
+	return ret
+}
+
+// Makes the changes and sends them, along with any new sprites, as one command. The descendants are sent too,
+// since their world values follow their parents.
+func (sim *simState) commit(builders map[*sprite]*spriteBuilder, adds []spritesmodels.CmdAddNewSprite) error {
+	changed := make([]*sprite, 0, len(builders))
+	for s := range builders {
+		changed = append(changed, s)
+	}
+	locked := lockOrder(changed)
+	for _, s := range locked {
+		s.updateMutex.Lock()
+	}
+	defer func() {
+		for _, s := range locked {
+			s.updateMutex.Unlock()
+		}
+	}()
+
+	for _, s := range changed {
+		if s.isDeleted() {
+			return fmt.Errorf("Failed to update sprite %d: %w", s.spriteID, ErrSpriteDeleted)
+		}
+	}
+	for s, b := range builders {
+		s.stateMutex.Lock()
+		for _, change := range b.changes {
+			change(s)
+		}
+		s.stateMutex.Unlock()
+	}
+
+	cmd := spritesmodels.CmdSpriteBatch{Adds: adds}
+	for _, s := range locked {
+		if s.isDeleted() {
+			continue
+		}
+		s.updateClickBody()
+		cmd.Updates = append(cmd.Updates, sim.fullUpdateCmd(s))
+	}
+	sim.cmdQueue.Push(cmd)
+	return nil
+}
+
+func (sim *simState) AddSprites(n int, template spritesmodels.SpriteState) []Sprite {
+	if n <= 0 {
+		return nil
+	}
+
+	builder := &spriteBuilder{}
+	builder.All(template)
+	if builder.err != nil {
+		log.Printf("Failed to add sprites: %v\n", builder.err)
+		return nil
+	}
+
+	added := make([]*sprite, n)
+	adds := make([]spritesmodels.CmdAddNewSprite, n)
+	builders := make(map[*sprite]*spriteBuilder, n)
+	for i := range added {
+		spriteID := sim.g.GetNextSpriteID()
+		sim.posBroker.AddSprite(spriteID)
+		added[i] = NewSprite(sim, randomSpriteName(), spriteID)
+		adds[i] = spritesmodels.CmdAddNewSprite{SpriteID: spriteID}
+		builders[added[i]] = builder
+	}
+	// Nobody else can see the new sprites yet, so this can't fail.
+	sim.commit(builders, adds)
+
+	// Only once the game knows about them, so that nothing is sent for a sprite before it is added.
+	ret := make([]Sprite, n)
+	sim.idToSpriteMapMutex.Lock()
+	for i, s := range added {
+		sim.idToSpriteMap[s.spriteID] = s
+		sim.nameToSpriteMap[s.UniqueName] = s
+		ret[i] = s
+	}
+	sim.idToSpriteMapMutex.Unlock()
+
+	if template.Kinematics != (spritesmodels.Kinematics{}) {
+		for _, s := range added {
+			s.SetKinematics(template.Kinematics)
+		}
+	}
+	return ret
+}
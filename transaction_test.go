@@ -0,0 +1,115 @@
+package sprites
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gary23b/sprites/spritesmodels"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpriteBatch(t *testing.T) {
+	sim := newTestSim()
+	s := sim.addTestSprite(1)
+
+	err := s.Batch(func(b SpriteBuilder) {
+		b.Costume("turtle").Pos(10, 20).Scale(2)
+		b.Opacity(50)
+	})
+	require.NoError(t, err)
+
+	state := s.GetState()
+	require.Equal(t, "turtle", state.CostumeName)
+	require.Equal(t, 10.0, state.X)
+	require.Equal(t, 20.0, state.Y)
+	require.Equal(t, 2.0, state.ScaleX)
+	require.Equal(t, 50.0, state.Opacity)
+	require.Equal(t, 10.0, sim.GetSpriteInfoByID(1).X)
+
+	cmds := sim.takeCmds()
+	require.Len(t, cmds, 1)
+	batch := cmds[0].(spritesmodels.CmdSpriteBatch)
+	require.Len(t, batch.Updates, 1)
+	require.Equal(t, "turtle", batch.Updates[0].CostumeName)
+	require.Equal(t, 2.0, batch.Updates[0].XScale)
+}
+
+func TestTransaction(t *testing.T) {
+	sim := newTestSim()
+	parent := sim.addTestSprite(1)
+	child := sim.addTestSprite(2)
+	other := sim.addTestSprite(3)
+	require.NoError(t, child.SetParent(parent))
+	require.NoError(t, child.Pos(5, 0))
+	sim.takeCmds()
+
+	err := sim.Transaction(func(tx Transaction) {
+		tx.Sprite(other).Pos(-50, 0)
+		tx.Sprite(parent).Pos(100, 0)
+		tx.Sprite(parent).Angle(90)
+	})
+	require.NoError(t, err)
+
+	cmds := sim.takeCmds()
+	require.Len(t, cmds, 1)
+	batch := cmds[0].(spritesmodels.CmdSpriteBatch)
+	require.Len(t, batch.Updates, 3)
+
+	// The child wasn't changed, but it moved with its parent. Parents come first.
+	require.Equal(t, 1, batch.Updates[0].SpriteID)
+	require.Equal(t, 3, batch.Updates[1].SpriteID)
+	require.Equal(t, 2, batch.Updates[2].SpriteID)
+	require.InDelta(t, 100.0, batch.Updates[2].X, 1e-9)
+	require.InDelta(t, 5.0, batch.Updates[2].Y, 1e-9)
+	require.Equal(t, -50.0, other.GetState().X)
+}
+
+func TestTransactionIsAllOrNothing(t *testing.T) {
+	sim := newTestSim()
+	a := sim.addTestSprite(1)
+	b := sim.addTestSprite(2)
+	require.NoError(t, b.DeleteSprite())
+	sim.takeCmds()
+
+	err := sim.Transaction(func(tx Transaction) {
+		tx.Sprite(a).Pos(1, 1)
+		tx.Sprite(b).Pos(2, 2)
+	})
+	require.ErrorIs(t, err, ErrSpriteDeleted)
+
+	err = a.Batch(func(b SpriteBuilder) {
+		b.Pos(3, 3).Z(math.NaN())
+	})
+	require.Error(t, err)
+
+	require.Equal(t, 0.0, a.GetState().X)
+	require.Empty(t, sim.takeCmds())
+}
+
+// Run with -race. Transactions lock many sprites at once, so this also checks that they can't deadlock with parents
+// updating their children.
+func TestTransactionConcurrentUse(t *testing.T) {
+	sim := newTestSim()
+	parent := sim.addTestSprite(1)
+	child := sim.addTestSprite(2)
+	grandchild := sim.addTestSprite(3)
+	require.NoError(t, child.SetParent(parent))
+	require.NoError(t, grandchild.SetParent(child))
+
+	const loops = 200
+	runConcurrently(loops,
+		func(i int) { parent.Pos(float64(i), 0) },
+		func(i int) { child.Angle(float64(i)) },
+		func(i int) {
+			sim.Transaction(func(tx Transaction) {
+				tx.Sprite(grandchild).Pos(float64(i), 1)
+				tx.Sprite(parent).Scale(float64(i))
+			})
+		},
+		func(i int) {
+			grandchild.Batch(func(b SpriteBuilder) { b.Opacity(float64(i)) })
+		},
+	)
+
+	require.Equal(t, float64(loops-1), grandchild.GetState().Opacity)
+}